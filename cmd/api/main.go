@@ -1,17 +1,47 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
 
-	_ "cleanarch/boiler/internal/docs"
+	"cleanarch/boiler/internal/config"
 	"cleanarch/boiler/internal/server"
+	userplugin "cleanarch/boiler/internal/user/plugin"
 )
 
 func main() {
+	if err := run(os.Args[1:]); err != nil {
+		// the usage was printed already
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	cfg, err := config.Load(args)
+	if err != nil {
+		return err
+	}
 
-	app := server.NewApp()
+	app, err := server.NewApp(cfg)
+	if err != nil {
+		return err
+	}
 
-	if err := app.Run("3000"); err != nil {
-		log.Fatalf("%s", err.Error())
+	// plugins are started in dependency order and stopped in reverse
+	if err := app.Register(
+		userplugin.NewUserPlugin(),
+	); err != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout.Std())
+		defer cancel()
+		return errors.Join(err, app.Close(ctx))
 	}
+
+	return app.Run()
 }
@@ -0,0 +1,306 @@
+// Package config holds the typed application configuration.
+//
+// Values are resolved from, in increasing order of precedence: the defaults
+// below, an optional YAML or JSON file, environment variables (a `.env` file
+// is loaded into the environment first) and command line flags. Every leaf
+// field can be set through its `env` tag and through a flag named after its
+// dotted path, e.g. `-server.port 8080`.
+package config
+
+import (
+	"time"
+)
+
+type Config struct {
+	Server    ServerConfig    `yaml:"server" json:"server"`
+	Mongo     MongoConfig     `yaml:"mongo" json:"mongo"`
+	Log       LogConfig       `yaml:"log" json:"log"`
+	CORS      CORSConfig      `yaml:"cors" json:"cors"`
+	RateLimit RateLimitConfig `yaml:"rateLimit" json:"rateLimit"`
+	Auth      AuthConfig      `yaml:"auth" json:"auth"`
+	Events    EventsConfig    `yaml:"events" json:"events"`
+	Tracing   TracingConfig   `yaml:"tracing" json:"tracing"`
+	// Compression applies to response bodies.
+	Compression CompressionConfig `yaml:"compression" json:"compression"`
+	Idempotency IdempotencyConfig `yaml:"idempotency" json:"idempotency"`
+	Webhooks    WebhooksConfig    `yaml:"webhooks" json:"webhooks"`
+}
+
+type ServerConfig struct {
+	Port            int      `yaml:"port" json:"port" env:"PORT"`
+	ReadTimeout     Duration `yaml:"readTimeout" json:"readTimeout" env:"SERVER_READ_TIMEOUT"`
+	WriteTimeout    Duration `yaml:"writeTimeout" json:"writeTimeout" env:"SERVER_WRITE_TIMEOUT"`
+	ShutdownTimeout Duration `yaml:"shutdownTimeout" json:"shutdownTimeout" env:"SERVER_SHUTDOWN_TIMEOUT"`
+	// DrainDelay is how long /readyz reports not ready before the server
+	// stops accepting connections, giving load balancers time to react.
+	DrainDelay     Duration  `yaml:"drainDelay" json:"drainDelay" env:"SERVER_DRAIN_DELAY"`
+	MaxHeaderBytes int       `yaml:"maxHeaderBytes" json:"maxHeaderBytes" env:"SERVER_MAX_HEADER_BYTES"`
+	TLS            TLSConfig `yaml:"tls" json:"tls" env:"SERVER_TLS"`
+	// GRPC serves the gRPC services of the plugins on a separate listener.
+	GRPC GRPCConfig `yaml:"grpc" json:"grpc" env:"GRPC"`
+}
+
+// GRPCConfig configures the gRPC listener. It uses the TLS settings of the
+// server when TLS is enabled.
+type GRPCConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled" env:"ENABLED"`
+	Port    int  `yaml:"port" json:"port" env:"PORT"`
+	// Reflection serves the gRPC reflection service, so tools such as
+	// grpcurl can list and call the services without their .proto files.
+	Reflection bool `yaml:"reflection" json:"reflection" env:"REFLECTION"`
+}
+
+type TLSConfig struct {
+	Enabled  bool   `yaml:"enabled" json:"enabled" env:"ENABLED"`
+	CertFile string `yaml:"certFile" json:"certFile" env:"CERT_FILE"`
+	KeyFile  string `yaml:"keyFile" json:"keyFile" env:"KEY_FILE"`
+	// MinVersion is "1.2" or "1.3".
+	MinVersion string `yaml:"minVersion" json:"minVersion" env:"MIN_VERSION"`
+	// CipherSuites restricts the TLS 1.2 cipher suites by their IANA names,
+	// empty keeps Go's secure defaults. TLS 1.3 suites are not configurable.
+	CipherSuites []string `yaml:"cipherSuites" json:"cipherSuites" env:"CIPHER_SUITES"`
+	// ReloadInterval is how often the certificate files are checked for
+	// changes. They are also reloaded on SIGHUP.
+	ReloadInterval Duration `yaml:"reloadInterval" json:"reloadInterval" env:"RELOAD_INTERVAL"`
+	// RedirectPort, when set, serves plain HTTP redirecting to HTTPS.
+	RedirectPort int `yaml:"redirectPort" json:"redirectPort" env:"REDIRECT_PORT"`
+}
+
+type MongoConfig struct {
+	URI            string   `yaml:"uri" json:"uri" env:"MONGO_URI"`
+	Database       string   `yaml:"database" json:"database" env:"DB_NAME"`
+	ConnectTimeout Duration `yaml:"connectTimeout" json:"connectTimeout" env:"MONGO_CONNECT_TIMEOUT"`
+	PingTimeout    Duration `yaml:"pingTimeout" json:"pingTimeout" env:"MONGO_PING_TIMEOUT"`
+}
+
+type LogConfig struct {
+	Level string `yaml:"level" json:"level" env:"LOG_LEVEL"`
+}
+
+// CORSConfig is the cross origin policy of the API. Origins are matched
+// exactly or, written as https://*.example.com, by subdomain. Plugins may
+// allow further origins, e.g. those registered by tenants.
+type CORSConfig struct {
+	AllowedOrigins   []string `yaml:"allowedOrigins" json:"allowedOrigins" env:"CORS_ALLOWED_ORIGINS"`
+	AllowedMethods   []string `yaml:"allowedMethods" json:"allowedMethods" env:"CORS_ALLOWED_METHODS"`
+	AllowedHeaders   []string `yaml:"allowedHeaders" json:"allowedHeaders" env:"CORS_ALLOWED_HEADERS"`
+	ExposedHeaders   []string `yaml:"exposedHeaders" json:"exposedHeaders" env:"CORS_EXPOSED_HEADERS"`
+	AllowCredentials bool     `yaml:"allowCredentials" json:"allowCredentials" env:"CORS_ALLOW_CREDENTIALS"`
+	MaxAge           int      `yaml:"maxAge" json:"maxAge" env:"CORS_MAX_AGE"`
+	// TenantOriginsTTL is how long the answer to whether a tenant registered
+	// an origin is cached.
+	TenantOriginsTTL Duration `yaml:"tenantOriginsTTL" json:"tenantOriginsTTL" env:"CORS_TENANT_ORIGINS_TTL"`
+}
+
+// RateLimitConfig holds one policy per dimension. Every policy that applies
+// to a request must allow it.
+type RateLimitConfig struct {
+	// IP limits every API request by client address.
+	IP RateLimitPolicy `yaml:"ip" json:"ip" env:"RATE_LIMIT_IP"`
+	// APIKey limits requests carrying an X-API-Key header by key.
+	APIKey RateLimitPolicy `yaml:"apiKey" json:"apiKey" env:"RATE_LIMIT_API_KEY"`
+	// Auth limits the credential endpoints, sign up and login, by client
+	// address.
+	Auth RateLimitPolicy `yaml:"auth" json:"auth" env:"RATE_LIMIT_AUTH"`
+	// User and Tenant limit authenticated requests by user and by tenant.
+	User   RateLimitPolicy `yaml:"user" json:"user" env:"RATE_LIMIT_USER"`
+	Tenant RateLimitPolicy `yaml:"tenant" json:"tenant" env:"RATE_LIMIT_TENANT"`
+}
+
+// RateLimitPolicy allows Requests per Window with bursts of up to Burst
+// requests, Burst defaults to Requests. Zero requests disable the policy.
+type RateLimitPolicy struct {
+	Requests int      `yaml:"requests" json:"requests" env:"REQUESTS"`
+	Window   Duration `yaml:"window" json:"window" env:"WINDOW"`
+	Burst    int      `yaml:"burst" json:"burst" env:"BURST"`
+}
+
+type AuthConfig struct {
+	Issuer       string       `yaml:"issuer" json:"issuer" env:"AUTH_ISSUER"`
+	AccessToken  TokenConfig  `yaml:"accessToken" json:"accessToken" env:"AUTH_ACCESS_TOKEN"`
+	RefreshToken TokenConfig  `yaml:"refreshToken" json:"refreshToken" env:"AUTH_REFRESH_TOKEN"`
+	Cookie       CookieConfig `yaml:"cookie" json:"cookie"`
+	UserCache    CacheConfig  `yaml:"userCache" json:"userCache"`
+	// Introspection configures the RFC 7662 token introspection endpoint.
+	Introspection IntrospectionConfig `yaml:"introspection" json:"introspection"`
+	// InvitationTTL is how long an invitation into a tenant can be accepted.
+	InvitationTTL Duration `yaml:"invitationTTL" json:"invitationTTL" env:"AUTH_INVITATION_TTL"`
+}
+
+// IntrospectionConfig lists the clients allowed to introspect tokens as
+// "id:secret" pairs. The endpoint rejects every request when it is empty.
+type IntrospectionConfig struct {
+	Clients []string `yaml:"clients" json:"clients" env:"AUTH_INTROSPECTION_CLIENTS"`
+}
+
+// TokenConfig describes one kind of JWT. The env tag of the parent field is
+// used as a prefix for the env names below.
+type TokenConfig struct {
+	PrivateKeyPath string   `yaml:"privateKeyPath" json:"privateKeyPath" env:"PRIVATE_KEY_PATH"`
+	PublicKeyPath  string   `yaml:"publicKeyPath" json:"publicKeyPath" env:"PUBLIC_KEY_PATH"`
+	TTL            Duration `yaml:"ttl" json:"ttl" env:"TTL"`
+	// CookieMaxAge is how long the browser keeps the token cookie.
+	CookieMaxAge Duration `yaml:"cookieMaxAge" json:"cookieMaxAge" env:"COOKIE_MAX_AGE"`
+}
+
+type CookieConfig struct {
+	Secure   bool   `yaml:"secure" json:"secure" env:"AUTH_COOKIE_SECURE"`
+	Domain   string `yaml:"domain" json:"domain" env:"AUTH_COOKIE_DOMAIN"`
+	SameSite string `yaml:"sameSite" json:"sameSite" env:"AUTH_COOKIE_SAME_SITE"`
+}
+
+type CacheConfig struct {
+	MaxEntries int      `yaml:"maxEntries" json:"maxEntries" env:"AUTH_USER_CACHE_MAX_ENTRIES"`
+	TTL        Duration `yaml:"ttl" json:"ttl" env:"AUTH_USER_CACHE_TTL"`
+}
+
+type EventsConfig struct {
+	RelayInterval  Duration `yaml:"relayInterval" json:"relayInterval" env:"EVENTS_RELAY_INTERVAL"`
+	RelayBatchSize int      `yaml:"relayBatchSize" json:"relayBatchSize" env:"EVENTS_RELAY_BATCH_SIZE"`
+}
+
+type CompressionConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled" env:"COMPRESSION_ENABLED"`
+	// MinSize is the body size in bytes below which responses are not
+	// compressed.
+	MinSize int `yaml:"minSize" json:"minSize" env:"COMPRESSION_MIN_SIZE"`
+	// ContentTypes lists the media types to compress, "text/" matches every
+	// text type.
+	ContentTypes []string `yaml:"contentTypes" json:"contentTypes" env:"COMPRESSION_CONTENT_TYPES"`
+}
+
+// IdempotencyConfig configures the replay of requests sent with an
+// Idempotency-Key header.
+type IdempotencyConfig struct {
+	// TTL is how long the response to a key is kept for retries.
+	TTL Duration `yaml:"ttl" json:"ttl" env:"IDEMPOTENCY_TTL"`
+}
+
+// WebhooksConfig configures the delivery of events to the URLs tenants
+// subscribe.
+type WebhooksConfig struct {
+	// PollInterval is how often the delivery queue is polled once drained.
+	PollInterval Duration `yaml:"pollInterval" json:"pollInterval" env:"WEBHOOKS_POLL_INTERVAL"`
+	BatchSize    int      `yaml:"batchSize" json:"batchSize" env:"WEBHOOKS_BATCH_SIZE"`
+	// Timeout bounds a single delivery request.
+	Timeout Duration `yaml:"timeout" json:"timeout" env:"WEBHOOKS_TIMEOUT"`
+	// MaxAttempts is the number of attempts after which a delivery fails.
+	MaxAttempts int `yaml:"maxAttempts" json:"maxAttempts" env:"WEBHOOKS_MAX_ATTEMPTS"`
+	// InitialBackoff is the delay before the first retry, doubled on each
+	// following one up to MaxBackoff.
+	InitialBackoff Duration `yaml:"initialBackoff" json:"initialBackoff" env:"WEBHOOKS_INITIAL_BACKOFF"`
+	MaxBackoff     Duration `yaml:"maxBackoff" json:"maxBackoff" env:"WEBHOOKS_MAX_BACKOFF"`
+	// AllowInsecure accepts http URLs and private network addresses, for
+	// local development only.
+	AllowInsecure bool `yaml:"allowInsecure" json:"allowInsecure" env:"WEBHOOKS_ALLOW_INSECURE"`
+}
+
+type TracingConfig struct {
+	// Exporter is either "none" or "otlp".
+	Exporter    string `yaml:"exporter" json:"exporter" env:"TRACING_EXPORTER"`
+	ServiceName string `yaml:"serviceName" json:"serviceName" env:"OTEL_SERVICE_NAME"`
+	// Endpoint is the OTLP/HTTP collector address as host:port.
+	Endpoint    string  `yaml:"endpoint" json:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	Insecure    bool    `yaml:"insecure" json:"insecure" env:"TRACING_INSECURE"`
+	SampleRatio float64 `yaml:"sampleRatio" json:"sampleRatio" env:"TRACING_SAMPLE_RATIO"`
+}
+
+// Default returns the configuration used when nothing else is set.
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:            3000,
+			ReadTimeout:     Duration(10 * time.Second),
+			WriteTimeout:    Duration(10 * time.Second),
+			ShutdownTimeout: Duration(5 * time.Second),
+			MaxHeaderBytes:  1 << 20,
+			GRPC: GRPCConfig{
+				Port:       9090,
+				Reflection: true,
+			},
+			TLS: TLSConfig{
+				MinVersion:     "1.2",
+				ReloadInterval: Duration(time.Minute),
+			},
+		},
+		Mongo: MongoConfig{
+			ConnectTimeout: Duration(10 * time.Second),
+			PingTimeout:    Duration(2 * time.Second),
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+		CORS: CORSConfig{
+			// set per environment, e.g. http://localhost:5173 in development
+			AllowedOrigins:   []string{},
+			AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+			AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "If-Match", "X-Request-ID", "Idempotency-Key"},
+			ExposedHeaders:   []string{"Link", "ETag", "X-Request-ID", "Deprecation", "Sunset", "Idempotent-Replayed"},
+			AllowCredentials: true,
+			MaxAge:           300, // Maximum value not ignored by any of major browsers
+			TenantOriginsTTL: Duration(time.Minute),
+		},
+		RateLimit: RateLimitConfig{
+			IP:     RateLimitPolicy{Requests: 600, Window: Duration(time.Minute)},
+			APIKey: RateLimitPolicy{Requests: 1200, Window: Duration(time.Minute)},
+			Auth:   RateLimitPolicy{Requests: 10, Window: Duration(time.Minute)},
+			User:   RateLimitPolicy{Requests: 300, Window: Duration(time.Minute)},
+			Tenant: RateLimitPolicy{Requests: 3000, Window: Duration(time.Minute)},
+		},
+		Auth: AuthConfig{
+			Issuer: "cleanarch.service",
+			AccessToken: TokenConfig{
+				PrivateKeyPath: "./keys/auth-private.pem",
+				PublicKeyPath:  "./keys/auth-public.pem",
+				TTL:            Duration(10 * time.Minute),
+				CookieMaxAge:   Duration(time.Hour),
+			},
+			RefreshToken: TokenConfig{
+				PrivateKeyPath: "./keys/auth-refresh-private.pem",
+				PublicKeyPath:  "./keys/auth-refresh-public.pem",
+				TTL:            Duration(100 * time.Minute),
+				CookieMaxAge:   Duration(10 * time.Hour),
+			},
+			Cookie: CookieConfig{
+				SameSite: "lax",
+			},
+			UserCache: CacheConfig{
+				MaxEntries: 10000,
+				TTL:        Duration(time.Minute),
+			},
+			InvitationTTL: Duration(7 * 24 * time.Hour),
+		},
+		Events: EventsConfig{
+			RelayInterval:  Duration(time.Second),
+			RelayBatchSize: 100,
+		},
+		Compression: CompressionConfig{
+			Enabled: true,
+			MinSize: 1024,
+			ContentTypes: []string{
+				"application/json",
+				"application/problem+json",
+				"application/javascript",
+				"image/svg+xml",
+				"text/",
+			},
+		},
+		Idempotency: IdempotencyConfig{
+			TTL: Duration(24 * time.Hour),
+		},
+		Webhooks: WebhooksConfig{
+			PollInterval:   Duration(time.Second),
+			BatchSize:      50,
+			Timeout:        Duration(10 * time.Second),
+			MaxAttempts:    8,
+			InitialBackoff: Duration(30 * time.Second),
+			MaxBackoff:     Duration(6 * time.Hour),
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			ServiceName: "cleanarch",
+			Endpoint:    "localhost:4318",
+			SampleRatio: 1,
+		},
+	}
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoad_Precedence(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	os.WriteFile(file, []byte(`
+server:
+  port: 4000
+  readTimeout: 3s
+mongo:
+  uri: mongodb://file
+  database: file
+auth:
+  accessToken:
+    ttl: 5m
+`), 0o600)
+
+	t.Setenv("MONGO_URI", "mongodb://env")
+	t.Setenv("AUTH_ACCESS_TOKEN_TTL", "7m")
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://a.example, https://b.example")
+
+	cfg, err := Load([]string{"-config", file, "-env-file", filepath.Join(dir, "missing.env"), "-server.port", "5000"})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.Server.Port != 5000 {
+		t.Errorf("flag should win over file, port = %d", cfg.Server.Port)
+	}
+	if cfg.Server.ReadTimeout.Std() != 3*time.Second {
+		t.Errorf("file should win over defaults, readTimeout = %s", cfg.Server.ReadTimeout)
+	}
+	if cfg.Mongo.URI != "mongodb://env" || cfg.Mongo.Database != "file" {
+		t.Errorf("env should win over file, mongo = %+v", cfg.Mongo)
+	}
+	if cfg.Auth.AccessToken.TTL.Std() != 7*time.Minute {
+		t.Errorf("nested env prefix not applied, ttl = %s", cfg.Auth.AccessToken.TTL)
+	}
+	if got := strings.Join(cfg.CORS.AllowedOrigins, "|"); got != "https://a.example|https://b.example" {
+		t.Errorf("allowedOrigins = %q", got)
+	}
+	if cfg.Server.WriteTimeout.Std() != 10*time.Second {
+		t.Errorf("defaults should apply, writeTimeout = %s", cfg.Server.WriteTimeout)
+	}
+}
+
+func TestLoad_UnknownFileKey(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(file, []byte(`{"server": {"prot": 1}}`), 0o600)
+
+	if _, err := Load([]string{"-config", file, "-env-file", ""}); err == nil {
+		t.Fatal("expected unknown key to be rejected")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := Default()
+	cfg.Server.Port = 0
+	cfg.Auth.RefreshToken.TTL = cfg.Auth.AccessToken.TTL
+	cfg.CORS.AllowedOrigins = []string{"https://*"}
+	cfg.Webhooks.MaxAttempts = 0
+	cfg.Auth.InvitationTTL = 0
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, want := range []string{"server.port", "mongo.uri", "mongo.database", "auth.refreshToken.ttl", "cors.allowedOrigins", "webhooks.maxAttempts", "auth.invitationTTL"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Load resolves the configuration from defaults, the config file, the
+// environment and the command line arguments (without the program name), then
+// validates it.
+func Load(args []string) (*Config, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("api", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML or JSON config file")
+	envFile := fs.String("env-file", ".env", "path to a dotenv file loaded into the environment if it exists")
+
+	// flags are recorded while parsing and applied last so they win over the
+	// file and env, which are only known once -config has been parsed
+	type override struct{ path, value string }
+	var overrides []override
+	for _, l := range leaves(reflect.ValueOf(cfg).Elem(), "", "") {
+		path := l.path
+		usage := "sets " + path
+		if l.env != "" {
+			usage += " (env " + l.env + ")"
+		}
+		fs.Func(path, usage, func(value string) error {
+			overrides = append(overrides, override{path, value})
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	// existing environment variables take precedence over the dotenv file
+	if err := godotenv.Load(*envFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("config: loading %s: %w", *envFile, err)
+	}
+	if *configFile != "" {
+		if err := loadFile(*configFile, cfg); err != nil {
+			return nil, err
+		}
+	}
+	if err := applyEnv(cfg); err != nil {
+		return nil, err
+	}
+
+	fields := map[string]leaf{}
+	for _, l := range leaves(reflect.ValueOf(cfg).Elem(), "", "") {
+		fields[l.path] = l
+	}
+	for _, o := range overrides {
+		if err := set(fields[o.path].value, o.value); err != nil {
+			return nil, fmt.Errorf("config: flag -%s: %w", o.path, err)
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadFile merges a YAML or JSON file into cfg. Unknown keys are rejected so
+// that typos do not go unnoticed.
+func loadFile(path string, cfg *Config) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(raw))
+		dec.KnownFields(true)
+		err = dec.Decode(cfg)
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(cfg)
+	default:
+		return fmt.Errorf("config: unsupported file type %q, use .yaml, .yml or .json", path)
+	}
+	if err != nil {
+		return fmt.Errorf("config: parsing %s: %w", path, err)
+	}
+	return nil
+}
+
+func applyEnv(cfg *Config) error {
+	for _, l := range leaves(reflect.ValueOf(cfg).Elem(), "", "") {
+		if l.env == "" {
+			continue
+		}
+		value, ok := os.LookupEnv(l.env)
+		if !ok {
+			continue
+		}
+		if err := set(l.value, value); err != nil {
+			return fmt.Errorf("config: env %s: %w", l.env, err)
+		}
+	}
+	return nil
+}
+
+// leaf is a settable scalar field of the config tree.
+type leaf struct {
+	path  string
+	env   string
+	value reflect.Value
+}
+
+// leaves walks the config struct. Paths join the yaml names with dots; env
+// names of nested structs are prefixed with the parent's env tag.
+func leaves(v reflect.Value, path, envPrefix string) []leaf {
+	var out []leaf
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if path != "" {
+			name = path + "." + name
+		}
+		env := field.Tag.Get("env")
+		if env != "" && envPrefix != "" {
+			env = envPrefix + "_" + env
+		}
+
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct && !isSettable(fv) {
+			out = append(out, leaves(fv, name, env)...)
+			continue
+		}
+		out = append(out, leaf{path: name, env: env, value: fv})
+	}
+	return out
+}
+
+type setter interface {
+	Set(string) error
+}
+
+func isSettable(v reflect.Value) bool {
+	_, ok := v.Addr().Interface().(setter)
+	return ok
+}
+
+// set parses value into the field v.
+func set(v reflect.Value, value string) error {
+	if s, ok := v.Addr().Interface().(setter); ok {
+		return s.Set(value)
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		v.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported config type %s", v.Type())
+	}
+	return nil
+}
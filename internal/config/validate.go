@@ -0,0 +1,115 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"cleanarch/boiler/internal/utils/origin"
+)
+
+// Validate reports every invalid setting at once so a broken deployment can
+// be fixed in one go.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port < 65536, "server.port must be between 1 and 65535, got %d", c.Server.Port)
+	check(c.Server.ReadTimeout > 0, "server.readTimeout must be positive")
+	check(c.Server.WriteTimeout > 0, "server.writeTimeout must be positive")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdownTimeout must be positive")
+	check(c.Server.DrainDelay >= 0, "server.drainDelay must not be negative")
+	check(c.Server.MaxHeaderBytes > 0, "server.maxHeaderBytes must be positive")
+	if c.Server.GRPC.Enabled {
+		check(c.Server.GRPC.Port > 0 && c.Server.GRPC.Port < 65536, "server.grpc.port must be between 1 and 65535, got %d", c.Server.GRPC.Port)
+		check(c.Server.GRPC.Port != c.Server.Port, "server.grpc.port must differ from server.port")
+	}
+	if c.Server.TLS.Enabled {
+		check(c.Server.TLS.CertFile != "", "server.tls.certFile is required when tls is enabled")
+		check(c.Server.TLS.KeyFile != "", "server.tls.keyFile is required when tls is enabled")
+		check(oneOf(c.Server.TLS.MinVersion, "1.2", "1.3"), "server.tls.minVersion must be one of 1.2, 1.3, got %q", c.Server.TLS.MinVersion)
+		check(c.Server.TLS.ReloadInterval > 0, "server.tls.reloadInterval must be positive")
+		check(c.Server.TLS.RedirectPort >= 0 && c.Server.TLS.RedirectPort < 65536, "server.tls.redirectPort must be between 0 and 65535")
+		check(c.Server.TLS.RedirectPort != c.Server.Port, "server.tls.redirectPort must differ from server.port")
+	}
+
+	check(c.Mongo.URI != "", "mongo.uri is required (env MONGO_URI)")
+	check(c.Mongo.Database != "", "mongo.database is required (env DB_NAME)")
+	check(c.Mongo.ConnectTimeout > 0, "mongo.connectTimeout must be positive")
+	check(c.Mongo.PingTimeout > 0, "mongo.pingTimeout must be positive")
+
+	check(oneOf(c.Log.Level, "debug", "info", "warn", "error"), "log.level must be one of debug, info, warn, error, got %q", c.Log.Level)
+
+	for _, pattern := range c.CORS.AllowedOrigins {
+		_, err := origin.ParsePattern(pattern)
+		check(err == nil, "cors.allowedOrigins: %v", err)
+	}
+	check(c.CORS.TenantOriginsTTL > 0, "cors.tenantOriginsTTL must be positive")
+	check(c.CORS.MaxAge >= 0, "cors.maxAge must not be negative")
+
+	for name, p := range map[string]RateLimitPolicy{
+		"ip": c.RateLimit.IP, "apiKey": c.RateLimit.APIKey, "auth": c.RateLimit.Auth,
+		"user": c.RateLimit.User, "tenant": c.RateLimit.Tenant,
+	} {
+		check(p.Requests >= 0, "rateLimit.%s.requests must not be negative", name)
+		check(p.Requests == 0 || p.Window > 0, "rateLimit.%s.window must be positive", name)
+		check(p.Burst >= 0, "rateLimit.%s.burst must not be negative", name)
+	}
+
+	check(c.Auth.Issuer != "", "auth.issuer is required")
+	for name, token := range map[string]TokenConfig{"accessToken": c.Auth.AccessToken, "refreshToken": c.Auth.RefreshToken} {
+		check(token.PrivateKeyPath != "", "auth.%s.privateKeyPath is required", name)
+		check(token.PublicKeyPath != "", "auth.%s.publicKeyPath is required", name)
+		check(token.TTL > 0, "auth.%s.ttl must be positive", name)
+		check(token.CookieMaxAge > 0, "auth.%s.cookieMaxAge must be positive", name)
+	}
+	check(c.Auth.RefreshToken.TTL > c.Auth.AccessToken.TTL, "auth.refreshToken.ttl must be longer than auth.accessToken.ttl")
+	check(oneOf(c.Auth.Cookie.SameSite, "lax", "strict", "none"), "auth.cookie.sameSite must be one of lax, strict, none, got %q", c.Auth.Cookie.SameSite)
+	check(c.Auth.Cookie.SameSite != "none" || c.Auth.Cookie.Secure, "auth.cookie.sameSite none requires auth.cookie.secure")
+	check(c.Auth.UserCache.MaxEntries >= 0, "auth.userCache.maxEntries must not be negative")
+	check(c.Auth.UserCache.TTL >= 0, "auth.userCache.ttl must not be negative")
+	check(c.Auth.InvitationTTL > 0, "auth.invitationTTL must be positive")
+	clientIDs := make(map[string]bool, len(c.Auth.Introspection.Clients))
+	for i, client := range c.Auth.Introspection.Clients {
+		id, secret, ok := strings.Cut(client, ":")
+		check(ok && id != "" && secret != "", "auth.introspection.clients[%d] must be an id:secret pair", i)
+		check(!clientIDs[id], "auth.introspection.clients[%d] repeats client %q", i, id)
+		clientIDs[id] = true
+	}
+
+	check(c.Events.RelayInterval > 0, "events.relayInterval must be positive")
+	check(c.Events.RelayBatchSize > 0, "events.relayBatchSize must be positive")
+
+	check(c.Compression.MinSize >= 0, "compression.minSize must not be negative")
+
+	check(c.Idempotency.TTL > 0, "idempotency.ttl must be positive")
+
+	check(c.Webhooks.PollInterval > 0, "webhooks.pollInterval must be positive")
+	check(c.Webhooks.BatchSize > 0, "webhooks.batchSize must be positive")
+	check(c.Webhooks.Timeout > 0, "webhooks.timeout must be positive")
+	check(c.Webhooks.MaxAttempts > 0, "webhooks.maxAttempts must be positive")
+	check(c.Webhooks.InitialBackoff > 0, "webhooks.initialBackoff must be positive")
+	check(c.Webhooks.MaxBackoff >= c.Webhooks.InitialBackoff, "webhooks.maxBackoff must not be below webhooks.initialBackoff")
+
+	check(oneOf(c.Tracing.Exporter, "none", "otlp"), "tracing.exporter must be one of none, otlp, got %q", c.Tracing.Exporter)
+	check(c.Tracing.Exporter != "otlp" || c.Tracing.Endpoint != "", "tracing.endpoint is required for the otlp exporter")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sampleRatio must be between 0 and 1")
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
+
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if strings.EqualFold(value, a) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,25 +0,0 @@
-// Package classification cleanarch
-//
-// Documentation for my go project
-//
-//	Schemes: http
-//	BasePath: /
-//	Version: 1.0.0
-//	Host: 127.0.0.1:3000
-//
-//	Consumes:
-//	- application/json
-//	- multipart/form-data
-//
-//	Produces:
-//	- application/json
-//
-//	Security:
-//	- basic
-//
-//	SecurityDefinitions:
-//	basic:
-//	  type: basic
-//
-// swagger:meta
-package docs
@@ -0,0 +1,64 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"cleanarch/boiler/internal/utils/logger"
+)
+
+// Wildcard subscribes a handler to every event.
+const Wildcard = "*"
+
+// Bus is an in-process subscriber registry. It does not persist anything;
+// events reach it through the outbox Relay.
+type Bus struct {
+	l        logger.Interface
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+func NewBus(l logger.Interface) *Bus {
+	return &Bus{
+		l:        l,
+		handlers: make(map[string][]Handler),
+	}
+}
+
+// Subscribe registers handler for events with the given name, or for every
+// event when name is Wildcard.
+func (b *Bus) Subscribe(name string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[name] = append(b.handlers[name], handler)
+}
+
+// Dispatch calls every handler subscribed to the event. All handlers run even
+// if one fails; the returned error joins all failures.
+func (b *Bus) Dispatch(ctx context.Context, event Event) error {
+	b.mu.RLock()
+	handlers := append(append([]Handler{}, b.handlers[event.Name]...), b.handlers[Wildcard]...)
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, handler := range handlers {
+		if err := b.call(ctx, handler, event); err != nil {
+			b.l.Error("event handler failed", "event", event.Name, "id", event.ID, "error", err)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// call runs a handler, turning a panic into an error so one misbehaving
+// subscriber cannot take the relay down.
+func (b *Bus) call(ctx context.Context, handler Handler, event Event) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("event handler panicked: %v", rec)
+		}
+	}()
+	return handler(ctx, event)
+}
@@ -0,0 +1,40 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event is something that happened in a domain that other parts of the
+// system may want to react to.
+type Event struct {
+	ID          string
+	Name        string
+	AggregateID string
+	TenantID    string
+	OccurredAt  time.Time
+	Payload     map[string]string
+}
+
+// New creates an event with a fresh id and the current time.
+func New(name, aggregateID, tenantID string, payload map[string]string) Event {
+	return Event{
+		ID:          uuid.NewString(),
+		Name:        name,
+		AggregateID: aggregateID,
+		TenantID:    tenantID,
+		OccurredAt:  time.Now().UTC(),
+		Payload:     payload,
+	}
+}
+
+// Publisher is what usecases call to emit events.
+type Publisher interface {
+	Publish(ctx context.Context, events ...Event) error
+}
+
+// Handler reacts to a single event. Delivery is at least once so handlers
+// must be idempotent, the event ID can be used to detect duplicates.
+type Handler func(ctx context.Context, event Event) error
@@ -0,0 +1,99 @@
+package events
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryOutbox is an OutboxStore kept in process memory. It has no
+// transactional guarantees and is meant for tests and local development.
+type MemoryOutbox struct {
+	mu      sync.Mutex
+	records map[string]*memoryRecord
+}
+
+type memoryRecord struct {
+	OutboxRecord
+	availableAt time.Time
+	delivered   bool
+	lastError   string
+}
+
+func NewMemoryOutbox() *MemoryOutbox {
+	return &MemoryOutbox{
+		records: make(map[string]*memoryRecord),
+	}
+}
+
+func (m *MemoryOutbox) Append(ctx context.Context, events ...Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, event := range events {
+		m.records[event.ID] = &memoryRecord{
+			OutboxRecord: OutboxRecord{Event: event},
+			availableAt:  event.OccurredAt,
+		}
+	}
+	return nil
+}
+
+func (m *MemoryOutbox) Claim(ctx context.Context, limit int, lease time.Duration) ([]OutboxRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+
+	var due []*memoryRecord
+	for _, record := range m.records {
+		if !record.delivered && !record.availableAt.After(now) {
+			due = append(due, record)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].OccurredAt.Before(due[j].OccurredAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := make([]OutboxRecord, 0, len(due))
+	for _, record := range due {
+		record.Attempts++
+		record.availableAt = now.Add(lease)
+		claimed = append(claimed, record.OutboxRecord)
+	}
+	return claimed, nil
+}
+
+func (m *MemoryOutbox) MarkDelivered(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if record, ok := m.records[id]; ok {
+		record.delivered = true
+	}
+	return nil
+}
+
+func (m *MemoryOutbox) MarkFailed(ctx context.Context, id string, cause error, retryAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if record, ok := m.records[id]; ok {
+		record.availableAt = retryAt
+		record.lastError = cause.Error()
+	}
+	return nil
+}
+
+// Pending returns the number of events not delivered yet.
+func (m *MemoryOutbox) Pending() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for _, record := range m.records {
+		if !record.delivered {
+			n++
+		}
+	}
+	return n
+}
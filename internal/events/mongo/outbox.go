@@ -0,0 +1,130 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cleanarch/boiler/internal/events"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const outboxCollection = "outbox"
+
+// deliveredRetention is how long delivered events are kept before the TTL
+// index removes them.
+const deliveredRetention = 7 * 24 * time.Hour
+
+type outboxRecord struct {
+	ID          string            `bson:"_id"`
+	Name        string            `bson:"name"`
+	AggregateID string            `bson:"aggregateId"`
+	TenantID    string            `bson:"tenantId,omitempty"`
+	Payload     map[string]string `bson:"payload,omitempty"`
+	OccurredAt  time.Time         `bson:"occurredAt"`
+	AvailableAt time.Time         `bson:"availableAt"`
+	Attempts    int               `bson:"attempts"`
+	LastError   string            `bson:"lastError,omitempty"`
+	DeliveredAt *time.Time        `bson:"deliveredAt,omitempty"`
+}
+
+// OutboxStore keeps the outbox in a Mongo collection. Appending with a
+// session context writes the events inside the caller's transaction.
+type OutboxStore struct {
+	db *mongo.Database
+}
+
+func NewOutboxStore(db *mongo.Database) *OutboxStore {
+	return &OutboxStore{
+		db: db,
+	}
+}
+
+// CreateIndexes creates the index used to find due records and the TTL index
+// that purges delivered ones.
+func (s *OutboxStore) CreateIndexes(ctx context.Context) error {
+	_, err := s.db.Collection(outboxCollection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "deliveredAt", Value: 1}, {Key: "availableAt", Value: 1}}},
+		{
+			Keys:    bson.D{{Key: "deliveredAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(deliveredRetention.Seconds())).SetName("deliveredAt_ttl"),
+		},
+	})
+	return err
+}
+
+func (s *OutboxStore) Append(ctx context.Context, evts ...events.Event) error {
+	docs := make([]interface{}, 0, len(evts))
+	for _, event := range evts {
+		docs = append(docs, outboxRecord{
+			ID:          event.ID,
+			Name:        event.Name,
+			AggregateID: event.AggregateID,
+			TenantID:    event.TenantID,
+			Payload:     event.Payload,
+			OccurredAt:  event.OccurredAt,
+			AvailableAt: event.OccurredAt,
+		})
+	}
+	_, err := s.db.Collection(outboxCollection).InsertMany(ctx, docs)
+	return err
+}
+
+// Claim leases due records one at a time with FindOneAndUpdate so that
+// several relays can poll the same collection without delivering a record
+// twice within a lease.
+func (s *OutboxStore) Claim(ctx context.Context, limit int, lease time.Duration) ([]events.OutboxRecord, error) {
+	coll := s.db.Collection(outboxCollection)
+	now := time.Now().UTC()
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "availableAt", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var claimed []events.OutboxRecord
+	for len(claimed) < limit {
+		record := new(outboxRecord)
+		err := coll.FindOneAndUpdate(ctx, bson.M{
+			"deliveredAt": nil,
+			"availableAt": bson.M{"$lte": now},
+		}, bson.M{
+			"$set": bson.M{"availableAt": now.Add(lease)},
+			"$inc": bson.M{"attempts": 1},
+		}, opts).Decode(record)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			break
+		}
+		if err != nil {
+			return claimed, err
+		}
+		claimed = append(claimed, events.OutboxRecord{
+			Event: events.Event{
+				ID:          record.ID,
+				Name:        record.Name,
+				AggregateID: record.AggregateID,
+				TenantID:    record.TenantID,
+				OccurredAt:  record.OccurredAt,
+				Payload:     record.Payload,
+			},
+			Attempts: record.Attempts,
+		})
+	}
+	return claimed, nil
+}
+
+func (s *OutboxStore) MarkDelivered(ctx context.Context, id string) error {
+	_, err := s.db.Collection(outboxCollection).UpdateByID(ctx, id, bson.M{
+		"$set":   bson.M{"deliveredAt": time.Now().UTC()},
+		"$unset": bson.M{"lastError": ""},
+	})
+	return err
+}
+
+func (s *OutboxStore) MarkFailed(ctx context.Context, id string, cause error, retryAt time.Time) error {
+	_, err := s.db.Collection(outboxCollection).UpdateByID(ctx, id, bson.M{
+		"$set": bson.M{"availableAt": retryAt.UTC(), "lastError": cause.Error()},
+	})
+	return err
+}
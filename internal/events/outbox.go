@@ -0,0 +1,47 @@
+package events
+
+import (
+	"context"
+	"time"
+)
+
+// OutboxRecord is an event waiting in the outbox together with its delivery
+// bookkeeping.
+type OutboxRecord struct {
+	Event
+	Attempts int
+}
+
+// OutboxStore persists events so they can be written in the same unit of work
+// as the state change that produced them and delivered afterwards.
+type OutboxStore interface {
+	// Append stores events. Implementations must honour a transaction carried
+	// by ctx.
+	Append(ctx context.Context, events ...Event) error
+	// Claim leases up to limit undelivered records that are due, hiding them
+	// from other relays for the lease duration.
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]OutboxRecord, error)
+	// MarkDelivered records a successful delivery.
+	MarkDelivered(ctx context.Context, id string) error
+	// MarkFailed makes the record available again at retryAt.
+	MarkFailed(ctx context.Context, id string, cause error, retryAt time.Time) error
+}
+
+// OutboxPublisher is a Publisher that writes events to an OutboxStore. When
+// called inside a transaction the events commit or roll back with it.
+type OutboxPublisher struct {
+	store OutboxStore
+}
+
+func NewOutboxPublisher(store OutboxStore) *OutboxPublisher {
+	return &OutboxPublisher{
+		store: store,
+	}
+}
+
+func (p *OutboxPublisher) Publish(ctx context.Context, events ...Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	return p.store.Append(ctx, events...)
+}
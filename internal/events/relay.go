@@ -0,0 +1,112 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"cleanarch/boiler/internal/utils/logger"
+)
+
+// RelayOptions tunes how the Relay polls the outbox.
+type RelayOptions struct {
+	// Interval between polls when the outbox is drained.
+	Interval time.Duration
+	// BatchSize is the maximum number of records claimed per poll.
+	BatchSize int
+	// Lease is how long a claimed record stays hidden from other relays.
+	Lease time.Duration
+	// MaxBackoff caps the exponential retry delay of failed deliveries.
+	MaxBackoff time.Duration
+}
+
+func (o RelayOptions) withDefaults() RelayOptions {
+	if o.Interval <= 0 {
+		o.Interval = time.Second
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = 100
+	}
+	if o.Lease <= 0 {
+		o.Lease = 30 * time.Second
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = 5 * time.Minute
+	}
+	return o
+}
+
+// Relay moves events from the outbox to the Bus. A record is only marked
+// delivered after every handler succeeded, so delivery is at least once.
+type Relay struct {
+	l     logger.Interface
+	store OutboxStore
+	bus   *Bus
+	opts  RelayOptions
+}
+
+func NewRelay(l logger.Interface, store OutboxStore, bus *Bus, opts RelayOptions) *Relay {
+	return &Relay{
+		l:     l,
+		store: store,
+		bus:   bus,
+		opts:  opts.withDefaults(),
+	}
+}
+
+// Run polls the outbox until ctx is cancelled.
+func (r *Relay) Run(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		n, err := r.RelayOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.l.Error("outbox relay failed", "error", err)
+		}
+		// keep draining while full batches come back
+		if err == nil && n == r.opts.BatchSize {
+			timer.Reset(0)
+		} else {
+			timer.Reset(r.opts.Interval)
+		}
+	}
+}
+
+// RelayOnce claims one batch of due records and dispatches them. It returns
+// the number of records claimed.
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	records, err := r.store.Claim(ctx, r.opts.BatchSize, r.opts.Lease)
+	if err != nil {
+		return 0, err
+	}
+	for _, record := range records {
+		if err := r.bus.Dispatch(ctx, record.Event); err != nil {
+			retryAt := time.Now().Add(r.backoff(record.Attempts))
+			if err := r.store.MarkFailed(ctx, record.ID, err, retryAt); err != nil {
+				r.l.Error("unable to reschedule outbox event", "id", record.ID, "error", err)
+			}
+			continue
+		}
+		if err := r.store.MarkDelivered(ctx, record.ID); err != nil {
+			r.l.Error("unable to mark outbox event delivered", "id", record.ID, "error", err)
+		}
+	}
+	return len(records), nil
+}
+
+// backoff doubles the retry delay with every attempt, starting at one second.
+func (r *Relay) backoff(attempts int) time.Duration {
+	d := time.Second
+	for i := 1; i < attempts && d < r.opts.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > r.opts.MaxBackoff {
+		d = r.opts.MaxBackoff
+	}
+	return d
+}
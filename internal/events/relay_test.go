@@ -0,0 +1,72 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cleanarch/boiler/internal/utils/logger"
+)
+
+func TestRelay_DeliversAtLeastOnce(t *testing.T) {
+	ctx := context.Background()
+	l := logger.NewLogger("error")
+	outbox := NewMemoryOutbox()
+	bus := NewBus(l)
+
+	var received []string
+	fail := true
+	bus.Subscribe("user.signed_up", func(ctx context.Context, event Event) error {
+		received = append(received, event.ID)
+		if fail {
+			fail = false
+			return errors.New("temporarily unavailable")
+		}
+		return nil
+	})
+
+	event := New("user.signed_up", "user-1", "tenant-1", nil)
+	if err := NewOutboxPublisher(outbox).Publish(ctx, event); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	relay := NewRelay(l, outbox, bus, RelayOptions{MaxBackoff: time.Nanosecond})
+	if n, err := relay.RelayOnce(ctx); err != nil || n != 1 {
+		t.Fatalf("RelayOnce() = %d, %v, want 1, nil", n, err)
+	}
+	if outbox.Pending() != 1 {
+		t.Fatal("failed delivery must stay in the outbox")
+	}
+
+	time.Sleep(time.Millisecond)
+	if n, err := relay.RelayOnce(ctx); err != nil || n != 1 {
+		t.Fatalf("RelayOnce() = %d, %v, want 1, nil", n, err)
+	}
+	if outbox.Pending() != 0 {
+		t.Fatal("event should be marked delivered after a successful retry")
+	}
+	if len(received) != 2 || received[0] != event.ID || received[1] != event.ID {
+		t.Fatalf("handler received %v, want the event twice", received)
+	}
+}
+
+func TestBus_WildcardAndPanics(t *testing.T) {
+	bus := NewBus(logger.NewLogger("error"))
+	var calls int
+	bus.Subscribe(Wildcard, func(ctx context.Context, event Event) error {
+		calls++
+		return nil
+	})
+	bus.Subscribe("tenant.created", func(ctx context.Context, event Event) error {
+		panic("boom")
+	})
+
+	err := bus.Dispatch(context.Background(), New("tenant.created", "t", "t", nil))
+	if err == nil {
+		t.Fatal("expected the panicking handler to be reported as an error")
+	}
+	if calls != 1 {
+		t.Fatalf("wildcard handler called %d times, want 1", calls)
+	}
+}
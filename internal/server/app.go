@@ -2,121 +2,528 @@ package server
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
-	"os"
 	"os/signal"
+	"slices"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/cors"
-	"github.com/go-chi/httprate"
-	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/reflection"
 
-	"cleanarch/boiler/internal/user/plugin"
+	"cleanarch/boiler/internal/config"
+	"cleanarch/boiler/internal/events"
+	eventsmongo "cleanarch/boiler/internal/events/mongo"
+	"cleanarch/boiler/internal/utils/apiversion"
+	"cleanarch/boiler/internal/utils/compress"
+	"cleanarch/boiler/internal/utils/grpcutil"
+	"cleanarch/boiler/internal/utils/idempotency"
 	"cleanarch/boiler/internal/utils/logger"
-	// authhttp "cleanarch/boiler/pkg/auth/delivery/http"
-	// authmongo "cleanarch/boiler/pkg/auth/repository/mongo"
-	// authusecase "cleanarch/boiler/pkg/auth/usecase"
+	"cleanarch/boiler/internal/utils/metrics"
+	"cleanarch/boiler/internal/utils/mongomonitor"
+	"cleanarch/boiler/internal/utils/openapi"
+	"cleanarch/boiler/internal/utils/origin"
+	"cleanarch/boiler/internal/utils/ratelimit"
+	"cleanarch/boiler/internal/utils/requestlog"
+	"cleanarch/boiler/internal/utils/tlsutil"
+	"cleanarch/boiler/internal/utils/tracing"
 )
 
 type App struct {
+	cfg    *config.Config
+	l      logger.Interface
+	router chi.Router
+	// versions holds the router of every API version served by a plugin
+	versions map[string]chi.Router
+	// spec serves the OpenAPI document, built once the plugins are mounted
+	spec       http.Handler
 	httpServer *http.Server
-	userPlugin Plugin
+	// certs is set when serving TLS, redirectServer when plain HTTP
+	// requests are redirected to HTTPS
+	certs          *tlsutil.CertReloader
+	redirectServer *http.Server
+	// grpcServer is set when server.grpc is enabled
+	grpcServer *grpc.Server
+	cors       *corsPolicy
+	plugins    *registry
+	started    []Plugin
+	deps       Deps
+	bus        *events.Bus
+	relay      *events.Relay
+	stopRelay  context.CancelFunc
+	relayDone  chan struct{}
+	// shutdownTracing flushes the spans that have not been exported yet
+	shutdownTracing func(context.Context) error
+	// hooks registered with OnShutdown
+	hooks []ShutdownHook
+	// ready is true while the app accepts traffic, see Readiness
+	ready atomic.Bool
 }
 
-type Plugin interface {
-	Register()
-}
+// NewApp builds the app described by cfg and connects to its dependencies.
+// When a step fails, whatever was already opened is released again and the
+// error is returned; the caller decides how to exit.
+func NewApp(cfg *config.Config) (_ *App, err error) {
+	logger := logger.NewLogger(cfg.Log.Level)
 
-func NewApp() *App {
-	err := godotenv.Load("./.env")
-	db := initDB()
+	// settings that can still be rejected are checked before anything is
+	// opened; plugins implementing OriginPolicy are added by startPlugins
+	allowedOrigins, err := origin.NewMatcher(cfg.CORS.AllowedOrigins)
+	if err != nil {
+		return nil, fmt.Errorf("parse cors.allowedOrigins: %w", err)
+	}
+	httpServer := &http.Server{
+		Addr:           fmt.Sprintf(":%d", cfg.Server.Port),
+		ReadTimeout:    cfg.Server.ReadTimeout.Std(),
+		WriteTimeout:   cfg.Server.WriteTimeout.Std(),
+		MaxHeaderBytes: cfg.Server.MaxHeaderBytes,
+	}
+	var certs *tlsutil.CertReloader
+	var redirectServer *http.Server
+	if cfg.Server.TLS.Enabled {
+		certs, err = tlsutil.NewCertReloader(logger, cfg.Server.TLS.CertFile, cfg.Server.TLS.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load TLS certificate: %w", err)
+		}
+		httpServer.TLSConfig, err = tlsutil.NewConfig(certs, tlsutil.Options{
+			MinVersion:   cfg.Server.TLS.MinVersion,
+			CipherSuites: cfg.Server.TLS.CipherSuites,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("configure TLS: %w", err)
+		}
+		if cfg.Server.TLS.RedirectPort > 0 {
+			redirectServer = &http.Server{
+				Addr:              fmt.Sprintf(":%d", cfg.Server.TLS.RedirectPort),
+				Handler:           tlsutil.RedirectHandler(cfg.Server.Port),
+				ReadHeaderTimeout: cfg.Server.ReadTimeout.Std(),
+			}
+		}
+	}
 
-	r := chi.NewRouter()
+	// opened resources are released in reverse order if a later step fails
+	var opened []ShutdownHook
+	defer func() {
+		if err == nil {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout.Std())
+		defer cancel()
+		slices.Reverse(opened)
+		runShutdownHooks(ctx, logger, opened)
+	}()
+
+	shutdownTracing, err := initTracing(cfg.Tracing)
+	if err != nil {
+		return nil, err
+	}
+	opened = append(opened, ShutdownHook{Name: "tracing", Fn: shutdownTracing})
 
+	reg := metrics.NewRegistry()
+	db, err := initDB(cfg.Mongo, mongomonitor.Combine(
+		metrics.NewMongo(reg).Monitor(),
+		tracing.MongoMonitor(),
+	))
 	if err != nil {
-		log.Fatal("Error loading.env file")
+		return nil, err
 	}
-	r.Use(middleware.Logger)
-	r.Use(httprate.LimitByIP(100, 1*time.Minute))
+	opened = append(opened, ShutdownHook{Name: "mongo", Fn: db.Client().Disconnect})
+
+	r := chi.NewRouter()
 
+	r.Use(requestlog.Middleware(logger))
+	r.Use(tracing.Middleware)
+	r.Use(metrics.NewHTTP(reg).Middleware)
+
+	// plugins add their own policies to their routes through Deps
+	limiter := ratelimit.NewLimiter(logger, ratelimit.NewMemoryStore(nil))
+	r.Use(limiter.Middleware(ratelimit.Policy{
+		Name:  "ip",
+		Limit: RateLimit(cfg.RateLimit.IP),
+		Key:   ratelimit.ByIP,
+	}))
+	r.Use(limiter.Middleware(ratelimit.Policy{
+		Name:  "apiKey",
+		Limit: RateLimit(cfg.RateLimit.APIKey),
+		Key:   ratelimit.ByHeader("X-API-Key"),
+	}))
+
+	// request bodies, responses are negotiated by compress.Middleware
 	r.Use(middleware.AllowContentEncoding("deflate", "gzip"))
 	r.Use(middleware.Recoverer)
 
-	//compressor := middleware.NewCompressor(5, "text/html", "text/css", "text/plain", "text/javascript", "application/json")
-	// compressor.SetEncoder("br", func(w io.Writer, level int) io.Writer {
-	// 	params := brotli_enc.NewBrotliParams()
-	// 	params.SetQuality(level)
-	// 	return brotli_enc.NewBrotliWriter(params, w)
-	// })
-	// r.Use(compressor.Handler)
-
-	r.Use(cors.Handler(cors.Options{
-		// AllowedOrigins:   []string{"https://foo.com"}, // Use this to allow specific origin hosts
-		AllowedOrigins: []string{"https://*", "http://*"},
-		// AllowOriginFunc:  func(r *http.Request, origin string) bool { return true },
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
-		AllowCredentials: false,
-		MaxAge:           300, // Maximum value not ignored by any of major browsers
-	}))
+	if cfg.Compression.Enabled {
+		r.Use(compress.Middleware(compress.Options{
+			MinSize:      cfg.Compression.MinSize,
+			ContentTypes: cfg.Compression.ContentTypes,
+		}))
+	}
 
-	httpServer := &http.Server{
-		Addr:           ":" + "3000",
-		Handler:        r,
-		ReadTimeout:    10 * time.Second,
-		WriteTimeout:   10 * time.Second,
-		MaxHeaderBytes: 1 << 20,
+	corsPolicy := &corsPolicy{static: allowedOrigins}
+	r.Use(corsPolicy.middleware(cfg.CORS))
+
+	// probes and metrics are served outside of the API middleware stack so
+	// that rate limiting and request logging do not apply to them
+	root := chi.NewRouter()
+	root.Use(middleware.Recoverer)
+	httpServer.Handler = root
+
+	var grpcServer *grpc.Server
+	if cfg.Server.GRPC.Enabled {
+		var opts grpcutil.Options
+		if httpServer.TLSConfig != nil {
+			opts.Credentials = credentials.NewTLS(httpServer.TLSConfig.Clone())
+		}
+		grpcServer = grpcutil.NewServer(logger, opts)
+		if cfg.Server.GRPC.Reflection {
+			reflection.Register(grpcServer)
+		}
 	}
-	logger := logger.NewLogger("")
-	userPlugin := plugin.NewUserPlugin(r, db, logger)
-	userPlugin.Register()
 
-	return &App{
-		userPlugin: userPlugin,
-		httpServer: httpServer,
+	// events are written to the outbox within the usecase's unit of work and
+	// relayed to bus subscribers in the background
+	outbox := eventsmongo.NewOutboxStore(db)
+	if err := outbox.CreateIndexes(context.Background()); err != nil {
+		logger.Error("unable to create outbox indexes", "error", err)
 	}
+	bus := events.NewBus(logger)
+	relay := events.NewRelay(logger, outbox, bus, events.RelayOptions{
+		Interval:  cfg.Events.RelayInterval.Std(),
+		BatchSize: cfg.Events.RelayBatchSize,
+	})
+
+	app := &App{
+		cfg:            cfg,
+		l:              logger,
+		router:         r,
+		httpServer:     httpServer,
+		certs:          certs,
+		redirectServer: redirectServer,
+		grpcServer:     grpcServer,
+		cors:           corsPolicy,
+		plugins:        newRegistry(),
+		deps: Deps{
+			Config:    cfg,
+			Logger:    logger,
+			DB:        db,
+			Publisher: events.NewOutboxPublisher(outbox),
+			Events:    bus,
+			Metrics:   reg,
+			Limiter:   limiter,
+			// responses are kept per instance, like rate limit buckets
+			Idempotency: idempotency.NewGuard(logger, idempotency.NewMemoryStore(nil), cfg.Idempotency.TTL.Std()),
+		},
+		bus:             bus,
+		relay:           relay,
+		shutdownTracing: shutdownTracing,
+	}
+	root.Get("/healthz", app.Liveness)
+	root.Get("/readyz", app.Readiness)
+	root.Handle("/metrics", reg.Handler())
+	root.Get("/openapi.json", app.OpenAPI)
+	root.Handle("/docs", openapi.UI())
+	root.Mount("/", r)
+
+	return app, nil
 }
 
-func (a *App) Run(port string) error {
+// Register adds plugins to the app. They are initialised and started in
+// dependency order by Run.
+func (a *App) Register(plugins ...Plugin) error {
+	for _, p := range plugins {
+		if err := a.plugins.add(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close releases what NewApp opened, for callers that do not Run the app,
+// e.g. because registering the plugins failed.
+func (a *App) Close(ctx context.Context) error {
+	return runShutdownHooks(ctx, a.l, a.releaseHooks())
+}
+
+// OnShutdown registers fn to run on shutdown, after the HTTP server has
+// drained and the plugins are stopped but while the database is still
+// connected. Hooks run in registration order.
+func (a *App) OnShutdown(name string, fn func(ctx context.Context) error) {
+	a.hooks = append(a.hooks, ShutdownHook{Name: name, Fn: fn})
+}
 
-	// HTTP Server
+// Events returns the in-process event bus so that other modules can
+// subscribe to domain events before the app is started.
+func (a *App) Events() *events.Bus {
+	return a.bus
+}
+
+// Run starts the plugins and serves HTTP until SIGINT or SIGTERM is received
+// or the server fails, then shuts everything down in order within
+// Server.ShutdownTimeout. A second signal during shutdown exits immediately.
+func (a *App) Run() error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	var serveErr error
+	if err := a.startPlugins(ctx); err != nil {
+		serveErr = err
+	} else {
+		relayCtx, stopRelay := context.WithCancel(context.Background())
+		a.stopRelay = stopRelay
+		a.relayDone = make(chan struct{})
+		go func() {
+			defer close(a.relayDone)
+			a.relay.Run(relayCtx)
+		}()
 
-	go func() {
-		if err := a.httpServer.ListenAndServe(); err != nil {
-			log.Fatalf("Failed to listen and serve: %+v", err)
+		if a.certs != nil {
+			watchCtx, stopWatch := context.WithCancel(context.Background())
+			defer stopWatch()
+			go a.certs.Watch(watchCtx, a.cfg.Server.TLS.ReloadInterval.Std())
 		}
-	}()
 
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt, os.Interrupt)
+		a.ready.Store(true)
+		serveErr = serveUntil(ctx, a.serveFuncs()...)
+	}
+	// restore the default behaviour so that a second signal kills the process
+	stop()
 
-	<-quit
+	ctx, cancel := context.WithTimeout(context.Background(), a.cfg.Server.ShutdownTimeout.Std())
+	defer cancel()
+	return errors.Join(serveErr, runShutdownHooks(ctx, a.l, a.shutdownHooks()))
+}
+
+// serveFuncs returns the listeners to run: the API over HTTP or HTTPS, the
+// optional HTTPS redirect and the optional gRPC listener.
+func (a *App) serveFuncs() []func() error {
+	var serves []func() error
+	if a.certs == nil {
+		a.l.Info("listening", "addr", a.httpServer.Addr)
+		serves = append(serves, a.httpServer.ListenAndServe)
+	} else {
+		a.l.Info("listening", "addr", a.httpServer.Addr, "tls", true)
+		serves = append(serves, func() error {
+			// the certificate comes from TLSConfig.GetCertificate
+			return a.httpServer.ListenAndServeTLS("", "")
+		})
+		if a.redirectServer != nil {
+			a.l.Info("redirecting to https", "addr", a.redirectServer.Addr)
+			serves = append(serves, a.redirectServer.ListenAndServe)
+		}
+	}
+	if a.grpcServer != nil {
+		addr := fmt.Sprintf(":%d", a.cfg.Server.GRPC.Port)
+		a.l.Info("listening", "addr", addr, "protocol", "grpc", "tls", a.certs != nil)
+		serves = append(serves, func() error {
+			lis, err := net.Listen("tcp", addr)
+			if err != nil {
+				return err
+			}
+			return a.grpcServer.Serve(lis)
+		})
+	}
+	return serves
+}
+
+// shutdownHooks returns the shutdown sequence: stop taking traffic, drain
+// in-flight requests, stop background work and plugins, run the registered
+// hooks, then release the tracer and the database.
+func (a *App) shutdownHooks() []ShutdownHook {
+	hooks := []ShutdownHook{
+		{Name: "readiness", Fn: func(ctx context.Context) error {
+			// fail readiness first and give load balancers time to stop
+			// routing new requests here before connections are closed
+			if !a.ready.Swap(false) {
+				return nil
+			}
+			select {
+			case <-time.After(a.cfg.Server.DrainDelay.Std()):
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}},
+		{Name: "http", Fn: func(ctx context.Context) error {
+			if a.redirectServer != nil {
+				if err := a.redirectServer.Shutdown(ctx); err != nil {
+					return err
+				}
+			}
+			return a.httpServer.Shutdown(ctx)
+		}},
+		{Name: "grpc", Fn: func(ctx context.Context) error {
+			if a.grpcServer == nil {
+				return nil
+			}
+			// let in-flight calls finish, cut them off at the deadline
+			done := make(chan struct{})
+			go func() {
+				a.grpcServer.GracefulStop()
+				close(done)
+			}()
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				a.grpcServer.Stop()
+				return ctx.Err()
+			}
+		}},
+		{Name: "relay", Fn: func(ctx context.Context) error {
+			if a.relayDone == nil {
+				return nil
+			}
+			a.stopRelay()
+			select {
+			case <-a.relayDone:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}},
+		{Name: "plugins", Fn: func(ctx context.Context) error {
+			a.stopPlugins(ctx)
+			return nil
+		}},
+	}
+	hooks = append(hooks, a.hooks...)
+	return append(hooks, a.releaseHooks()...)
+}
+
+// releaseHooks flush the tracer and disconnect the database, the last steps
+// of every shutdown.
+func (a *App) releaseHooks() []ShutdownHook {
+	return []ShutdownHook{
+		{Name: "tracing", Fn: a.shutdownTracing},
+		{Name: "mongo", Fn: a.deps.DB.Client().Disconnect},
+	}
+}
+
+// startPlugins initialises every plugin, mounts its routes and starts it, in
+// dependency order. If a plugin fails to start the ones already started are
+// stopped again.
+func (a *App) startPlugins(ctx context.Context) error {
+	plugins, err := a.plugins.ordered()
+	if err != nil {
+		return err
+	}
+	for _, p := range plugins {
+		if err := p.Init(a.deps); err != nil {
+			return fmt.Errorf("init plugin %s: %w", p.Name(), err)
+		}
+		a.mountRoutes(p)
+		if g, ok := p.(GRPCService); ok && a.grpcServer != nil {
+			g.RegisterGRPC(a.grpcServer)
+		}
+		if o, ok := p.(OriginPolicy); ok && a.cors != nil {
+			a.cors.plugins = append(a.cors.plugins, o)
+		}
+	}
+	a.mountVersions()
+	if err := a.buildSpec(plugins); err != nil {
+		return fmt.Errorf("build OpenAPI document: %w", err)
+	}
+	for _, p := range plugins {
+		a.l.Info("starting plugin", "plugin", p.Name())
+		if err := p.Start(ctx); err != nil {
+			a.stopPlugins(ctx)
+			return fmt.Errorf("start plugin %s: %w", p.Name(), err)
+		}
+		a.started = append(a.started, p)
+	}
+	return nil
+}
 
-	ctx, shutdown := context.WithTimeout(context.Background(), 5*time.Second)
-	defer shutdown()
+// mountRoutes registers the endpoints of p under every version it serves.
+func (a *App) mountRoutes(p Plugin) {
+	versioned, ok := p.(Versioned)
+	if !ok {
+		p.Routes(a.version(DefaultVersion))
+		return
+	}
+	for _, v := range versioned.Versions() {
+		a.version(v.Version.Name).Group(func(r chi.Router) {
+			r.Use(v.Version.Headers)
+			v.Routes(r)
+		})
+	}
+}
+
+// version returns the router of an API version, mounting it on first use.
+func (a *App) version(name string) chi.Router {
+	if r, ok := a.versions[name]; ok {
+		return r
+	}
+	if a.versions == nil {
+		a.versions = make(map[string]chi.Router)
+	}
+	r := chi.NewRouter()
+	a.router.Mount("/"+name, r)
+	a.versions[name] = r
+	return r
+}
 
-	return a.httpServer.Shutdown(ctx)
+// mountVersions serves unversioned paths with the version negotiated from
+// the Accept header, so clients predating versioning keep working.
+func (a *App) mountVersions() {
+	handlers := make(map[string]http.Handler, len(a.versions))
+	for name, r := range a.versions {
+		handlers[name] = r
+	}
+	a.router.Mount("/", apiversion.Negotiate(handlers, DefaultVersion))
+}
+
+// stopPlugins stops the started plugins in reverse start order.
+func (a *App) stopPlugins(ctx context.Context) {
+	for i := len(a.started) - 1; i >= 0; i-- {
+		p := a.started[i]
+		a.l.Info("stopping plugin", "plugin", p.Name())
+		if err := p.Stop(ctx); err != nil {
+			a.l.Error("unable to stop plugin", "plugin", p.Name(), "error", err)
+		}
+	}
+	a.started = nil
+}
+
+func initTracing(cfg config.TracingConfig) (func(context.Context) error, error) {
+	opts := tracing.Options{
+		ServiceName: cfg.ServiceName,
+		SampleRatio: cfg.SampleRatio,
+	}
+	if cfg.Exporter == "otlp" {
+		exporter, err := tracing.NewOTLPExporter(context.Background(), cfg.Endpoint, cfg.Insecure)
+		if err != nil {
+			return nil, fmt.Errorf("create trace exporter: %w", err)
+		}
+		opts.Exporter = exporter
+	}
+	return tracing.Setup(opts), nil
 }
 
-func initDB() *mongo.Database {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func initDB(cfg config.MongoConfig, monitor *event.CommandMonitor) (*mongo.Database, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ConnectTimeout.Std())
 	defer cancel()
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(os.Getenv("MONGO_URI")))
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.URI).SetMonitor(monitor))
 	if err != nil {
-		log.Fatalf("Error occurred while establishing connection to MongoDB: %v", err)
+		return nil, fmt.Errorf("connect to MongoDB: %w", err)
 	}
 	// It's a good idea to ping the database to verify that the connection is alive
 	err = client.Ping(ctx, readpref.Primary())
 	if err != nil {
-		log.Fatalf("Ping failed to MongoDB: %v", err)
+		client.Disconnect(context.Background())
+		return nil, fmt.Errorf("ping MongoDB: %w", err)
 	}
 
-	return client.Database(os.Getenv("DB_NAME"))
+	return client.Database(cfg.Database), nil
 }
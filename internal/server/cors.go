@@ -0,0 +1,54 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/go-chi/cors"
+
+	"cleanarch/boiler/internal/config"
+	"cleanarch/boiler/internal/utils/origin"
+)
+
+// OriginPolicy is implemented by plugins that allow cross origin requests
+// from origins beyond cors.allowedOrigins, e.g. the app origins registered by
+// tenants. It is consulted on every cross origin request the configuration
+// does not allow, preflights included, so it should answer from memory.
+type OriginPolicy interface {
+	AllowsOrigin(r *http.Request, origin string) bool
+}
+
+// corsPolicy decides which origins may call the API: the configured
+// patterns first, then the plugins.
+type corsPolicy struct {
+	static  origin.Matcher
+	plugins []OriginPolicy
+}
+
+// allow is the AllowOriginFunc of the CORS middleware. Plugins get the
+// origin normalized.
+func (c *corsPolicy) allow(r *http.Request, o string) bool {
+	if c.static.Match(o) {
+		return true
+	}
+	normalized, err := origin.Normalize(o)
+	if err != nil {
+		return false
+	}
+	for _, p := range c.plugins {
+		if p.AllowsOrigin(r, normalized) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *corsPolicy) middleware(cfg config.CORSConfig) func(http.Handler) http.Handler {
+	return cors.Handler(cors.Options{
+		AllowOriginFunc:  c.allow,
+		AllowedMethods:   cfg.AllowedMethods,
+		AllowedHeaders:   cfg.AllowedHeaders,
+		ExposedHeaders:   cfg.ExposedHeaders,
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           cfg.MaxAge,
+	})
+}
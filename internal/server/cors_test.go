@@ -0,0 +1,54 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cleanarch/boiler/internal/config"
+	"cleanarch/boiler/internal/utils/origin"
+)
+
+// tenantOrigins allows the origins a tenant registered.
+type tenantOrigins map[string]bool
+
+func (t tenantOrigins) AllowsOrigin(r *http.Request, origin string) bool {
+	return t[origin]
+}
+
+func TestCORSPolicy(t *testing.T) {
+	static, err := origin.NewMatcher([]string{"https://app.example.com", "https://*.example.dev"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := &corsPolicy{static: static, plugins: []OriginPolicy{tenantOrigins{"https://shop.tenant.io": true}}}
+	cfg := config.Default().CORS
+	h := policy.middleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"https://app.example.com", true},
+		{"https://preview-42.example.dev", true},
+		{"https://SHOP.tenant.io", true},
+		{"https://other.tenant.io", false},
+		{"http://app.example.com", false},
+		{"https://example.dev", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodOptions, "/v1/me", nil)
+		r.Header.Set("Origin", tt.origin)
+		r.Header.Set("Access-Control-Request-Method", http.MethodPatch)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		got := w.Header().Get("Access-Control-Allow-Origin")
+		if tt.allowed != (got == tt.origin) {
+			t.Errorf("%s: Access-Control-Allow-Origin = %q", tt.origin, got)
+		}
+		if tt.allowed && w.Header().Get("Access-Control-Allow-Credentials") != "true" {
+			t.Errorf("%s: credentials not allowed", tt.origin)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"net/http"
+
+	"cleanarch/boiler/internal/utils/openapi"
+)
+
+// Secured is implemented by plugins whose routes require authentication. It
+// declares the security schemes their OpenAPI operations refer to.
+type Secured interface {
+	SecuritySchemes() map[string]openapi.SecurityScheme
+}
+
+var apiInfo = openapi.Info{
+	Title:       "cleanarch API",
+	Version:     "1.0.0",
+	Description: "Errors are answered with application/problem+json.",
+}
+
+// buildSpec documents the routes mounted by the plugins. It runs once all
+// plugins are mounted, before the server accepts requests.
+func (a *App) buildSpec(plugins []Plugin) error {
+	schemes := map[string]openapi.SecurityScheme{}
+	for _, p := range plugins {
+		if s, ok := p.(Secured); ok {
+			for name, scheme := range s.SecuritySchemes() {
+				schemes[name] = scheme
+			}
+		}
+	}
+	doc, err := openapi.Build(apiInfo, schemes, a.router)
+	if err != nil {
+		return err
+	}
+	a.spec, err = doc.Handler()
+	return err
+}
+
+// OpenAPI answers /openapi.json with the document of the API routes.
+func (a *App) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	if a.spec == nil {
+		http.Error(w, "document not built yet", http.StatusServiceUnavailable)
+		return
+	}
+	a.spec.ServeHTTP(w, r)
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/render"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+
+	"cleanarch/boiler/internal/utils/health"
+)
+
+// Liveness answers /healthz. It only tells that the process is able to serve
+// HTTP and never checks dependencies, so a database outage does not make
+// Kubernetes restart every pod.
+func (a *App) Liveness(w http.ResponseWriter, r *http.Request) {
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, health.Report{Status: health.StatusUp})
+}
+
+// Readiness answers /readyz with the result of the server and plugin checks.
+// It reports 503 while any check is down and once shutdown has begun.
+func (a *App) Readiness(w http.ResponseWriter, r *http.Request) {
+	checks := append([]health.Check{a.shutdownCheck(), a.mongoCheck()}, a.pluginChecks()...)
+	report := health.Run(r.Context(), checks)
+
+	status := http.StatusOK
+	if report.Status != health.StatusUp {
+		status = http.StatusServiceUnavailable
+	}
+	render.Status(r, status)
+	render.JSON(w, r, report)
+}
+
+func (a *App) shutdownCheck() health.Check {
+	return health.Check{
+		Name: "server",
+		Func: func(ctx context.Context) error {
+			if !a.ready.Load() {
+				return errors.New("not accepting traffic")
+			}
+			return nil
+		},
+	}
+}
+
+func (a *App) mongoCheck() health.Check {
+	return health.Check{
+		Name:    "mongo",
+		Timeout: a.cfg.Mongo.PingTimeout.Std(),
+		Func: func(ctx context.Context) error {
+			return a.deps.DB.Client().Ping(ctx, readpref.Primary())
+		},
+	}
+}
+
+func (a *App) pluginChecks() []health.Check {
+	var checks []health.Check
+	for _, p := range a.plugins.plugins {
+		checks = append(checks, p.HealthChecks()...)
+	}
+	return checks
+}
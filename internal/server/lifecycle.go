@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"cleanarch/boiler/internal/utils/logger"
+)
+
+// ShutdownHook is one named step of the shutdown sequence.
+type ShutdownHook struct {
+	Name string
+	Fn   func(ctx context.Context) error
+}
+
+// serveUntil runs every serve func in the background until ctx is cancelled,
+// typically by a termination signal, or one of them fails.
+// http.ErrServerClosed is not an error, it is what serve returns once the
+// server is shut down.
+func serveUntil(ctx context.Context, serves ...func() error) error {
+	errc := make(chan error, len(serves))
+	for _, serve := range serves {
+		go func(serve func() error) {
+			errc <- serve()
+		}(serve)
+	}
+
+	for range serves {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errc:
+			if !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// runShutdownHooks runs hooks in order. A failing hook does not stop the
+// sequence, every error is logged and returned joined. Hooks share ctx, so
+// they all fit in one shutdown deadline.
+func runShutdownHooks(ctx context.Context, l logger.Interface, hooks []ShutdownHook) error {
+	var errs []error
+	for _, h := range hooks {
+		l.Info("shutting down", "step", h.Name)
+		if err := h.Fn(ctx); err != nil {
+			l.Error("shutdown step failed", "step", h.Name, "error", err)
+			errs = append(errs, fmt.Errorf("shutdown %s: %w", h.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"cleanarch/boiler/internal/config"
+	"cleanarch/boiler/internal/utils/logger"
+)
+
+func TestServeUntil(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	block := make(chan struct{})
+	defer close(block)
+	if err := serveUntil(ctx, func() error { <-block; return nil }); err != nil {
+		t.Errorf("cancelled context: got %v, want nil", err)
+	}
+
+	if err := serveUntil(context.Background(), func() error { return http.ErrServerClosed }); err != nil {
+		t.Errorf("ErrServerClosed: got %v, want nil", err)
+	}
+
+	boom := errors.New("address already in use")
+	if err := serveUntil(context.Background(), func() error { return boom }); !errors.Is(err, boom) {
+		t.Errorf("serve failure: got %v, want %v", err, boom)
+	}
+}
+
+func TestRunShutdownHooks(t *testing.T) {
+	var ran []string
+	hook := func(name string, err error) ShutdownHook {
+		return ShutdownHook{Name: name, Fn: func(ctx context.Context) error {
+			ran = append(ran, name)
+			return err
+		}}
+	}
+
+	err := runShutdownHooks(context.Background(), logger.NewLogger("error"), []ShutdownHook{
+		hook("http", nil),
+		hook("plugins", errors.New("boom")),
+		hook("mongo", nil),
+	})
+
+	if want := []string{"http", "plugins", "mongo"}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("hooks ran %v, want %v", ran, want)
+	}
+	if err == nil || !strings.Contains(err.Error(), "shutdown plugins: boom") {
+		t.Errorf("got error %v, want the failing step", err)
+	}
+}
+
+func TestNewApp_ReturnsStartupErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(*config.Config)
+		want   string
+	}{
+		{"cors", func(c *config.Config) { c.CORS.AllowedOrigins = []string{"https://*"} }, "cors.allowedOrigins"},
+		{"tls", func(c *config.Config) {
+			c.Server.TLS.Enabled = true
+			c.Server.TLS.CertFile = t.TempDir() + "/missing.pem"
+			c.Server.TLS.KeyFile = t.TempDir() + "/missing.key"
+		}, "TLS certificate"},
+		{"mongo", func(c *config.Config) { c.Mongo.URI = "not-a-mongo-uri" }, "MongoDB"},
+	}
+	for _, tt := range tests {
+		cfg := config.Default()
+		cfg.Log.Level = "error"
+		tt.change(cfg)
+
+		app, err := NewApp(cfg)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: NewApp() = %v, %v, want an error about %s", tt.name, app, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/mongo"
+	"google.golang.org/grpc"
+
+	"cleanarch/boiler/internal/config"
+	"cleanarch/boiler/internal/events"
+	"cleanarch/boiler/internal/utils/apiversion"
+	"cleanarch/boiler/internal/utils/health"
+	"cleanarch/boiler/internal/utils/idempotency"
+	"cleanarch/boiler/internal/utils/logger"
+	"cleanarch/boiler/internal/utils/ratelimit"
+)
+
+// Plugin is a feature module hosted by the App. The App calls Init once with
+// the shared dependencies, mounts Routes, then calls Start before serving and
+// Stop after the HTTP server has drained.
+type Plugin interface {
+	// Name identifies the plugin in logs, health output and DependsOn lists.
+	Name() string
+	// Init wires the plugin. It must not start background work.
+	Init(deps Deps) error
+	// Routes registers the plugin's HTTP endpoints. They are served under
+	// DefaultVersion unless the plugin implements Versioned.
+	Routes(r chi.Router)
+	// Start launches background work. It should return once started.
+	Start(ctx context.Context) error
+	// Stop releases what Start acquired, honouring the ctx deadline.
+	Stop(ctx context.Context) error
+	// HealthChecks lists the readiness checks of the plugin. They are run
+	// by /readyz together with the checks of the other plugins.
+	HealthChecks() []health.Check
+}
+
+// Dependent is implemented by plugins that must be started after other
+// plugins, identified by name.
+type Dependent interface {
+	DependsOn() []string
+}
+
+// GRPCService is implemented by plugins exposing gRPC services. They are
+// registered after Init when server.grpc is enabled.
+type GRPCService interface {
+	RegisterGRPC(s grpc.ServiceRegistrar)
+}
+
+// DefaultVersion is the API version of plugins that are not Versioned, and
+// the version serving unversioned requests whose Accept header names none.
+const DefaultVersion = "v1"
+
+// Versioned is implemented by plugins serving several API versions side by
+// side. Their routes are registered per version instead of through Routes.
+type Versioned interface {
+	Versions() []VersionRoutes
+}
+
+// VersionRoutes are the endpoints of a plugin in one API version, mounted
+// under /<Version.Name>. Deprecated versions mark their responses with
+// Deprecation and Sunset headers.
+type VersionRoutes struct {
+	Version apiversion.Version
+	Routes  func(r chi.Router)
+}
+
+// Deps are the shared services handed to every plugin.
+type Deps struct {
+	Config    *config.Config
+	Logger    logger.Interface
+	DB        *mongo.Database
+	Publisher events.Publisher
+	Events    *events.Bus
+	Metrics   prometheus.Registerer
+	// Limiter applies rate limit policies, sharing the app's bucket store.
+	Limiter *ratelimit.Limiter
+	// Idempotency replays the responses of retried requests carrying an
+	// Idempotency-Key.
+	Idempotency *idempotency.Guard
+}
+
+// RateLimit converts a configured policy to a limit for Deps.Limiter.
+func RateLimit(p config.RateLimitPolicy) ratelimit.Limit {
+	return ratelimit.Limit{Requests: p.Requests, Window: p.Window.Std(), Burst: p.Burst}
+}
+
+// registry keeps plugins in registration order.
+type registry struct {
+	plugins []Plugin
+	byName  map[string]Plugin
+}
+
+func newRegistry() *registry {
+	return &registry{
+		byName: make(map[string]Plugin),
+	}
+}
+
+func (r *registry) add(p Plugin) error {
+	if _, ok := r.byName[p.Name()]; ok {
+		return fmt.Errorf("plugin %q registered twice", p.Name())
+	}
+	r.byName[p.Name()] = p
+	r.plugins = append(r.plugins, p)
+	return nil
+}
+
+// ordered returns the plugins sorted so that every plugin comes after the
+// plugins it depends on. Independent plugins keep their registration order.
+func (r *registry) ordered() ([]Plugin, error) {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(r.plugins))
+	ordered := make([]Plugin, 0, len(r.plugins))
+
+	var visit func(p Plugin, path []string) error
+	visit = func(p Plugin, path []string) error {
+		switch state[p.Name()] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("plugin dependency cycle: %v", append(path, p.Name()))
+		}
+		state[p.Name()] = visiting
+		if d, ok := p.(Dependent); ok {
+			for _, name := range d.DependsOn() {
+				dep, ok := r.byName[name]
+				if !ok {
+					return fmt.Errorf("plugin %q depends on unregistered plugin %q", p.Name(), name)
+				}
+				if err := visit(dep, append(path, p.Name())); err != nil {
+					return err
+				}
+			}
+		}
+		state[p.Name()] = done
+		ordered = append(ordered, p)
+		return nil
+	}
+
+	for _, p := range r.plugins {
+		if err := visit(p, nil); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"cleanarch/boiler/internal/utils/health"
+)
+
+type testPlugin struct {
+	name string
+	deps []string
+}
+
+func (p testPlugin) Name() string                    { return p.name }
+func (p testPlugin) DependsOn() []string             { return p.deps }
+func (p testPlugin) Init(deps Deps) error            { return nil }
+func (p testPlugin) Routes(r chi.Router)             {}
+func (p testPlugin) Start(ctx context.Context) error { return nil }
+func (p testPlugin) Stop(ctx context.Context) error  { return nil }
+func (p testPlugin) HealthChecks() []health.Check    { return nil }
+
+func TestRegistry_Ordered(t *testing.T) {
+	r := newRegistry()
+	r.add(testPlugin{name: "webhooks", deps: []string{"user", "events"}})
+	r.add(testPlugin{name: "user"})
+	r.add(testPlugin{name: "audit"})
+	r.add(testPlugin{name: "events", deps: []string{"user"}})
+
+	ordered, err := r.ordered()
+	if err != nil {
+		t.Fatalf("ordered() error = %v", err)
+	}
+	var names []string
+	for _, p := range ordered {
+		names = append(names, p.Name())
+	}
+	if got, want := strings.Join(names, ","), "user,events,webhooks,audit"; got != want {
+		t.Fatalf("ordered() = %s, want %s", got, want)
+	}
+}
+
+func TestRegistry_Errors(t *testing.T) {
+	r := newRegistry()
+	if err := r.add(testPlugin{name: "a", deps: []string{"b"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.add(testPlugin{name: "a"}); err == nil {
+		t.Fatal("expected duplicate name to be rejected")
+	}
+	if _, err := r.ordered(); err == nil || !strings.Contains(err.Error(), "unregistered") {
+		t.Fatalf("ordered() error = %v, want unregistered dependency", err)
+	}
+
+	r.add(testPlugin{name: "b", deps: []string{"a"}})
+	if _, err := r.ordered(); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Fatalf("ordered() error = %v, want cycle", err)
+	}
+}
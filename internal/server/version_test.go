@@ -0,0 +1,109 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"cleanarch/boiler/internal/utils/apiversion"
+)
+
+// versionedPlugin serves /me in v1 (deprecated) and v2 with different bodies.
+type versionedPlugin struct {
+	testPlugin
+}
+
+func (p versionedPlugin) Versions() []VersionRoutes {
+	me := func(body string) func(r chi.Router) {
+		return func(r chi.Router) {
+			r.Get("/me", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(body)) })
+		}
+	}
+	return []VersionRoutes{
+		{
+			Version: apiversion.Version{
+				Name:        "v1",
+				Deprecation: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+				Sunset:      time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC),
+				Successor:   "v2",
+			},
+			Routes: me("one"),
+		},
+		{Version: apiversion.Version{Name: "v2"}, Routes: me("two")},
+	}
+}
+
+func TestMountRoutes_Versions(t *testing.T) {
+	a := &App{router: chi.NewRouter()}
+	a.mountRoutes(versionedPlugin{testPlugin{name: "user"}})
+	a.mountVersions()
+
+	tests := []struct {
+		name       string
+		path       string
+		accept     string
+		status     int
+		body       string
+		deprecated bool
+	}{
+		{name: "path v1", path: "/v1/me", status: http.StatusOK, body: "one", deprecated: true},
+		{name: "path v2", path: "/v2/me", status: http.StatusOK, body: "two"},
+		{name: "path wins over accept", path: "/v2/me", accept: "application/vnd.cleanarch.v1+json", status: http.StatusOK, body: "two"},
+		{name: "unversioned default", path: "/me", status: http.StatusOK, body: "one", deprecated: true},
+		{name: "unversioned accept", path: "/me", accept: "application/vnd.cleanarch.v2+json", status: http.StatusOK, body: "two"},
+		{name: "unknown accept", path: "/me", accept: "application/vnd.cleanarch.v9+json", status: http.StatusNotAcceptable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			a.router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if tt.body != "" && rec.Body.String() != tt.body {
+				t.Errorf("body = %q, want %q", rec.Body, tt.body)
+			}
+			if got := rec.Header().Get("Deprecation") != ""; got != tt.deprecated {
+				t.Errorf("Deprecation header = %q", rec.Header().Get("Deprecation"))
+			}
+			if tt.deprecated {
+				if got := rec.Header().Get("Sunset"); got != "Thu, 31 Dec 2026 00:00:00 GMT" {
+					t.Errorf("Sunset = %q", got)
+				}
+				if got := rec.Header().Get("Link"); got != `</v2>; rel="successor-version"` {
+					t.Errorf("Link = %q", got)
+				}
+			}
+		})
+	}
+}
+
+func TestMountRoutes_DefaultVersion(t *testing.T) {
+	a := &App{router: chi.NewRouter()}
+	a.mountRoutes(routesPlugin{testPlugin{name: "user"}})
+	a.mountVersions()
+
+	for _, path := range []string{"/v1/ping", "/ping"} {
+		rec := httptest.NewRecorder()
+		a.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %s = %d, want 200", path, rec.Code)
+		}
+	}
+}
+
+type routesPlugin struct {
+	testPlugin
+}
+
+func (p routesPlugin) Routes(r chi.Router) {
+	r.Get("/ping", func(w http.ResponseWriter, r *http.Request) {})
+}
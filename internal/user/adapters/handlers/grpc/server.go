@@ -0,0 +1,212 @@
+package grpc
+
+import (
+	"cleanarch/boiler/internal/user/adapters/handlers/grpc/userv1"
+	"cleanarch/boiler/internal/user/domain"
+	"cleanarch/boiler/internal/utils/apperr"
+	"cleanarch/boiler/internal/utils/grpcutil"
+	"cleanarch/boiler/internal/utils/logger"
+	"cleanarch/boiler/internal/utils/requestlog"
+	"cleanarch/boiler/internal/utils/validate"
+	"context"
+	"errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type AuthUseCases interface {
+	Login(ctx context.Context, user *domain.AddUserRequest) (*domain.UserTokens, error)
+	ValidateAccessToken(ctx context.Context, token string) (string, error)
+	RefreshTokenAccess(ctx context.Context, refreshToken string) (*domain.UserTokens, error)
+}
+
+type UserUseCases interface {
+	GetUserByID(ctx context.Context, id string) (*domain.UserResponse, error)
+}
+
+// LoginLimit is checked with the caller's address before every Login and
+// returns an error to reject it, like the credentials rate limit of the
+// HTTP login.
+type LoginLimit func(ctx context.Context, key string) error
+
+// Server implements the AuthService of proto/cleanarch/user/v1/auth.proto on
+// top of the same usecases as the HTTP handlers.
+type Server struct {
+	userv1.UnimplementedAuthServiceServer
+	l           logger.Interface
+	authUseCase AuthUseCases
+	userUseCase UserUseCases
+	loginLimit  LoginLimit
+}
+
+var _ userv1.AuthServiceServer = (*Server)(nil)
+var _ grpcutil.Authenticator = (*Server)(nil)
+
+// NewServer creates the server. A nil loginLimit disables rate limiting of
+// Login.
+func NewServer(l logger.Interface, authUseCase AuthUseCases, userUseCase UserUseCases, loginLimit LoginLimit) *Server {
+	return &Server{
+		l:           l,
+		authUseCase: authUseCase,
+		userUseCase: userUseCase,
+		loginLimit:  loginLimit,
+	}
+}
+
+// RegisterAuthServiceServer registers srv on s through the generated
+// service descriptor.
+func RegisterAuthServiceServer(s grpc.ServiceRegistrar, srv *Server) {
+	userv1.RegisterAuthServiceServer(s, srv)
+}
+
+// public lists the methods callable without an access token.
+var public = map[string]bool{
+	userv1.AuthService_Login_FullMethodName:               true,
+	userv1.AuthService_Refresh_FullMethodName:             true,
+	userv1.AuthService_ValidateAccessToken_FullMethodName: true,
+}
+
+// Authenticate resolves the caller of non public methods from the bearer
+// access token in the authorization metadata, like the HTTP middleware
+// does for the cookie or header.
+func (s *Server) Authenticate(ctx context.Context, fullMethod string) (context.Context, error) {
+	if public[fullMethod] {
+		return ctx, nil
+	}
+	token, ok := grpcutil.BearerToken(ctx)
+	if !ok {
+		return nil, domain.ErrInvalidToken
+	}
+	user, err := s.resolve(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	ctx = context.WithValue(ctx, domain.UserIDKey{}, user.ID)
+	ctx = context.WithValue(ctx, domain.UserKey{}, user)
+	requestlog.SetUser(ctx, user.ID, user.TenantID)
+	return ctx, nil
+}
+
+func (s *Server) Login(ctx context.Context, req *userv1.LoginRequest) (*userv1.Tokens, error) {
+	if s.loginLimit != nil {
+		if host, ok := grpcutil.PeerHost(ctx); ok {
+			if err := s.loginLimit(ctx, host); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := required(field{"email", req.GetEmail()}, field{"password", req.GetPassword()}); err != nil {
+		return nil, err
+	}
+	tokens, err := s.authUseCase.Login(ctx, &domain.AddUserRequest{Email: req.GetEmail(), Password: req.GetPassword()})
+	if err != nil {
+		// never tell whether the email exists
+		if errors.Is(err, domain.ErrUserNotFound) {
+			err = domain.ErrUserInvalidCredentials.Wrap(err)
+		}
+		return nil, err
+	}
+	return toTokens(tokens), nil
+}
+
+func (s *Server) Refresh(ctx context.Context, req *userv1.RefreshRequest) (*userv1.Tokens, error) {
+	if err := required(field{"refresh_token", req.GetRefreshToken()}); err != nil {
+		return nil, err
+	}
+	tokens, err := s.authUseCase.RefreshTokenAccess(ctx, req.GetRefreshToken())
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			err = domain.ErrInvalidToken.Wrap(err)
+		}
+		return nil, err
+	}
+	return toTokens(tokens), nil
+}
+
+func (s *Server) ValidateAccessToken(ctx context.Context, req *userv1.ValidateAccessTokenRequest) (*userv1.ValidateAccessTokenResponse, error) {
+	if err := required(field{"access_token", req.GetAccessToken()}); err != nil {
+		return nil, err
+	}
+	user, err := s.resolve(ctx, req.GetAccessToken())
+	if err != nil {
+		return nil, err
+	}
+	return &userv1.ValidateAccessTokenResponse{UserId: user.ID, TenantId: user.TenantID}, nil
+}
+
+func (s *Server) GetUser(ctx context.Context, req *userv1.GetUserRequest) (*userv1.User, error) {
+	if err := required(field{"id", req.GetId()}); err != nil {
+		return nil, err
+	}
+	// set by Authenticate, missing if the method is reached without it
+	caller, ok := ctx.Value(domain.UserKey{}).(*domain.UserResponse)
+	if !ok {
+		return nil, domain.ErrInvalidToken
+	}
+	user, err := s.userUseCase.GetUserByID(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	// users of other tenants do not exist for the caller
+	if user.TenantID != caller.TenantID {
+		return nil, domain.ErrUserNotFound
+	}
+	return toUser(user), nil
+}
+
+// resolve returns the user an access token was issued to. Tokens of deleted
+// users are invalid.
+func (s *Server) resolve(ctx context.Context, token string) (*domain.UserResponse, error) {
+	userID, err := s.authUseCase.ValidateAccessToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.userUseCase.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			err = domain.ErrInvalidToken.Wrap(err)
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// field is a request field that must not be empty.
+type field struct {
+	name, value string
+}
+
+// required rejects a request with empty fields, reported like the
+// `validate:"required"` tags of the HTTP requests.
+func required(fields ...field) error {
+	var missing []apperr.FieldError
+	for _, f := range fields {
+		if f.value == "" {
+			missing = append(missing, apperr.FieldError{Field: f.name, Message: "is required"})
+		}
+	}
+	if len(missing) > 0 {
+		return validate.ErrInvalid.WithFields(missing...)
+	}
+	return nil
+}
+
+func toTokens(tokens *domain.UserTokens) *userv1.Tokens {
+	return &userv1.Tokens{AccessToken: tokens.AccessToken, RefreshToken: tokens.RefreshToken}
+}
+
+func toUser(u *domain.UserResponse) *userv1.User {
+	return &userv1.User{
+		Id:         u.ID,
+		Email:      u.Email,
+		FirstName:  u.FirstName,
+		MiddleName: u.MiddleName,
+		LastName:   u.LastName,
+		Status:     u.Status.String(),
+		TenantId:   u.TenantID,
+		CreatedAt:  timestamppb.New(u.CreatedAt),
+		UpdatedAt:  timestamppb.New(u.UpdatedAt),
+		Version:    u.Version,
+	}
+}
@@ -0,0 +1,196 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"cleanarch/boiler/internal/user/adapters/handlers/grpc/userv1"
+	"cleanarch/boiler/internal/user/domain"
+	"cleanarch/boiler/internal/utils/grpcutil"
+	"cleanarch/boiler/internal/utils/logger"
+	"cleanarch/boiler/internal/utils/ratelimit"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+var users = map[string]*domain.UserResponse{
+	"u1": {ID: "u1", Email: "one@example.com", TenantID: "t1"},
+	"u2": {ID: "u2", Email: "two@example.com", TenantID: "t1"},
+	"u3": {ID: "u3", Email: "three@example.com", TenantID: "t2"},
+}
+
+type fakeUseCases struct{}
+
+func (fakeUseCases) Login(ctx context.Context, user *domain.AddUserRequest) (*domain.UserTokens, error) {
+	if user.Email != "one@example.com" || user.Password != "secret-password" {
+		return nil, domain.ErrUserInvalidCredentials
+	}
+	return &domain.UserTokens{AccessToken: "token-u1", RefreshToken: "refresh-u1"}, nil
+}
+
+func (fakeUseCases) ValidateAccessToken(ctx context.Context, token string) (string, error) {
+	if len(token) > 6 && token[:6] == "token-" {
+		return token[6:], nil
+	}
+	return "", domain.ErrInvalidToken
+}
+
+func (fakeUseCases) RefreshTokenAccess(ctx context.Context, refreshToken string) (*domain.UserTokens, error) {
+	return nil, domain.ErrInvalidToken
+}
+
+func (fakeUseCases) GetUserByID(ctx context.Context, id string) (*domain.UserResponse, error) {
+	if u, ok := users[id]; ok {
+		return u, nil
+	}
+	return nil, domain.ErrUserNotFound
+}
+
+func newClient(t *testing.T) userv1.AuthServiceClient {
+	t.Helper()
+	return newClientWithLimit(t, nil)
+}
+
+func newClientWithLimit(t *testing.T, loginLimit LoginLimit) userv1.AuthServiceClient {
+	t.Helper()
+	return userv1.NewAuthServiceClient(newConn(t, loginLimit))
+}
+
+// newConn serves the auth service and reflection over an in-memory listener.
+func newConn(t *testing.T, loginLimit LoginLimit) *grpc.ClientConn {
+	t.Helper()
+	l := logger.NewLogger("error")
+	s := grpcutil.NewServer(l, grpcutil.Options{})
+	RegisterAuthServiceServer(s, NewServer(l, fakeUseCases{}, fakeUseCases{}, loginLimit))
+	reflection.Register(s)
+	lis := bufconn.Listen(1 << 20)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	cc, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cc.Close() })
+	return cc
+}
+
+func reason(err error) string {
+	for _, d := range status.Convert(err).Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok {
+			return info.Reason
+		}
+	}
+	return ""
+}
+
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestLogin(t *testing.T) {
+	c := newClient(t)
+
+	tokens, err := c.Login(context.Background(), &userv1.LoginRequest{Email: "one@example.com", Password: "secret-password"})
+	if err != nil || tokens.AccessToken != "token-u1" {
+		t.Fatalf("Login() = %+v, %v", tokens, err)
+	}
+
+	_, err = c.Login(context.Background(), &userv1.LoginRequest{Email: "one@example.com", Password: "wrong"})
+	if status.Code(err) != codes.Unauthenticated || reason(err) != "invalid_credentials" {
+		t.Errorf("Login() with a wrong password error = %v (%s)", err, reason(err))
+	}
+
+	_, err = c.Login(context.Background(), &userv1.LoginRequest{Email: "one@example.com"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Login() without password error = %v", err)
+	}
+}
+
+func TestValidateAccessToken(t *testing.T) {
+	c := newClient(t)
+
+	resp, err := c.ValidateAccessToken(context.Background(), &userv1.ValidateAccessTokenRequest{AccessToken: "token-u3"})
+	if err != nil || resp.UserId != "u3" || resp.TenantId != "t2" {
+		t.Fatalf("ValidateAccessToken() = %+v, %v", resp, err)
+	}
+	// the user behind the token is gone
+	_, err = c.ValidateAccessToken(context.Background(), &userv1.ValidateAccessTokenRequest{AccessToken: "token-u9"})
+	if status.Code(err) != codes.Unauthenticated || reason(err) != "invalid_token" {
+		t.Errorf("ValidateAccessToken() of a deleted user error = %v", err)
+	}
+}
+
+func TestGetUser(t *testing.T) {
+	c := newClient(t)
+
+	if _, err := c.GetUser(context.Background(), &userv1.GetUserRequest{Id: "u2"}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("GetUser() without token error = %v", err)
+	}
+	user, err := c.GetUser(withToken("token-u1"), &userv1.GetUserRequest{Id: "u2"})
+	if err != nil || user.Email != "two@example.com" {
+		t.Fatalf("GetUser() = %+v, %v", user, err)
+	}
+	_, err = c.GetUser(withToken("token-u1"), &userv1.GetUserRequest{Id: "u3"})
+	if status.Code(err) != codes.NotFound || reason(err) != "user_not_found" {
+		t.Errorf("GetUser() of another tenant error = %v", err)
+	}
+}
+
+func TestLogin_RateLimited(t *testing.T) {
+	limiter := ratelimit.NewLimiter(logger.NewLogger("error"), ratelimit.NewMemoryStore(nil))
+	policy := ratelimit.Policy{Name: "auth", Limit: ratelimit.Limit{Requests: 1, Window: time.Minute}}
+	c := newClientWithLimit(t, func(ctx context.Context, key string) error {
+		return limiter.Allow(ctx, policy, key)
+	})
+
+	req := &userv1.LoginRequest{Email: "one@example.com", Password: "secret-password"}
+	if _, err := c.Login(context.Background(), req); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	_, err := c.Login(context.Background(), req)
+	if status.Code(err) != codes.ResourceExhausted || reason(err) != "rate_limited" {
+		t.Errorf("second Login() error = %v, want rate_limited", err)
+	}
+}
+
+func TestGetUser_WithoutAuthenticate(t *testing.T) {
+	s := NewServer(logger.NewLogger("error"), fakeUseCases{}, fakeUseCases{}, nil)
+	if _, err := s.GetUser(context.Background(), &userv1.GetUserRequest{Id: "u1"}); !errors.Is(err, domain.ErrInvalidToken) {
+		t.Errorf("GetUser() without a caller error = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestReflection(t *testing.T) {
+	stream, err := reflectionpb.NewServerReflectionClient(newConn(t, nil)).ServerReflectionInfo(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: "cleanarch.user.v1.AuthService"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files := resp.GetFileDescriptorResponse().GetFileDescriptorProto(); len(files) == 0 {
+		t.Fatalf("reflection response = %v, want the descriptor of auth.proto", resp)
+	}
+}
@@ -2,14 +2,16 @@ package http
 
 import (
 	"cleanarch/boiler/internal/user/domain"
+	"cleanarch/boiler/internal/utils/bind"
+	"cleanarch/boiler/internal/utils/csrf"
 	"cleanarch/boiler/internal/utils/logger"
+	"cleanarch/boiler/internal/utils/query"
+	"cleanarch/boiler/internal/utils/requestlog"
 	"context"
-	"encoding/json"
 	"errors"
-	"fmt"
-	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -19,6 +21,19 @@ type Handler struct {
 	authUseCase   AuthUseCases
 	tenantUsecase TenantUsecases
 	userUseCase   UserUseCases
+	invitations   InvitationUseCases
+	cookies       CookieOptions
+	clients       ClientCredentials
+	webhooks      Webhooks
+}
+
+// CookieOptions controls the token cookies set on login and refresh.
+type CookieOptions struct {
+	Secure             bool
+	Domain             string
+	SameSite           http.SameSite
+	AccessTokenMaxAge  time.Duration
+	RefreshTokenMaxAge time.Duration
 }
 
 type AuthUseCases interface {
@@ -26,22 +41,31 @@ type AuthUseCases interface {
 	Login(ctx context.Context, user *domain.AddUserRequest) (*domain.UserTokens, error)
 	ValidateAccessToken(ctx context.Context, token string) (string, error)
 	RefreshTokenAccess(ctx context.Context, refreshToken string) (*domain.UserTokens, error)
+	Introspect(ctx context.Context, token, hint string) (*domain.TokenIntrospection, error)
 }
 
 type TenantUsecases interface {
-	Create(ctx context.Context, tenantId string) error
+	GetByID(ctx context.Context, tenantId string) (*domain.Tenant, error)
+	Update(ctx context.Context, tenantId string, update *domain.UpdateTenantRequest, expectedVersion int64) (*domain.Tenant, error)
 }
 
 type UserUseCases interface {
 	GetUserByID(ctx context.Context, id string) (*domain.UserResponse, error)
+	ReloadUserByID(ctx context.Context, id string) (*domain.UserResponse, error)
+	ListUsers(ctx context.Context, tenantId string, spec query.Spec) (*query.Page[*domain.UserResponse], error)
+	UpdateUser(ctx context.Context, id string, update *domain.UpdateUserRequest, expectedVersion int64) (*domain.UserResponse, error)
 }
 
-func NewHandler(l logger.Interface, authUseCase AuthUseCases, userUseCase UserUseCases, tenant TenantUsecases) *Handler {
+func NewHandler(l logger.Interface, authUseCase AuthUseCases, userUseCase UserUseCases, tenant TenantUsecases, invitations InvitationUseCases, cookies CookieOptions, clients ClientCredentials, webhooks Webhooks) *Handler {
 	return &Handler{
 		l:             l,
 		authUseCase:   authUseCase,
 		userUseCase:   userUseCase,
 		tenantUsecase: tenant,
+		invitations:   invitations,
+		cookies:       cookies,
+		clients:       clients,
+		webhooks:      webhooks,
 	}
 }
 
@@ -52,42 +76,22 @@ func (h *Handler) Ping(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", http.DetectContentType([]byte("pong")))
 	w.Write([]byte("pong"))
 }
-func (h *Handler) Me(w http.ResponseWriter, r *http.Request) {
-	user := r.Context().Value(domain.UserKey{})
-	SuccessResponse(user, "success").Send(w, r, http.StatusOK)
-}
 
-// swagger:route POST /signup signup signupRequest
-// User registration can be done with this endpoint.
-// responses:
-//
-//	200: signupSuccessResponse
-//	409: signupErrorResponse
-//	401: signupErrorResponse
-//	500: signupErrorResponse
+// SignUp registers a user in a new tenant. It is documented in register.go.
 func (h *Handler) SignUp(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 	tenantId := uuid.NewString()
 
-	if err := h.tenantUsecase.Create(ctx, tenantId); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-
 	addUserRequest := new(domain.AddUserRequest)
-	decoder := json.NewDecoder(r.Body)
-	err := decoder.Decode(&addUserRequest)
-	if err != nil {
-		fmt.Println(err)
-		ErrorResponse(err.Error()).Send(w, r, http.StatusBadRequest)
+	if err := bind.JSON(w, r, addUserRequest); err != nil {
+		h.sendError(w, r, err)
 		return
 	}
 
-	err = h.authUseCase.SignUp(ctx, addUserRequest, tenantId)
-	h.l.Debug("signup", "error", err)
+	err := h.authUseCase.SignUp(ctx, addUserRequest, tenantId)
 	if err != nil {
-		ErrorResponse(err.Error()).Send(w, r, http.StatusBadRequest)
+		h.sendError(w, r, err)
 		return
 	}
 	SuccessResponse("success", "signed up successfully").Send(w, r, http.StatusOK)
@@ -96,35 +100,41 @@ func (h *Handler) SignUp(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	addUserRequest := new(domain.AddUserRequest)
 
-	decoder := json.NewDecoder(r.Body)
-	err := decoder.Decode(&addUserRequest)
-	if err != nil {
-		ErrorResponse(err.Error()).Send(w, r, http.StatusBadRequest)
+	// the password rules only apply to new passwords, accounts created before
+	// them must still be able to log in
+	if err := bind.DecodeJSON(w, r, addUserRequest); err != nil {
+		h.sendError(w, r, err)
+		return
 	}
 	ctx := r.Context()
 	tokens, err := h.authUseCase.Login(ctx, addUserRequest)
 	if err != nil {
-		if err == domain.ErrUserNotFound {
-			ErrorResponse(err.Error()).Send(w, r, http.StatusUnauthorized)
-			return
+		// never tell whether the email exists
+		if errors.Is(err, domain.ErrUserNotFound) {
+			err = domain.ErrUserInvalidCredentials.Wrap(err)
 		}
-
-		ErrorResponse(err.Error()).Send(w, r, http.StatusInternalServerError)
+		h.sendError(w, r, err)
+		return
+	}
+	h.setCookieValues(w, r, tokens)
+	// a new session gets a new CSRF token
+	if _, err := csrf.Issue(w, h.csrfCookie()); err != nil {
+		h.sendError(w, r, err)
 		return
 	}
-	h.setCookieValues(w, tokens)
 	SuccessResponse(tokens, "Login successful").Send(w, r, http.StatusOK)
 }
 
-func (h *Handler) setCookieValues(w http.ResponseWriter, tokens *domain.UserTokens) {
+func (h *Handler) setCookieValues(w http.ResponseWriter, r *http.Request, tokens *domain.UserTokens) {
 	cookie := http.Cookie{
 		Name:     domain.AccessTokenKey,
 		Value:    tokens.AccessToken,
 		Path:     "/",
-		MaxAge:   3600,
+		Domain:   h.cookies.Domain,
+		MaxAge:   int(h.cookies.AccessTokenMaxAge.Seconds()),
 		HttpOnly: true,
-		// Secure:   true,
-		SameSite: http.SameSiteLaxMode,
+		Secure:   h.cookies.Secure,
+		SameSite: h.cookies.SameSite,
 	}
 	// Use the http.SetCookie() function to send the cookie to the client.
 	// Behind the scenes this adds a `Set-Cookie` header to the response
@@ -134,15 +144,54 @@ func (h *Handler) setCookieValues(w http.ResponseWriter, tokens *domain.UserToke
 	cookie = http.Cookie{
 		Name:     domain.RefreshTokenKey,
 		Value:    tokens.RefreshToken,
-		Path:     "/auth/refresh-access",
-		MaxAge:   36000,
+		Path:     refreshPath(r),
+		Domain:   h.cookies.Domain,
+		MaxAge:   int(h.cookies.RefreshTokenMaxAge.Seconds()),
 		HttpOnly: true,
-		// Secure:   true,
-		SameSite: http.SameSiteLaxMode,
+		Secure:   h.cookies.Secure,
+		SameSite: h.cookies.SameSite,
 	}
 	http.SetCookie(w, &cookie)
 }
 
+// CSRFTokenResponse carries the token to echo in the X-CSRF-Token header.
+type CSRFTokenResponse struct {
+	Token string `json:"csrf_token"`
+}
+
+// CSRFToken issues a new CSRF token cookie for cookie authenticated clients
+// and returns it. It is documented in register.go.
+func (h *Handler) CSRFToken(w http.ResponseWriter, r *http.Request) {
+	token, err := csrf.Issue(w, h.csrfCookie())
+	if err != nil {
+		h.sendError(w, r, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	SuccessResponse(CSRFTokenResponse{Token: token}, "CSRF token issued").Send(w, r, http.StatusOK)
+}
+
+// csrfCookie lives as long as the refresh token cookie, the longest a
+// cookie session lasts.
+func (h *Handler) csrfCookie() csrf.CookieOptions {
+	return csrf.CookieOptions{
+		Secure:   h.cookies.Secure,
+		Domain:   h.cookies.Domain,
+		SameSite: h.cookies.SameSite,
+		MaxAge:   h.cookies.RefreshTokenMaxAge,
+	}
+}
+
+// refreshPath is the refresh endpoint as the client addresses it, with or
+// without a version prefix, so the refresh cookie is only sent there.
+func refreshPath(r *http.Request) string {
+	prefix, _, found := strings.Cut(r.URL.Path, "/auth/")
+	if !found {
+		prefix = ""
+	}
+	return prefix + "/auth/refresh-access"
+}
+
 /**
  * Middleware
  * This is the middleware that validates the access token.
@@ -152,26 +201,28 @@ func (h *Handler) MiddlewareValidateAccessToken(next http.Handler) http.Handler
 
 		token, err := h.extractToken(r)
 		if err != nil {
-			h.l.Error("Token not provided or malformed")
-			ErrorResponse(err.Error()).Send(w, r, http.StatusBadRequest)
+			h.sendError(w, r, err)
 			return
 		}
 
 		userId, err := h.authUseCase.ValidateAccessToken(r.Context(), token)
 		if err != nil {
-			h.l.Error("token validation failed", "error", err)
-			ErrorResponse(err.Error()).Send(w, r, http.StatusBadRequest)
+			h.sendError(w, r, err)
 			return
 		}
 		ctx := context.WithValue(r.Context(), domain.UserIDKey{}, userId)
 		r = r.WithContext(ctx)
-		user, error := h.userUseCase.GetUserByID(ctx, userId)
-		if error != nil {
-			h.l.Error("unable to get user", "error", error)
-			ErrorResponse(error.Error()).Send(w, r, http.StatusInternalServerError)
+		user, err := h.userUseCase.GetUserByID(ctx, userId)
+		if err != nil {
+			// the token outlived its user
+			if errors.Is(err, domain.ErrUserNotFound) {
+				err = domain.ErrInvalidToken.Wrap(err)
+			}
+			h.sendError(w, r, err)
 			return
 		}
 		ctx = context.WithValue(r.Context(), domain.UserKey{}, user)
+		requestlog.SetUser(ctx, user.ID, user.TenantID)
 		r = r.WithContext(ctx)
 		next.ServeHTTP(w, r)
 	})
@@ -179,31 +230,39 @@ func (h *Handler) MiddlewareValidateAccessToken(next http.Handler) http.Handler
 func (h *Handler) RefreshAccess(w http.ResponseWriter, r *http.Request) {
 	refreshToken, err := getTokenFromCookie(r, domain.RefreshTokenKey)
 	if err != nil {
-		ErrorResponse(err.Error()).Send(w, r, http.StatusBadRequest)
+		h.sendError(w, r, domain.ErrInvalidToken.Wrap(err))
 		return
 	}
 	tokens, err := h.authUseCase.RefreshTokenAccess(r.Context(), refreshToken)
 	if err != nil {
-		ErrorResponse(err.Error()).Send(w, r, http.StatusBadRequest)
+		if errors.Is(err, domain.ErrUserNotFound) {
+			err = domain.ErrInvalidToken.Wrap(err)
+		}
+		h.sendError(w, r, err)
 		return
 	}
-	h.setCookieValues(w, tokens)
+	h.setCookieValues(w, r, tokens)
 	SuccessResponse(tokens, "Refresh access token successful").Send(w, r, http.StatusOK)
 }
+
+// extractToken prefers the Authorization header over the access token
+// cookie. Only cookie authenticated requests need a CSRF token, so a request
+// sending both must be treated as the bearer request csrf.Protect let in.
 func (h *Handler) extractToken(r *http.Request) (string, error) {
-	token, err := getTokenFromCookie(r, domain.AccessTokenKey)
-	if err != nil {
-		h.l.Error("unable to get token from cookie", "error", err)
-	} else {
-		return token, nil
+	authHeader := r.Header.Get("Authorization")
+	if authHeader != "" {
+		authHeaderContent := strings.Split(authHeader, " ")
+		if len(authHeaderContent) != 2 {
+			return "", domain.ErrInvalidToken
+		}
+		return authHeaderContent[1], nil
 	}
 
-	authHeader := r.Header.Get("Authorization")
-	authHeaderContent := strings.Split(authHeader, " ")
-	if len(authHeaderContent) != 2 {
-		return "", errors.New("TOKEN NOT PROVIDED OR MALFORMED")
+	token, err := getTokenFromCookie(r, domain.AccessTokenKey)
+	if err != nil {
+		return "", domain.ErrInvalidToken.Wrap(err)
 	}
-	return authHeaderContent[1], nil
+	return token, nil
 }
 func getTokenFromCookie(r *http.Request, tokenType string) (string, error) {
 	// Retrieve the cookie from the request using its name (which in our case is
@@ -216,7 +275,6 @@ func getTokenFromCookie(r *http.Request, tokenType string) (string, error) {
 		case errors.Is(err, http.ErrNoCookie):
 			return "", http.ErrNoCookie
 		default:
-			log.Println(err)
 			return "", err
 		}
 
@@ -0,0 +1,18 @@
+package http
+
+import (
+	"cleanarch/boiler/internal/utils/problem"
+	"net/http"
+)
+
+// sendError answers with the application/problem+json matching err. Internal
+// errors are logged with their cause and reach the client as an opaque 500.
+func (h *Handler) sendError(w http.ResponseWriter, r *http.Request, err error) {
+	p := problem.From(r, err)
+	if p.Status >= http.StatusInternalServerError {
+		h.l.WithContext(r.Context()).Error("request failed", "error", err)
+	} else {
+		h.l.WithContext(r.Context()).Debug("request rejected", "code", p.Code, "error", err)
+	}
+	p.Write(w)
+}
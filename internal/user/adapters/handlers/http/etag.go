@@ -0,0 +1,110 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"cleanarch/boiler/internal/user/domain"
+	"cleanarch/boiler/internal/utils/apperr"
+)
+
+var ErrIfMatchRequired = apperr.New(apperr.PreconditionRequired, "if_match_required", "If-Match header is required")
+var ErrIfMatchMalformed = apperr.New(apperr.Validation, "if_match_malformed", "If-Match header is malformed")
+
+// setETag exposes the version of a resource as a strong entity tag.
+func setETag(w http.ResponseWriter, version int64) {
+	w.Header().Set("ETag", strconv.Quote(strconv.FormatInt(version, 10)))
+}
+
+// ifMatchVersion reads the version a client expects to update from the
+// If-Match header: `*`, matching any version, or a comma separated list of
+// entity tags (RFC 9110 §13.1.1). If-Match uses strong comparison, so weak
+// tags and tags that are not one of our versions never match; when no tag is
+// left the precondition fails with domain.ErrConflict. current reads the
+// stored version and is only called when the list names several versions.
+// Only a syntactically broken header is rejected as malformed.
+func ifMatchVersion(r *http.Request, current func() (int64, error)) (int64, error) {
+	values := r.Header.Values("If-Match")
+	value := strings.TrimSpace(strings.Join(values, ","))
+	if value == "" {
+		return 0, ErrIfMatchRequired
+	}
+	if value == "*" {
+		return domain.AnyVersion, nil
+	}
+	tags, err := parseEntityTags(value)
+	if err != nil {
+		return 0, err
+	}
+
+	var versions []int64
+	for _, tag := range tags {
+		if tag.weak {
+			continue
+		}
+		version, err := strconv.ParseInt(tag.opaque, 10, 64)
+		if err != nil || version < 0 {
+			continue
+		}
+		versions = append(versions, version)
+	}
+	switch len(versions) {
+	case 0:
+		return 0, domain.ErrConflict
+	case 1:
+		return versions[0], nil
+	}
+	// the update is a compare-and-swap on a single version, pick the stored
+	// one if the client listed it
+	stored, err := current()
+	if err != nil {
+		return 0, err
+	}
+	for _, version := range versions {
+		if version == stored {
+			return version, nil
+		}
+	}
+	return 0, domain.ErrConflict
+}
+
+type entityTag struct {
+	weak   bool
+	opaque string
+}
+
+// parseEntityTags parses a non-empty list of entity tags such as
+// `"3", W/"4"`. Commas are allowed inside a tag, `*` is not part of a list.
+func parseEntityTags(value string) ([]entityTag, error) {
+	var tags []entityTag
+	for {
+		value = strings.TrimLeft(value, " \t,")
+		if value == "" {
+			break
+		}
+		var tag entityTag
+		if strings.HasPrefix(value, "W/") {
+			tag.weak = true
+			value = value[2:]
+		}
+		if !strings.HasPrefix(value, `"`) {
+			return nil, ErrIfMatchMalformed
+		}
+		end := strings.IndexByte(value[1:], '"')
+		if end < 0 {
+			return nil, ErrIfMatchMalformed
+		}
+		tag.opaque = value[1 : end+1]
+		tags = append(tags, tag)
+
+		value = strings.TrimLeft(value[end+2:], " \t")
+		if value != "" && value[0] != ',' {
+			return nil, ErrIfMatchMalformed
+		}
+	}
+	if len(tags) == 0 {
+		return nil, ErrIfMatchMalformed
+	}
+	return tags, nil
+}
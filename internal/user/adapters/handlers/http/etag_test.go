@@ -0,0 +1,55 @@
+package http
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"cleanarch/boiler/internal/user/domain"
+)
+
+func TestIfMatchVersion(t *testing.T) {
+	stored := func() (int64, error) { return 4, nil }
+	tests := []struct {
+		header  string
+		want    int64
+		wantErr error
+	}{
+		{header: "", wantErr: ErrIfMatchRequired},
+		{header: "*", want: domain.AnyVersion},
+		{header: `"3"`, want: 3},
+		{header: ` "3" `, want: 3},
+		// strong comparison: weak tags never match
+		{header: `W/"3"`, wantErr: domain.ErrConflict},
+		{header: `W/"3", "5"`, want: 5},
+		{header: `"a,b"`, wantErr: domain.ErrConflict},
+		{header: `"-1"`, wantErr: domain.ErrConflict},
+		// several versions are resolved against the stored one
+		{header: `"3", "4"`, want: 4},
+		{header: `"3","4",`, want: 4},
+		{header: `"1", "2"`, wantErr: domain.ErrConflict},
+		{header: `3`, wantErr: ErrIfMatchMalformed},
+		{header: `"3`, wantErr: ErrIfMatchMalformed},
+		{header: `"3" "4"`, wantErr: ErrIfMatchMalformed},
+		{header: `"3", *`, wantErr: ErrIfMatchMalformed},
+		{header: `,`, wantErr: ErrIfMatchMalformed},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("PATCH", "/me", nil)
+		if tt.header != "" {
+			r.Header.Set("If-Match", tt.header)
+		}
+		got, err := ifMatchVersion(r, stored)
+		if !errors.Is(err, tt.wantErr) || (err == nil && got != tt.want) {
+			t.Errorf("ifMatchVersion(%q) = %d, %v, want %d, %v", tt.header, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	// header lines combine into one list
+	r := httptest.NewRequest("PATCH", "/me", nil)
+	r.Header.Add("If-Match", `W/"4"`)
+	r.Header.Add("If-Match", `"4"`)
+	if got, err := ifMatchVersion(r, stored); err != nil || got != 4 {
+		t.Errorf("ifMatchVersion() over two lines = %d, %v, want 4", got, err)
+	}
+}
@@ -0,0 +1,121 @@
+package http
+
+import (
+	"cleanarch/boiler/internal/user/domain"
+	"cleanarch/boiler/internal/utils/bind"
+	"cleanarch/boiler/internal/utils/validate"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/render"
+)
+
+// ClientCredentials maps the ids of the clients allowed to call the client
+// authenticated endpoints to their secrets.
+type ClientCredentials map[string]string
+
+// IntrospectionRequest is the form posted to the introspection endpoint.
+type IntrospectionRequest struct {
+	Token         string `json:"token" validate:"required"`
+	TokenTypeHint string `json:"token_type_hint,omitempty" validate:"omitempty,oneof=access_token refresh_token"`
+}
+
+// IntrospectionResponse is the RFC 7662 introspection response. Inactive
+// tokens only carry active.
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	TokenType string `json:"token_type,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	TenantID  string `json:"tenant_id,omitempty"`
+	TokenID   string `json:"jti,omitempty"`
+	Issuer    string `json:"iss,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+}
+
+// MiddlewareAuthenticateClient admits requests carrying the HTTP Basic
+// credentials of a configured client.
+func (h *Handler) MiddlewareAuthenticateClient(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok || !h.clients.valid(id, secret) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="introspection", charset="UTF-8"`)
+			h.sendError(w, r, domain.ErrInvalidClient)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// valid compares digests so that neither the secret nor its length leaks
+// through timing.
+func (c ClientCredentials) valid(id, secret string) bool {
+	want, ok := c[id]
+	if !ok {
+		return false
+	}
+	got, expected := sha256.Sum256([]byte(secret)), sha256.Sum256([]byte(want))
+	return subtle.ConstantTimeCompare(got[:], expected[:]) == 1
+}
+
+// Introspect tells a resource server whether a token is active and whom it
+// was issued to. It is documented in register.go.
+func (h *Handler) Introspect(w http.ResponseWriter, r *http.Request) {
+	req, err := introspectionRequest(w, r)
+	if err != nil {
+		h.sendError(w, r, err)
+		return
+	}
+	info, err := h.authUseCase.Introspect(r.Context(), req.Token, req.TokenTypeHint)
+	if err != nil {
+		h.sendError(w, r, err)
+		return
+	}
+
+	resp := IntrospectionResponse{Active: info.Active}
+	if info.Active {
+		resp.TokenType = info.TokenType
+		resp.Scope = strings.Join(info.Scopes, " ")
+		resp.Subject = info.Subject
+		resp.TenantID = info.TenantID
+		resp.TokenID = info.TokenID
+		resp.Issuer = info.Issuer
+		if !info.IssuedAt.IsZero() {
+			resp.IssuedAt = info.IssuedAt.Unix()
+		}
+		if !info.ExpiresAt.IsZero() {
+			resp.ExpiresAt = info.ExpiresAt.Unix()
+		}
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	render.JSON(w, r, resp)
+}
+
+// introspectionRequest reads the form encoded body RFC 7662 prescribes.
+func introspectionRequest(w http.ResponseWriter, r *http.Request) (*IntrospectionRequest, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/x-www-form-urlencoded" {
+		return nil, bind.ErrUnsupportedMediaType.WithMessage("Content-Type must be application/x-www-form-urlencoded")
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, bind.MaxBytes)
+	if err := r.ParseForm(); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return nil, bind.ErrBodyTooLarge.Wrap(err)
+		}
+		return nil, bind.ErrMalformedBody.WithMessage("request body is not a valid form").Wrap(err)
+	}
+	req := &IntrospectionRequest{
+		Token:         r.PostForm.Get("token"),
+		TokenTypeHint: r.PostForm.Get("token_type_hint"),
+	}
+	if err := validate.Struct(req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
@@ -0,0 +1,85 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"cleanarch/boiler/internal/user/domain"
+	"cleanarch/boiler/internal/utils/logger"
+)
+
+type introspectingAuth struct {
+	AuthUseCases
+	hint string
+}
+
+func (a *introspectingAuth) Introspect(ctx context.Context, token, hint string) (*domain.TokenIntrospection, error) {
+	a.hint = hint
+	if token != "good" {
+		return &domain.TokenIntrospection{}, nil
+	}
+	return &domain.TokenIntrospection{
+		Active:    true,
+		TokenType: domain.AccessTokenKey,
+		Subject:   "user-1",
+		TokenID:   "jti-1",
+		TenantID:  "tenant-1",
+		Scopes:    []string{domain.ScopeAPI},
+		ExpiresAt: time.Unix(1700000000, 0),
+	}, nil
+}
+
+func TestIntrospect(t *testing.T) {
+	auth := &introspectingAuth{}
+	h := NewHandler(logger.NewLogger("error"), auth, nil, nil, nil, CookieOptions{}, ClientCredentials{"billing": "s3cret"}, nil)
+	handler := h.MiddlewareAuthenticateClient(http.HandlerFunc(h.Introspect))
+
+	introspect := func(form url.Values, user, password string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/oauth/introspect", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if user != "" {
+			r.SetBasicAuth(user, password)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	for _, creds := range [][2]string{{"", ""}, {"billing", "wrong"}, {"other", "s3cret"}} {
+		w := introspect(url.Values{"token": {"good"}}, creds[0], creds[1])
+		if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("client %q: status = %d, WWW-Authenticate = %q", creds[0], w.Code, w.Header().Get("WWW-Authenticate"))
+		}
+	}
+
+	w := introspect(url.Values{"token": {"good"}, "token_type_hint": {"refresh_token"}}, "billing", "s3cret")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body)
+	}
+	var got map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &got)
+	if got["active"] != true || got["sub"] != "user-1" || got["tenant_id"] != "tenant-1" || got["scope"] != "api" || got["exp"] != float64(1700000000) || got["jti"] != "jti-1" {
+		t.Errorf("response = %v", got)
+	}
+	if auth.hint != domain.RefreshTokenKey {
+		t.Errorf("hint = %q", auth.hint)
+	}
+	if w.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("Cache-Control = %q", w.Header().Get("Cache-Control"))
+	}
+
+	w = introspect(url.Values{"token": {"revoked"}}, "billing", "s3cret")
+	if strings.TrimSpace(w.Body.String()) != `{"active":false}` {
+		t.Errorf("inactive response = %s", w.Body)
+	}
+
+	if w := introspect(url.Values{}, "billing", "s3cret"); w.Code != http.StatusBadRequest {
+		t.Errorf("missing token: status = %d", w.Code)
+	}
+}
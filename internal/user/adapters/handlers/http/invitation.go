@@ -0,0 +1,47 @@
+package http
+
+import (
+	"cleanarch/boiler/internal/user/domain"
+	"cleanarch/boiler/internal/utils/bind"
+	"context"
+	"net/http"
+)
+
+type InvitationUseCases interface {
+	Invite(ctx context.Context, inviter *domain.UserResponse, req *domain.InviteUserRequest) (*domain.IssuedInvitation, error)
+	Accept(ctx context.Context, req *domain.AcceptInvitationRequest) error
+}
+
+// InviteUser invites an email into the tenant of the authenticated user. The
+// response is the only one carrying the invitation token.
+func (h *Handler) InviteUser(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(domain.UserKey{}).(*domain.UserResponse)
+	req := new(domain.InviteUserRequest)
+	if err := bind.JSON(w, r, req); err != nil {
+		h.sendError(w, r, err)
+		return
+	}
+
+	invitation, err := h.invitations.Invite(r.Context(), user, req)
+	if err != nil {
+		h.sendError(w, r, err)
+		return
+	}
+	SuccessResponse(invitation, "user invited").Send(w, r, http.StatusCreated)
+}
+
+// AcceptInvitation signs the invitee up in the inviting tenant. They log in
+// afterwards like any other user.
+func (h *Handler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	req := new(domain.AcceptInvitationRequest)
+	if err := bind.JSON(w, r, req); err != nil {
+		h.sendError(w, r, err)
+		return
+	}
+
+	if err := h.invitations.Accept(r.Context(), req); err != nil {
+		h.sendError(w, r, err)
+		return
+	}
+	SuccessResponse("success", "invitation accepted").Send(w, r, http.StatusOK)
+}
@@ -0,0 +1,80 @@
+package http
+
+import (
+	"cleanarch/boiler/internal/utils/csrf"
+	"cleanarch/boiler/internal/utils/idempotency"
+	"cleanarch/boiler/internal/utils/openapi"
+	"cleanarch/boiler/internal/utils/query"
+	"fmt"
+)
+
+// Security schemes accepted by the authenticated routes. ClientAuth is the
+// HTTP Basic authentication of the clients calling introspection.
+const (
+	BearerAuth = "bearerAuth"
+	CookieAuth = "cookieAuth"
+	ClientAuth = "clientAuth"
+)
+
+// SecuritySchemes describes BearerAuth, CookieAuth and ClientAuth for the
+// API document.
+func SecuritySchemes() map[string]openapi.SecurityScheme {
+	return map[string]openapi.SecurityScheme{
+		BearerAuth: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+		CookieAuth: {Type: "apiKey", In: "cookie", Name: "access_token", Description: "set by login and refresh"},
+		ClientAuth: {Type: "http", Scheme: "basic", Description: "client id and secret from auth.introspection.clients"},
+	}
+}
+
+var authenticated = []string{BearerAuth, CookieAuth}
+
+// envelope and pageEnvelope document Response with a typed data field.
+type envelope[T any] struct {
+	Ok      bool   `json:"ok"`
+	Message string `json:"message"`
+	Data    T      `json:"data"`
+}
+
+type pageEnvelope[T any] struct {
+	Ok         bool   `json:"ok"`
+	Message    string `json:"message"`
+	Data       []T    `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+var ifMatchParameter = openapi.Parameter{
+	Name:        "If-Match",
+	In:          "header",
+	Description: "ETags of the versions the update applies to, or *. Weak tags never match.",
+	Required:    true,
+	Schema:      &openapi.Schema{Type: "string"},
+}
+
+var csrfParameter = openapi.Parameter{
+	Name:        csrf.HeaderName,
+	In:          "header",
+	Description: "required with cookie authentication, the csrf_token cookie issued by /auth/csrf and login",
+	Schema:      &openapi.Schema{Type: "string"},
+}
+
+var idempotencyKeyParameter = openapi.Parameter{
+	Name:        idempotency.HeaderName,
+	In:          "header",
+	Description: "random key making retries replay the first response instead of running again",
+	Schema:      &openapi.Schema{Type: "string"},
+}
+
+var pageParameters = []openapi.Parameter{
+	{Name: "limit", In: "query", Description: fmt.Sprintf("page size, 1 to %d", query.MaxLimit), Schema: &openapi.Schema{Type: "integer"}},
+	{Name: "cursor", In: "query", Description: "next_cursor of the previous page", Schema: &openapi.Schema{Type: "string"}},
+	{Name: "sort", In: "query", Description: "comma separated fields, prefixed with - for descending order", Schema: &openapi.Schema{Type: "string"}},
+}
+
+// problems documents the error statuses of an operation next to its
+// successful responses.
+func problems(responses map[int]openapi.Response, statuses ...int) map[int]openapi.Response {
+	for _, status := range statuses {
+		responses[status] = openapi.ProblemResponse("")
+	}
+	return responses
+}
@@ -0,0 +1,33 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"cleanarch/boiler/internal/utils/query"
+)
+
+// ParsePageRequest builds a query spec from the `limit`, `cursor` and `sort`
+// query parameters shared by all list endpoints, e.g.
+// `?limit=20&sort=-createdAt&cursor=<next_cursor of the previous page>`.
+// Whether the sort fields exist is checked by the repository.
+func ParsePageRequest(r *http.Request) (*query.Spec, error) {
+	values := r.URL.Query()
+	spec := query.New()
+
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > query.MaxLimit {
+			return nil, query.ErrInvalidLimit
+		}
+		spec.Take(n)
+	}
+
+	sorts, err := query.ParseSort(values.Get("sort"))
+	if err != nil {
+		return nil, err
+	}
+	spec.Sort = sorts
+
+	return spec.After(values.Get("cursor")), nil
+}
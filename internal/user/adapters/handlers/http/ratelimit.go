@@ -0,0 +1,46 @@
+package http
+
+import (
+	"net/http"
+
+	"cleanarch/boiler/internal/user/domain"
+)
+
+// RateLimits are the rate limiting middlewares of the user routes. Nil
+// entries are skipped.
+type RateLimits struct {
+	// Credentials guards sign up and login.
+	Credentials func(http.Handler) http.Handler
+	// Authenticated runs after the access token has been validated, so it
+	// can key on the user and tenant.
+	Authenticated []func(http.Handler) http.Handler
+}
+
+// RateLimitByUser keys authenticated requests by user id.
+func RateLimitByUser(r *http.Request) (string, bool) {
+	user, ok := r.Context().Value(domain.UserKey{}).(*domain.UserResponse)
+	if !ok {
+		return "", false
+	}
+	return user.ID, true
+}
+
+// RateLimitByTenant keys authenticated requests by tenant id.
+func RateLimitByTenant(r *http.Request) (string, bool) {
+	user, ok := r.Context().Value(domain.UserKey{}).(*domain.UserResponse)
+	if !ok || user.TenantID == "" {
+		return "", false
+	}
+	return user.TenantID, true
+}
+
+// IdempotencyPrincipal scopes idempotency keys to the authenticated user.
+// Keys sent to public routes such as sign up are shared by anonymous
+// clients, which is why they must be random.
+func IdempotencyPrincipal(r *http.Request) string {
+	user, ok := r.Context().Value(domain.UserKey{}).(*domain.UserResponse)
+	if !ok {
+		return ""
+	}
+	return user.ID
+}
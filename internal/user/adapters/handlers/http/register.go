@@ -1,23 +1,233 @@
 package http
 
 import (
+	"cleanarch/boiler/internal/user/domain"
+	"cleanarch/boiler/internal/utils/csrf"
+	"cleanarch/boiler/internal/utils/openapi"
+	"cleanarch/boiler/internal/webhook"
+	"net/http"
+
 	"github.com/go-chi/chi/v5"
 )
 
-func RegisterAuthHTTPEndpoints(r chi.Router, h *Handler) {
+// RegisterAuthHTTPEndpoints mounts the user routes. idempotent, when not nil,
+// replays retried sign ups, invitation acceptances and authenticated POST
+// requests carrying an Idempotency-Key.
+func RegisterAuthHTTPEndpoints(r chi.Router, h *Handler, limits RateLimits, idempotent func(http.Handler) http.Handler) {
 
 	authRouter := chi.NewRouter()
 
-	authRouter.Post("/signup", h.SignUp)
-	authRouter.Post("/login", h.Login)
-	authRouter.Get("/ping", h.Ping)
+	authRouter.Group(func(r chi.Router) {
+		if limits.Credentials != nil {
+			r.Use(limits.Credentials)
+		}
+		// login is left out: its response hands out tokens and must never
+		// be replayed to anyone resending the key
+		signUp := r
+		if idempotent != nil {
+			signUp = r.With(idempotent)
+		}
+		signUp.Method(http.MethodPost, "/signup", openapi.Handle(h.SignUp, openapi.Operation{
+			Summary:     "Sign up",
+			Description: "Creates a tenant and its first user.",
+			Tags:        []string{"auth"},
+			Parameters:  []openapi.Parameter{idempotencyKeyParameter},
+			Request:     domain.AddUserRequest{},
+			Responses: problems(map[int]openapi.Response{
+				http.StatusOK: {Body: envelope[string]{}},
+			}, http.StatusBadRequest, http.StatusConflict, http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity, http.StatusTooManyRequests),
+		}))
+		signUp.Method(http.MethodPost, "/invitations/accept", openapi.Handle(h.AcceptInvitation, openapi.Operation{
+			Summary:     "Accept an invitation",
+			Description: "Signs the invitee up in the inviting tenant with the token of the invitation and a password of their choice. A token can be used once, until it expires.",
+			Tags:        []string{"auth"},
+			Parameters:  []openapi.Parameter{idempotencyKeyParameter},
+			Request:     domain.AcceptInvitationRequest{},
+			Responses: problems(map[int]openapi.Response{
+				http.StatusOK: {Body: envelope[string]{}},
+			}, http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity, http.StatusTooManyRequests),
+		}))
+		r.Method(http.MethodPost, "/login", openapi.Handle(h.Login, openapi.Operation{
+			Summary:     "Log in",
+			Description: "Returns the tokens and sets them as the access_token and refresh_token cookies.",
+			Tags:        []string{"auth"},
+			Request:     domain.AddUserRequest{},
+			Responses: problems(map[int]openapi.Response{
+				http.StatusOK: {Body: envelope[domain.UserTokens]{}},
+			}, http.StatusBadRequest, http.StatusUnauthorized, http.StatusUnsupportedMediaType, http.StatusTooManyRequests),
+		}))
+	})
+	authRouter.Method(http.MethodGet, "/ping", openapi.Handle(h.Ping, openapi.Operation{
+		Summary: "Ping",
+		Tags:    []string{"auth"},
+		Responses: map[int]openapi.Response{
+			http.StatusOK: {Body: "pong", ContentType: "text/plain"},
+		},
+	}))
+
+	authRouter.Method(http.MethodGet, "/csrf", openapi.Handle(h.CSRFToken, openapi.Operation{
+		Summary:     "Issue a CSRF token",
+		Description: "Sets the csrf_token cookie and returns its value. Cookie authenticated clients send it in the X-CSRF-Token header of state changing requests; login issues a new one.",
+		Tags:        []string{"auth"},
+		Responses: map[int]openapi.Response{
+			http.StatusOK: {Body: envelope[CSRFTokenResponse]{}},
+		},
+	}))
 
 	authenticatedRouter := chi.NewRouter()
+	// checked before the token so forged requests learn nothing about it
+	authenticatedRouter.Use(csrf.Protect(domain.AccessTokenKey))
 	authenticatedRouter.Use(h.MiddlewareValidateAccessToken)
-	authenticatedRouter.Get("/me", h.Me)
-	authRouter.Get("/refresh-access", h.RefreshAccess)
+	for _, limit := range limits.Authenticated {
+		if limit != nil {
+			authenticatedRouter.Use(limit)
+		}
+	}
+	if idempotent != nil {
+		authenticatedRouter.Use(idempotent)
+	}
+	authenticatedRouter.Method(http.MethodGet, "/me", openapi.Handle(h.Me, openapi.Operation{
+		Summary:     "Current user",
+		Description: "The ETag header carries the version to send in If-Match when updating.",
+		Tags:        []string{"users"},
+		Security:    authenticated,
+		Responses: problems(map[int]openapi.Response{
+			http.StatusOK: {Body: envelope[domain.UserResponse]{}},
+		}, http.StatusUnauthorized, http.StatusTooManyRequests),
+	}))
+	authenticatedRouter.Method(http.MethodPatch, "/me", openapi.Handle(h.UpdateMe, openapi.Operation{
+		Summary:     "Update the current user",
+		Description: "Applies a partial profile update. Fields left out are unchanged.",
+		Tags:        []string{"users"},
+		Security:    authenticated,
+		Parameters:  []openapi.Parameter{ifMatchParameter, csrfParameter},
+		Request:     domain.UpdateUserRequest{},
+		Responses: problems(map[int]openapi.Response{
+			http.StatusOK: {Body: envelope[domain.UserResponse]{}},
+		}, http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusPreconditionFailed, http.StatusPreconditionRequired, http.StatusTooManyRequests),
+	}))
+	authenticatedRouter.Method(http.MethodGet, "/users", openapi.Handle(h.ListUsers, openapi.Operation{
+		Summary:    "List the users of the tenant",
+		Tags:       []string{"users"},
+		Security:   authenticated,
+		Parameters: pageParameters,
+		Responses: problems(map[int]openapi.Response{
+			http.StatusOK: {Body: pageEnvelope[domain.UserResponse]{}},
+		}, http.StatusBadRequest, http.StatusUnauthorized, http.StatusTooManyRequests),
+	}))
+	authenticatedRouter.Method(http.MethodPost, "/users/invitations", openapi.Handle(h.InviteUser, openapi.Operation{
+		Summary:     "Invite a user into the tenant",
+		Description: "Returns the token to pass on to the invitee, which is not shown again. Inviting an email again replaces its pending invitation.",
+		Tags:        []string{"users"},
+		Security:    authenticated,
+		Parameters:  []openapi.Parameter{csrfParameter, idempotencyKeyParameter},
+		Request:     domain.InviteUserRequest{},
+		Responses: problems(map[int]openapi.Response{
+			http.StatusCreated: {Body: envelope[domain.IssuedInvitation]{}},
+		}, http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity, http.StatusTooManyRequests),
+	}))
+	authenticatedRouter.Method(http.MethodGet, "/tenant", openapi.Handle(h.GetTenant, openapi.Operation{
+		Summary:  "Current tenant",
+		Tags:     []string{"tenants"},
+		Security: authenticated,
+		Responses: problems(map[int]openapi.Response{
+			http.StatusOK: {Body: envelope[domain.Tenant]{}},
+		}, http.StatusUnauthorized, http.StatusNotFound, http.StatusTooManyRequests),
+	}))
+	authenticatedRouter.Method(http.MethodPatch, "/tenant", openapi.Handle(h.UpdateTenant, openapi.Operation{
+		Summary:     "Update the current tenant",
+		Description: "A settings map or allowed_origins list replaces the stored one. Allowed origins may call the API cross origin with credentials.",
+		Tags:        []string{"tenants"},
+		Security:    authenticated,
+		Parameters:  []openapi.Parameter{ifMatchParameter, csrfParameter},
+		Request:     domain.UpdateTenantRequest{},
+		Responses: problems(map[int]openapi.Response{
+			http.StatusOK: {Body: envelope[domain.Tenant]{}},
+		}, http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusPreconditionFailed, http.StatusPreconditionRequired, http.StatusTooManyRequests),
+	}))
+	authenticatedRouter.Method(http.MethodPost, "/webhooks", openapi.Handle(h.CreateWebhook, openapi.Operation{
+		Summary:     "Subscribe a webhook",
+		Description: "Events of the tenant are POSTed to url, signed in the Webhook-Signature header with the returned secret, which is not shown again. Failed deliveries are retried with exponential backoff.",
+		Tags:        []string{"webhooks"},
+		Security:    authenticated,
+		Parameters:  []openapi.Parameter{csrfParameter, idempotencyKeyParameter},
+		Request:     domain.CreateWebhookRequest{},
+		Responses: problems(map[int]openapi.Response{
+			http.StatusCreated: {Body: envelope[webhook.Subscription]{}},
+		}, http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusConflict, http.StatusUnprocessableEntity, http.StatusTooManyRequests),
+	}))
+	authenticatedRouter.Method(http.MethodGet, "/webhooks", openapi.Handle(h.ListWebhooks, openapi.Operation{
+		Summary:  "List the webhooks of the tenant",
+		Tags:     []string{"webhooks"},
+		Security: authenticated,
+		Responses: problems(map[int]openapi.Response{
+			http.StatusOK: {Body: envelope[[]webhook.Subscription]{}},
+		}, http.StatusUnauthorized, http.StatusTooManyRequests),
+	}))
+	authenticatedRouter.Method(http.MethodDelete, "/webhooks/{id}", openapi.Handle(h.DeleteWebhook, openapi.Operation{
+		Summary:     "Delete a webhook",
+		Description: "Deliveries still queued for the webhook fail.",
+		Tags:        []string{"webhooks"},
+		Security:    authenticated,
+		Parameters:  []openapi.Parameter{csrfParameter},
+		Responses: problems(map[int]openapi.Response{
+			http.StatusNoContent: {},
+		}, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusTooManyRequests),
+	}))
+	authenticatedRouter.Method(http.MethodGet, "/webhooks/{id}/deliveries", openapi.Handle(h.ListWebhookDeliveries, openapi.Operation{
+		Summary:     "List the deliveries of a webhook",
+		Description: "Newest first unless sorted otherwise, on createdAt, event or status. Each delivery keeps its latest attempts.",
+		Tags:        []string{"webhooks"},
+		Security:    authenticated,
+		Parameters:  pageParameters,
+		Responses: problems(map[int]openapi.Response{
+			http.StatusOK: {Body: pageEnvelope[webhook.Delivery]{}},
+		}, http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusTooManyRequests),
+	}))
+	authenticatedRouter.Method(http.MethodPost, "/webhooks/{id}/deliveries/{deliveryId}/replay", openapi.Handle(h.ReplayWebhookDelivery, openapi.Operation{
+		Summary:     "Replay a delivery",
+		Description: "Queues the payload of a past delivery again as a new delivery, whatever the outcome of the original.",
+		Tags:        []string{"webhooks"},
+		Security:    authenticated,
+		Parameters:  []openapi.Parameter{csrfParameter, idempotencyKeyParameter},
+		Responses: problems(map[int]openapi.Response{
+			http.StatusAccepted: {Body: envelope[webhook.Delivery]{}},
+		}, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity, http.StatusTooManyRequests),
+	}))
+	authRouter.Method(http.MethodGet, "/refresh-access", openapi.Handle(h.RefreshAccess, openapi.Operation{
+		Summary:     "Refresh the tokens",
+		Description: "Rotates the refresh token sent in the refresh_token cookie.",
+		Tags:        []string{"auth"},
+		Parameters: []openapi.Parameter{
+			{Name: "refresh_token", In: "cookie", Required: true, Schema: &openapi.Schema{Type: "string"}},
+		},
+		Responses: problems(map[int]openapi.Response{
+			http.StatusOK: {Body: envelope[domain.UserTokens]{}},
+		}, http.StatusUnauthorized),
+	}))
+
+	// RFC 7662 introspection for resource servers that cannot verify tokens
+	// themselves
+	oauthRouter := chi.NewRouter()
+	if limits.Credentials != nil {
+		oauthRouter.Use(limits.Credentials)
+	}
+	oauthRouter.Use(h.MiddlewareAuthenticateClient)
+	oauthRouter.Method(http.MethodPost, "/introspect", openapi.Handle(h.Introspect, openapi.Operation{
+		Summary:            "Introspect a token",
+		Description:        "Tells whether an access or refresh token is active, following RFC 7662. Revoked, rotated, expired and malformed tokens as well as tokens of deleted users are inactive.",
+		Tags:               []string{"auth"},
+		Security:           []string{ClientAuth},
+		Request:            IntrospectionRequest{},
+		RequestContentType: "application/x-www-form-urlencoded",
+		Responses: problems(map[int]openapi.Response{
+			http.StatusOK: {Body: IntrospectionResponse{}},
+		}, http.StatusBadRequest, http.StatusUnauthorized, http.StatusUnsupportedMediaType, http.StatusTooManyRequests),
+	}))
+
 	r.Mount("/", authenticatedRouter)
 	// Mounting the new Sub Router on the main router
 	r.Mount("/auth", authRouter)
+	r.Mount("/oauth", oauthRouter)
 
 }
@@ -0,0 +1,31 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+
+	"cleanarch/boiler/internal/utils/openapi"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestRoutesAreDocumented(t *testing.T) {
+	r := chi.NewRouter()
+	RegisterAuthHTTPEndpoints(r, &Handler{}, RateLimits{}, func(next http.Handler) http.Handler { return next })
+
+	missing, err := openapi.Undocumented(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, route := range missing {
+		t.Errorf("%s has no OpenAPI operation, register it with openapi.Handle", route)
+	}
+
+	doc, err := openapi.Build(openapi.Info{Title: "test", Version: "1"}, SecuritySchemes(), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Paths["/auth/login"]["post"] == nil || doc.Paths["/me"]["patch"] == nil {
+		t.Errorf("paths = %v", doc.Paths)
+	}
+}
@@ -3,13 +3,16 @@ package http
 import (
 	"net/http"
 
+	"cleanarch/boiler/internal/utils/query"
+
 	"github.com/go-chi/render"
 )
 
 type Response struct {
-	Ok      bool        `json:"ok"`
-	Message string      `json:"message" omitempty:"true"`
-	Data    interface{} `json:"data" omitempty:"true"`
+	Ok         bool        `json:"ok"`
+	Message    string      `json:"message" omitempty:"true"`
+	Data       interface{} `json:"data" omitempty:"true"`
+	NextCursor string      `json:"next_cursor,omitempty"`
 }
 
 func SuccessResponse(data interface{}, message string) *Response {
@@ -20,12 +23,20 @@ func SuccessResponse(data interface{}, message string) *Response {
 	}
 	return response
 }
-func ErrorResponse(message string) *Response {
-	response := &Response{
-		Ok:      false,
-		Message: message,
+
+// PageResponse wraps one page of a list. next_cursor is omitted on the last
+// page.
+func PageResponse[T any](page *query.Page[T], message string) *Response {
+	items := page.Items
+	if items == nil {
+		items = []T{}
+	}
+	return &Response{
+		Ok:         true,
+		Message:    message,
+		Data:       items,
+		NextCursor: page.NextCursor,
 	}
-	return response
 }
 func (resp *Response) Send(w http.ResponseWriter, r *http.Request, status int) {
 	render.Status(r, status)
@@ -0,0 +1,50 @@
+package http
+
+import (
+	"cleanarch/boiler/internal/user/domain"
+	"cleanarch/boiler/internal/utils/bind"
+	"net/http"
+)
+
+// GetTenant returns the tenant of the authenticated user.
+func (h *Handler) GetTenant(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(domain.UserKey{}).(*domain.UserResponse)
+	tenant, err := h.tenantUsecase.GetByID(r.Context(), user.TenantID)
+	if err != nil {
+		h.sendError(w, r, err)
+		return
+	}
+	setETag(w, tenant.Version)
+	SuccessResponse(tenant, "success").Send(w, r, http.StatusOK)
+}
+
+// UpdateTenant changes the settings of the authenticated user's tenant. Like
+// UpdateMe it requires If-Match and answers 412 when the tenant has changed.
+func (h *Handler) UpdateTenant(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(domain.UserKey{}).(*domain.UserResponse)
+	version, err := ifMatchVersion(r, func() (int64, error) {
+		current, err := h.tenantUsecase.GetByID(r.Context(), user.TenantID)
+		if err != nil {
+			return 0, err
+		}
+		return current.Version, nil
+	})
+	if err != nil {
+		h.sendError(w, r, err)
+		return
+	}
+
+	updateTenantRequest := new(domain.UpdateTenantRequest)
+	if err := bind.JSON(w, r, updateTenantRequest); err != nil {
+		h.sendError(w, r, err)
+		return
+	}
+
+	tenant, err := h.tenantUsecase.Update(r.Context(), user.TenantID, updateTenantRequest, version)
+	if err != nil {
+		h.sendError(w, r, err)
+		return
+	}
+	setETag(w, tenant.Version)
+	SuccessResponse(tenant, "tenant updated").Send(w, r, http.StatusOK)
+}
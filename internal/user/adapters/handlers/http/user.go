@@ -0,0 +1,69 @@
+package http
+
+import (
+	"cleanarch/boiler/internal/user/domain"
+	"cleanarch/boiler/internal/utils/bind"
+	"net/http"
+)
+
+// Me returns the authenticated user. It is read again, bypassing the user
+// cache, because clients send its ETag back in If-Match and a version cached
+// before an update on another instance would only earn them a 412.
+func (h *Handler) Me(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(domain.UserKey{}).(*domain.UserResponse)
+	current, err := h.userUseCase.ReloadUserByID(r.Context(), user.ID)
+	if err != nil {
+		h.sendError(w, r, err)
+		return
+	}
+	setETag(w, current.Version)
+	SuccessResponse(current, "success").Send(w, r, http.StatusOK)
+}
+
+// UpdateMe applies a partial profile update to the authenticated user. The
+// client must send the ETag it last saw in If-Match; a stale tag yields 412.
+func (h *Handler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(domain.UserKey{}).(*domain.UserResponse)
+	version, err := ifMatchVersion(r, func() (int64, error) {
+		current, err := h.userUseCase.ReloadUserByID(r.Context(), user.ID)
+		if err != nil {
+			return 0, err
+		}
+		return current.Version, nil
+	})
+	if err != nil {
+		h.sendError(w, r, err)
+		return
+	}
+
+	updateUserRequest := new(domain.UpdateUserRequest)
+	if err := bind.JSON(w, r, updateUserRequest); err != nil {
+		h.sendError(w, r, err)
+		return
+	}
+
+	updated, err := h.userUseCase.UpdateUser(r.Context(), user.ID, updateUserRequest, version)
+	if err != nil {
+		h.sendError(w, r, err)
+		return
+	}
+	setETag(w, updated.Version)
+	SuccessResponse(updated, "user updated").Send(w, r, http.StatusOK)
+}
+
+// ListUsers pages through the users of the authenticated user's tenant.
+func (h *Handler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(domain.UserKey{}).(*domain.UserResponse)
+	spec, err := ParsePageRequest(r)
+	if err != nil {
+		h.sendError(w, r, err)
+		return
+	}
+
+	page, err := h.userUseCase.ListUsers(r.Context(), user.TenantID, *spec)
+	if err != nil {
+		h.sendError(w, r, err)
+		return
+	}
+	PageResponse(page, "success").Send(w, r, http.StatusOK)
+}
@@ -0,0 +1,92 @@
+package http
+
+import (
+	"cleanarch/boiler/internal/user/domain"
+	"cleanarch/boiler/internal/utils/bind"
+	"cleanarch/boiler/internal/utils/query"
+	"cleanarch/boiler/internal/webhook"
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Webhooks manages the webhook subscriptions of tenants and their delivery
+// log.
+type Webhooks interface {
+	Subscribe(ctx context.Context, tenantID, url string, events []string) (*webhook.Subscription, error)
+	Subscriptions(ctx context.Context, tenantID string) ([]*webhook.Subscription, error)
+	Unsubscribe(ctx context.Context, tenantID, id string) error
+	Deliveries(ctx context.Context, tenantID, subscriptionID string, spec query.Spec) (*query.Page[*webhook.Delivery], error)
+	Replay(ctx context.Context, tenantID, subscriptionID, deliveryID string) (*webhook.Delivery, error)
+}
+
+// CreateWebhook subscribes a URL of the tenant to events. The response is
+// the only one carrying the signing secret.
+func (h *Handler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(domain.UserKey{}).(*domain.UserResponse)
+	req := new(domain.CreateWebhookRequest)
+	if err := bind.JSON(w, r, req); err != nil {
+		h.sendError(w, r, err)
+		return
+	}
+
+	sub, err := h.webhooks.Subscribe(r.Context(), user.TenantID, req.URL, req.Events)
+	if err != nil {
+		h.sendError(w, r, err)
+		return
+	}
+	SuccessResponse(sub, "webhook created").Send(w, r, http.StatusCreated)
+}
+
+// ListWebhooks returns the webhook subscriptions of the tenant, without
+// their secrets.
+func (h *Handler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(domain.UserKey{}).(*domain.UserResponse)
+	subs, err := h.webhooks.Subscriptions(r.Context(), user.TenantID)
+	if err != nil {
+		h.sendError(w, r, err)
+		return
+	}
+	SuccessResponse(subs, "success").Send(w, r, http.StatusOK)
+}
+
+// DeleteWebhook removes a subscription. Deliveries still queued for it fail.
+func (h *Handler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(domain.UserKey{}).(*domain.UserResponse)
+	if err := h.webhooks.Unsubscribe(r.Context(), user.TenantID, chi.URLParam(r, "id")); err != nil {
+		h.sendError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListWebhookDeliveries pages through the delivery log of a subscription,
+// newest first by default.
+func (h *Handler) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(domain.UserKey{}).(*domain.UserResponse)
+	spec, err := ParsePageRequest(r)
+	if err != nil {
+		h.sendError(w, r, err)
+		return
+	}
+
+	page, err := h.webhooks.Deliveries(r.Context(), user.TenantID, chi.URLParam(r, "id"), *spec)
+	if err != nil {
+		h.sendError(w, r, err)
+		return
+	}
+	PageResponse(page, "success").Send(w, r, http.StatusOK)
+}
+
+// ReplayWebhookDelivery queues the payload of a past delivery again, as a
+// new delivery.
+func (h *Handler) ReplayWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(domain.UserKey{}).(*domain.UserResponse)
+	delivery, err := h.webhooks.Replay(r.Context(), user.TenantID, chi.URLParam(r, "id"), chi.URLParam(r, "deliveryId"))
+	if err != nil {
+		h.sendError(w, r, err)
+		return
+	}
+	SuccessResponse(delivery, "delivery queued").Send(w, r, http.StatusAccepted)
+}
@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"errors"
+
+	"cleanarch/boiler/internal/user/domain"
+	utilmetrics "cleanarch/boiler/internal/utils/metrics"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// AuthMetrics counts authentication outcomes in Prometheus.
+type AuthMetrics struct {
+	logins           *prometheus.CounterVec
+	refreshRotations prometheus.Counter
+	tokenFailures    *prometheus.CounterVec
+}
+
+func NewAuthMetrics(reg prometheus.Registerer) (*AuthMetrics, error) {
+	m := &AuthMetrics{
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: utilmetrics.Namespace,
+			Subsystem: "auth",
+			Name:      "logins_total",
+			Help:      "Login attempts by result and failure reason.",
+		}, []string{"result", "reason"}),
+		refreshRotations: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: utilmetrics.Namespace,
+			Subsystem: "auth",
+			Name:      "refresh_rotations_total",
+			Help:      "Refresh tokens rotated.",
+		}),
+		tokenFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: utilmetrics.Namespace,
+			Subsystem: "auth",
+			Name:      "token_validation_failures_total",
+			Help:      "Access tokens rejected by reason.",
+		}, []string{"reason"}),
+	}
+	for _, c := range []prometheus.Collector{m.logins, m.refreshRotations, m.tokenFailures} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (m *AuthMetrics) LoginSucceeded() {
+	m.logins.WithLabelValues("success", "").Inc()
+}
+
+func (m *AuthMetrics) LoginFailed(err error) {
+	reason := "error"
+	if errors.Is(err, domain.ErrUserNotFound) || errors.Is(err, domain.ErrUserInvalidCredentials) {
+		reason = "invalid_credentials"
+	}
+	m.logins.WithLabelValues("failure", reason).Inc()
+}
+
+func (m *AuthMetrics) RefreshRotated() {
+	m.refreshRotations.Inc()
+}
+
+func (m *AuthMetrics) TokenValidationFailed(err error) {
+	m.tokenFailures.WithLabelValues(tokenFailureReason(err)).Inc()
+}
+
+func tokenFailureReason(err error) string {
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return "expired"
+	case errors.Is(err, jwt.ErrTokenNotValidYet):
+		return "not_valid_yet"
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return "malformed"
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid), errors.Is(err, jwt.ErrTokenUnverifiable):
+		return "invalid_signature"
+	default:
+		return "invalid_claims"
+	}
+}
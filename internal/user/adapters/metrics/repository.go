@@ -0,0 +1,204 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"cleanarch/boiler/internal/user/domain"
+	utilmetrics "cleanarch/boiler/internal/utils/metrics"
+	"cleanarch/boiler/internal/utils/query"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RepositoryMetrics records the latency of repository methods. Unlike the
+// per command Mongo histogram it tells apart methods sharing a collection,
+// e.g. GetUserByID and GetAuthenticatedUser.
+type RepositoryMetrics struct {
+	duration *prometheus.HistogramVec
+}
+
+func NewRepositoryMetrics(reg prometheus.Registerer) (*RepositoryMetrics, error) {
+	m := &RepositoryMetrics{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: utilmetrics.Namespace,
+			Subsystem: "repository",
+			Name:      "operation_duration_seconds",
+			Help:      "Repository method latency by repository, method and outcome.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"repository", "method", "status"}),
+	}
+	if err := reg.Register(m.duration); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// observe records a call of method that started at start. It is deferred
+// with a pointer to the method's error.
+func (m *RepositoryMetrics) observe(repository, method string, start time.Time, err *error) {
+	status := "ok"
+	if *err != nil {
+		status = "error"
+	}
+	m.duration.WithLabelValues(repository, method, status).Observe(time.Since(start).Seconds())
+}
+
+// UserRepository is the user repository being measured.
+type UserRepository interface {
+	SignUp(ctx context.Context, user *domain.AddUserRequest, tenantId string) (string, error)
+	GetAuthenticatedUser(ctx context.Context, user *domain.AddUserRequest) (*domain.UserResponse, error)
+	GetUserByID(ctx context.Context, id string) (*domain.UserResponse, error)
+	ListUsers(ctx context.Context, tenantId string, spec query.Spec) (*query.Page[*domain.UserResponse], error)
+	UpdateUser(ctx context.Context, id string, update *domain.UpdateUserRequest, expectedVersion int64) (*domain.UserResponse, error)
+	DeleteUser(ctx context.Context, id string) (*domain.UserResponse, error)
+	RestoreUser(ctx context.Context, id string) (*domain.UserResponse, error)
+}
+
+// MeasuredUserRepository decorates a UserRepository with latency metrics.
+type MeasuredUserRepository struct {
+	next UserRepository
+	m    *RepositoryMetrics
+}
+
+func (m *RepositoryMetrics) UserRepository(next UserRepository) *MeasuredUserRepository {
+	return &MeasuredUserRepository{next: next, m: m}
+}
+
+func (r *MeasuredUserRepository) SignUp(ctx context.Context, user *domain.AddUserRequest, tenantId string) (_ string, err error) {
+	defer r.m.observe("user", "SignUp", time.Now(), &err)
+	return r.next.SignUp(ctx, user, tenantId)
+}
+
+func (r *MeasuredUserRepository) GetAuthenticatedUser(ctx context.Context, user *domain.AddUserRequest) (_ *domain.UserResponse, err error) {
+	defer r.m.observe("user", "GetAuthenticatedUser", time.Now(), &err)
+	return r.next.GetAuthenticatedUser(ctx, user)
+}
+
+func (r *MeasuredUserRepository) GetUserByID(ctx context.Context, id string) (_ *domain.UserResponse, err error) {
+	defer r.m.observe("user", "GetUserByID", time.Now(), &err)
+	return r.next.GetUserByID(ctx, id)
+}
+
+func (r *MeasuredUserRepository) ListUsers(ctx context.Context, tenantId string, spec query.Spec) (_ *query.Page[*domain.UserResponse], err error) {
+	defer r.m.observe("user", "ListUsers", time.Now(), &err)
+	return r.next.ListUsers(ctx, tenantId, spec)
+}
+
+func (r *MeasuredUserRepository) UpdateUser(ctx context.Context, id string, update *domain.UpdateUserRequest, expectedVersion int64) (_ *domain.UserResponse, err error) {
+	defer r.m.observe("user", "UpdateUser", time.Now(), &err)
+	return r.next.UpdateUser(ctx, id, update, expectedVersion)
+}
+
+func (r *MeasuredUserRepository) DeleteUser(ctx context.Context, id string) (_ *domain.UserResponse, err error) {
+	defer r.m.observe("user", "DeleteUser", time.Now(), &err)
+	return r.next.DeleteUser(ctx, id)
+}
+
+func (r *MeasuredUserRepository) RestoreUser(ctx context.Context, id string) (_ *domain.UserResponse, err error) {
+	defer r.m.observe("user", "RestoreUser", time.Now(), &err)
+	return r.next.RestoreUser(ctx, id)
+}
+
+// TenantRepository is the tenant repository being measured.
+type TenantRepository interface {
+	Create(ctx context.Context, tenantId string) error
+	GetByID(ctx context.Context, tenantId string) (*domain.Tenant, error)
+	Update(ctx context.Context, tenantId string, update *domain.UpdateTenantRequest, expectedVersion int64) (*domain.Tenant, error)
+	Delete(ctx context.Context, tenantId string) error
+	Restore(ctx context.Context, tenantId string) error
+	HasAllowedOrigin(ctx context.Context, origin string) (bool, error)
+}
+
+// MeasuredTenantRepository decorates a TenantRepository with latency metrics.
+type MeasuredTenantRepository struct {
+	next TenantRepository
+	m    *RepositoryMetrics
+}
+
+func (m *RepositoryMetrics) TenantRepository(next TenantRepository) *MeasuredTenantRepository {
+	return &MeasuredTenantRepository{next: next, m: m}
+}
+
+func (r *MeasuredTenantRepository) Create(ctx context.Context, tenantId string) (err error) {
+	defer r.m.observe("tenant", "Create", time.Now(), &err)
+	return r.next.Create(ctx, tenantId)
+}
+
+func (r *MeasuredTenantRepository) GetByID(ctx context.Context, tenantId string) (_ *domain.Tenant, err error) {
+	defer r.m.observe("tenant", "GetByID", time.Now(), &err)
+	return r.next.GetByID(ctx, tenantId)
+}
+
+func (r *MeasuredTenantRepository) Update(ctx context.Context, tenantId string, update *domain.UpdateTenantRequest, expectedVersion int64) (_ *domain.Tenant, err error) {
+	defer r.m.observe("tenant", "Update", time.Now(), &err)
+	return r.next.Update(ctx, tenantId, update, expectedVersion)
+}
+
+func (r *MeasuredTenantRepository) Delete(ctx context.Context, tenantId string) (err error) {
+	defer r.m.observe("tenant", "Delete", time.Now(), &err)
+	return r.next.Delete(ctx, tenantId)
+}
+
+func (r *MeasuredTenantRepository) Restore(ctx context.Context, tenantId string) (err error) {
+	defer r.m.observe("tenant", "Restore", time.Now(), &err)
+	return r.next.Restore(ctx, tenantId)
+}
+
+func (r *MeasuredTenantRepository) HasAllowedOrigin(ctx context.Context, origin string) (_ bool, err error) {
+	defer r.m.observe("tenant", "HasAllowedOrigin", time.Now(), &err)
+	return r.next.HasAllowedOrigin(ctx, origin)
+}
+
+// JwtRepository is the refresh token repository being measured.
+type JwtRepository interface {
+	CreateRefreshJwt(ctx context.Context, jwt *domain.Jwt, currentRefreshId string) error
+	RefreshJwtExists(ctx context.Context, id string) (bool, error)
+}
+
+// MeasuredJwtRepository decorates a JwtRepository with latency metrics.
+type MeasuredJwtRepository struct {
+	next JwtRepository
+	m    *RepositoryMetrics
+}
+
+func (m *RepositoryMetrics) JwtRepository(next JwtRepository) *MeasuredJwtRepository {
+	return &MeasuredJwtRepository{next: next, m: m}
+}
+
+func (r *MeasuredJwtRepository) CreateRefreshJwt(ctx context.Context, jwt *domain.Jwt, currentRefreshId string) (err error) {
+	defer r.m.observe("jwt", "CreateRefreshJwt", time.Now(), &err)
+	return r.next.CreateRefreshJwt(ctx, jwt, currentRefreshId)
+}
+
+func (r *MeasuredJwtRepository) RefreshJwtExists(ctx context.Context, id string) (_ bool, err error) {
+	defer r.m.observe("jwt", "RefreshJwtExists", time.Now(), &err)
+	return r.next.RefreshJwtExists(ctx, id)
+}
+
+// InvitationRepository is the invitation repository being measured.
+type InvitationRepository interface {
+	Save(ctx context.Context, invitation *domain.Invitation, tokenHash string) (*domain.Invitation, error)
+	Take(ctx context.Context, tokenHash string, at time.Time) (*domain.Invitation, error)
+}
+
+// MeasuredInvitationRepository decorates an InvitationRepository with latency
+// metrics.
+type MeasuredInvitationRepository struct {
+	next InvitationRepository
+	m    *RepositoryMetrics
+}
+
+func (m *RepositoryMetrics) InvitationRepository(next InvitationRepository) *MeasuredInvitationRepository {
+	return &MeasuredInvitationRepository{next: next, m: m}
+}
+
+func (r *MeasuredInvitationRepository) Save(ctx context.Context, invitation *domain.Invitation, tokenHash string) (_ *domain.Invitation, err error) {
+	defer r.m.observe("invitation", "Save", time.Now(), &err)
+	return r.next.Save(ctx, invitation, tokenHash)
+}
+
+func (r *MeasuredInvitationRepository) Take(ctx context.Context, tokenHash string, at time.Time) (_ *domain.Invitation, err error) {
+	defer r.m.observe("invitation", "Take", time.Now(), &err)
+	return r.next.Take(ctx, tokenHash, at)
+}
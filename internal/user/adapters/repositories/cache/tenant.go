@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"context"
+
+	"cleanarch/boiler/internal/user/domain"
+	utilcache "cleanarch/boiler/internal/utils/cache"
+	"cleanarch/boiler/internal/utils/logger"
+)
+
+// TenantRepository is the tenant repository being decorated.
+type TenantRepository interface {
+	Create(ctx context.Context, tenantId string) error
+	GetByID(ctx context.Context, tenantId string) (*domain.Tenant, error)
+	Update(ctx context.Context, tenantId string, update *domain.UpdateTenantRequest, expectedVersion int64) (*domain.Tenant, error)
+	Delete(ctx context.Context, tenantId string) error
+	Restore(ctx context.Context, tenantId string) error
+	HasAllowedOrigin(ctx context.Context, origin string) (bool, error)
+}
+
+// CachedTenantRepository caches HasAllowedOrigin, which answers every cross
+// origin request, negative answers included. An origin may belong to any
+// tenant, so every tenant write drops all cached answers. Other instances
+// pick up changes once their entries expire.
+type CachedTenantRepository struct {
+	l       logger.Interface
+	next    TenantRepository
+	origins *utilcache.Cache[string, bool]
+}
+
+func NewTenantRepository(l logger.Interface, next TenantRepository, opts utilcache.Options) *CachedTenantRepository {
+	return &CachedTenantRepository{
+		l:       l,
+		next:    next,
+		origins: utilcache.New[string, bool](opts),
+	}
+}
+
+// HasAllowedOrigin answers from the cache, asking the wrapped repository on a
+// miss. Concurrent misses for the same origin share one lookup.
+func (r *CachedTenantRepository) HasAllowedOrigin(ctx context.Context, origin string) (bool, error) {
+	return r.origins.GetOrLoad(ctx, origin, func(ctx context.Context) (bool, error) {
+		return r.next.HasAllowedOrigin(ctx, origin)
+	})
+}
+
+// Create is passed on, a new tenant has no origins yet.
+func (r *CachedTenantRepository) Create(ctx context.Context, tenantId string) error {
+	return r.next.Create(ctx, tenantId)
+}
+
+// GetByID is not cached.
+func (r *CachedTenantRepository) GetByID(ctx context.Context, tenantId string) (*domain.Tenant, error) {
+	return r.next.GetByID(ctx, tenantId)
+}
+
+// Update updates the tenant and drops the cached origins.
+func (r *CachedTenantRepository) Update(ctx context.Context, tenantId string, update *domain.UpdateTenantRequest, expectedVersion int64) (*domain.Tenant, error) {
+	defer r.Invalidate()
+	return r.next.Update(ctx, tenantId, update, expectedVersion)
+}
+
+// Delete soft deletes the tenant and drops the cached origins.
+func (r *CachedTenantRepository) Delete(ctx context.Context, tenantId string) error {
+	defer r.Invalidate()
+	return r.next.Delete(ctx, tenantId)
+}
+
+// Restore restores the tenant and drops the cached origins.
+func (r *CachedTenantRepository) Restore(ctx context.Context, tenantId string) error {
+	defer r.Invalidate()
+	return r.next.Restore(ctx, tenantId)
+}
+
+// Invalidate drops every cached origin.
+func (r *CachedTenantRepository) Invalidate() {
+	r.l.Debug("invalidating cached tenant origins")
+	r.origins.Purge()
+}
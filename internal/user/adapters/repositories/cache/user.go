@@ -1,17 +1,93 @@
 package cache
 
-type Cache struct {
-	Items map[string]map[string]interface{}
+import (
+	"context"
+
+	"cleanarch/boiler/internal/user/domain"
+	utilcache "cleanarch/boiler/internal/utils/cache"
+	"cleanarch/boiler/internal/utils/logger"
+	"cleanarch/boiler/internal/utils/query"
+)
+
+// UserRepository is the user repository being decorated. Reads go through the
+// cache, writes are passed on and invalidate the affected entry.
+type UserRepository interface {
+	GetUserByID(ctx context.Context, id string) (*domain.UserResponse, error)
+	ListUsers(ctx context.Context, tenantId string, spec query.Spec) (*query.Page[*domain.UserResponse], error)
+	UpdateUser(ctx context.Context, id string, update *domain.UpdateUserRequest, expectedVersion int64) (*domain.UserResponse, error)
+	DeleteUser(ctx context.Context, id string) (*domain.UserResponse, error)
+	RestoreUser(ctx context.Context, id string) (*domain.UserResponse, error)
+}
+
+// CachedUserRepository decorates a UserRepository with a read-through cache
+// for GetUserByID. Writers must call Invalidate after changing a user so that
+// stale entries are not served until they expire.
+type CachedUserRepository struct {
+	l     logger.Interface
+	next  UserRepository
+	cache *utilcache.Cache[string, *domain.UserResponse]
+}
+
+func NewUserRepository(l logger.Interface, next UserRepository, opts utilcache.Options) *CachedUserRepository {
+	return &CachedUserRepository{
+		l:     l,
+		next:  next,
+		cache: utilcache.New[string, *domain.UserResponse](opts),
+	}
 }
 
-func NewCache() *Cache {
-	return &Cache{
-		Items: make(map[string]map[string]interface{}),
+// GetUserByID returns the user from the cache, loading it from the wrapped
+// repository on a miss. Concurrent misses for the same id share one load.
+func (r *CachedUserRepository) GetUserByID(ctx context.Context, id string) (*domain.UserResponse, error) {
+	user, err := r.cache.GetOrLoad(ctx, id, func(ctx context.Context) (*domain.UserResponse, error) {
+		return r.next.GetUserByID(ctx, id)
+	})
+	if err != nil {
+		return nil, err
 	}
+	// hand out a copy so callers cannot mutate the cached value
+	u := *user
+	return &u, nil
 }
-func (c *Cache) Get(topic string, key string) interface{} {
-	return c.Items[topic][key]
+
+// ReloadUserByID reads the user from the wrapped repository and caches the
+// result, for callers that must not see a version written on another
+// instance less than a TTL ago.
+func (r *CachedUserRepository) ReloadUserByID(ctx context.Context, id string) (*domain.UserResponse, error) {
+	r.Invalidate(id)
+	return r.GetUserByID(ctx, id)
+}
+
+// ListUsers is not cached.
+func (r *CachedUserRepository) ListUsers(ctx context.Context, tenantId string, spec query.Spec) (*query.Page[*domain.UserResponse], error) {
+	return r.next.ListUsers(ctx, tenantId, spec)
 }
-func (c *Cache) Set(topic string, key string, item interface{}) {
-	c.Items[topic][key] = item
+
+// UpdateUser updates the user and evicts it from the cache.
+func (r *CachedUserRepository) UpdateUser(ctx context.Context, id string, update *domain.UpdateUserRequest, expectedVersion int64) (*domain.UserResponse, error) {
+	defer r.Invalidate(id)
+	return r.next.UpdateUser(ctx, id, update, expectedVersion)
+}
+
+// DeleteUser soft deletes the user and evicts it from the cache.
+func (r *CachedUserRepository) DeleteUser(ctx context.Context, id string) (*domain.UserResponse, error) {
+	defer r.Invalidate(id)
+	return r.next.DeleteUser(ctx, id)
+}
+
+// RestoreUser restores the user and evicts any cached lookup for it.
+func (r *CachedUserRepository) RestoreUser(ctx context.Context, id string) (*domain.UserResponse, error) {
+	defer r.Invalidate(id)
+	return r.next.RestoreUser(ctx, id)
+}
+
+// Invalidate drops the cached entry for the user with the given id.
+func (r *CachedUserRepository) Invalidate(id string) {
+	r.l.Debug("invalidating cached user", "id", id)
+	r.cache.Delete(id)
+}
+
+// Stats exposes the cache counters.
+func (r *CachedUserRepository) Stats() utilcache.Stats {
+	return r.cache.Stats()
 }
@@ -0,0 +1,154 @@
+package cache
+
+import (
+	"cleanarch/boiler/internal/user/domain"
+	utilcache "cleanarch/boiler/internal/utils/cache"
+	"cleanarch/boiler/internal/utils/logger"
+	"cleanarch/boiler/internal/utils/query"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeUsers is a UserRepository counting reads, where deleted users are not
+// found like in the mongo repository.
+type fakeUsers struct {
+	users   map[string]*domain.UserResponse
+	deleted map[string]bool
+	reads   int
+}
+
+func newFakeUsers() *fakeUsers {
+	return &fakeUsers{
+		users:   map[string]*domain.UserResponse{"u1": {ID: "u1", FirstName: "Ada", TenantID: "t1", Version: 1}},
+		deleted: map[string]bool{},
+	}
+}
+
+func (f *fakeUsers) GetUserByID(ctx context.Context, id string) (*domain.UserResponse, error) {
+	f.reads++
+	u, ok := f.users[id]
+	if !ok || f.deleted[id] {
+		return nil, domain.ErrUserNotFound
+	}
+	copied := *u
+	return &copied, nil
+}
+
+func (f *fakeUsers) ListUsers(ctx context.Context, tenantId string, spec query.Spec) (*query.Page[*domain.UserResponse], error) {
+	return &query.Page[*domain.UserResponse]{}, nil
+}
+
+func (f *fakeUsers) UpdateUser(ctx context.Context, id string, update *domain.UpdateUserRequest, expectedVersion int64) (*domain.UserResponse, error) {
+	u := f.users[id]
+	if update.FirstName != nil {
+		u.FirstName = *update.FirstName
+	}
+	u.Version++
+	copied := *u
+	return &copied, nil
+}
+
+func (f *fakeUsers) DeleteUser(ctx context.Context, id string) (*domain.UserResponse, error) {
+	f.deleted[id] = true
+	copied := *f.users[id]
+	return &copied, nil
+}
+
+func (f *fakeUsers) RestoreUser(ctx context.Context, id string) (*domain.UserResponse, error) {
+	delete(f.deleted, id)
+	copied := *f.users[id]
+	return &copied, nil
+}
+
+func newCachedUsers(next *fakeUsers) *CachedUserRepository {
+	return NewUserRepository(logger.NewLogger("error"), next, utilcache.Options{MaxEntries: 10, DefaultTTL: time.Minute})
+}
+
+func TestCachedUserRepository_CopyOnRead(t *testing.T) {
+	ctx := context.Background()
+	next := newFakeUsers()
+	repo := newCachedUsers(next)
+
+	u, err := repo.GetUserByID(ctx, "u1")
+	if err != nil {
+		t.Fatalf("GetUserByID() error = %v", err)
+	}
+	u.FirstName = "Mallory"
+
+	again, err := repo.GetUserByID(ctx, "u1")
+	if err != nil {
+		t.Fatalf("GetUserByID() error = %v", err)
+	}
+	if again.FirstName != "Ada" {
+		t.Fatalf("FirstName = %q, mutating a returned user must not change the cache", again.FirstName)
+	}
+	if next.reads != 1 {
+		t.Fatalf("repository read %d times, want 1", next.reads)
+	}
+}
+
+func TestCachedUserRepository_UpdateInvalidates(t *testing.T) {
+	ctx := context.Background()
+	next := newFakeUsers()
+	repo := newCachedUsers(next)
+
+	if _, err := repo.GetUserByID(ctx, "u1"); err != nil {
+		t.Fatalf("GetUserByID() error = %v", err)
+	}
+	name := "Grace"
+	if _, err := repo.UpdateUser(ctx, "u1", &domain.UpdateUserRequest{FirstName: &name}, 1); err != nil {
+		t.Fatalf("UpdateUser() error = %v", err)
+	}
+	u, err := repo.GetUserByID(ctx, "u1")
+	if err != nil {
+		t.Fatalf("GetUserByID() error = %v", err)
+	}
+	if u.FirstName != "Grace" || u.Version != 2 {
+		t.Fatalf("user = %+v, want the updated user", u)
+	}
+}
+
+func TestCachedUserRepository_DeletedUserNotServed(t *testing.T) {
+	ctx := context.Background()
+	next := newFakeUsers()
+	repo := newCachedUsers(next)
+
+	if _, err := repo.GetUserByID(ctx, "u1"); err != nil {
+		t.Fatalf("GetUserByID() error = %v", err)
+	}
+	if _, err := repo.DeleteUser(ctx, "u1"); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+	if _, err := repo.GetUserByID(ctx, "u1"); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Fatalf("GetUserByID() after delete error = %v, want ErrUserNotFound", err)
+	}
+	// not found is not cached either, so a restored user is seen at once
+	if _, err := repo.RestoreUser(ctx, "u1"); err != nil {
+		t.Fatalf("RestoreUser() error = %v", err)
+	}
+	if _, err := repo.GetUserByID(ctx, "u1"); err != nil {
+		t.Fatalf("GetUserByID() after restore error = %v", err)
+	}
+}
+
+func TestCachedUserRepository_ReloadSkipsCache(t *testing.T) {
+	ctx := context.Background()
+	next := newFakeUsers()
+	repo := newCachedUsers(next)
+
+	if _, err := repo.GetUserByID(ctx, "u1"); err != nil {
+		t.Fatalf("GetUserByID() error = %v", err)
+	}
+	// another instance updates the user behind this cache
+	next.users["u1"].Version = 5
+
+	u, err := repo.ReloadUserByID(ctx, "u1")
+	if err != nil || u.Version != 5 {
+		t.Fatalf("ReloadUserByID() = %+v, %v, want version 5", u, err)
+	}
+	if u, _ := repo.GetUserByID(ctx, "u1"); u.Version != 5 || next.reads != 2 {
+		t.Fatalf("GetUserByID() after reload = version %d after %d reads, want the reloaded user cached", u.Version, next.reads)
+	}
+}
@@ -0,0 +1,84 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Audit fields maintained on every user and tenant document.
+const (
+	createdAtField = "createdAt"
+	updatedAtField = "updatedAt"
+	deletedAtField = "deletedAt"
+)
+
+// now returns the timestamp written into audit fields. Mongo stores dates with
+// millisecond precision, truncating here keeps round trips comparable.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
+
+// withCreateAudit stamps a new document with its creation timestamps and
+// initial version.
+func withCreateAudit(doc bson.M) bson.M {
+	t := now()
+	doc[createdAtField] = t
+	doc[updatedAtField] = t
+	doc[versionField] = int64(1)
+	return doc
+}
+
+// notDeleted narrows filter to documents that have not been soft deleted.
+// A nil match in Mongo covers both a missing and a null field.
+func notDeleted(filter bson.M) bson.M {
+	filter[deletedAtField] = nil
+	return filter
+}
+
+// onlyDeleted narrows filter to soft deleted documents.
+func onlyDeleted(filter bson.M) bson.M {
+	filter[deletedAtField] = bson.M{"$ne": nil}
+	return filter
+}
+
+// softDelete marks the live document matching filter as deleted and decodes
+// it into out. It reports whether a document was matched.
+func softDelete(ctx context.Context, coll *mongo.Collection, filter bson.M, out interface{}, opts *options.FindOneAndUpdateOptions) (bool, error) {
+	t := now()
+	return updateOne(ctx, coll, notDeleted(filter), bson.M{
+		"$set": bson.M{deletedAtField: t, updatedAtField: t},
+		"$inc": bson.M{versionField: 1},
+	}, out, opts)
+}
+
+// restore clears the deletion mark of the soft deleted document matching
+// filter and decodes it into out. It reports whether a document was matched.
+func restore(ctx context.Context, coll *mongo.Collection, filter bson.M, out interface{}, opts *options.FindOneAndUpdateOptions) (bool, error) {
+	return updateOne(ctx, coll, onlyDeleted(filter), bson.M{
+		"$set":   bson.M{updatedAtField: now()},
+		"$unset": bson.M{deletedAtField: ""},
+		"$inc":   bson.M{versionField: 1},
+	}, out, opts)
+}
+
+// updateOne applies update to the document matching filter and decodes the
+// result into out.
+func updateOne(ctx context.Context, coll *mongo.Collection, filter, update bson.M, out interface{}, opts *options.FindOneAndUpdateOptions) (bool, error) {
+	if opts == nil {
+		opts = options.FindOneAndUpdate()
+	}
+	opts.SetReturnDocument(options.After)
+	err := coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(out)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,92 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cleanarch/boiler/internal/user/domain"
+	"cleanarch/boiler/internal/utils/tracing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type Invitation struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	Email     string             `bson:"email"`
+	TenantID  string             `bson:"tenantId"`
+	InvitedBy string             `bson:"invitedBy"`
+	TokenHash string             `bson:"tokenHash"`
+	CreatedAt time.Time          `bson:"createdAt"`
+	ExpiresAt time.Time          `bson:"expiresAt"`
+}
+
+type InvitationRepository struct {
+	db *mongo.Database
+}
+
+func NewInvitationRepository(db *mongo.Database) *InvitationRepository {
+	return &InvitationRepository{
+		db: db,
+	}
+}
+
+// Save stores an invitation under the hash of its token. Inviting the same
+// email to the tenant again replaces the pending invitation, so only the
+// latest token can be accepted.
+func (r *InvitationRepository) Save(ctx context.Context, invitation *domain.Invitation, tokenHash string) (_ *domain.Invitation, err error) {
+	ctx, span := tracing.Start(ctx, "InvitationRepository.Save")
+	defer tracing.End(span, &err)
+
+	saved := new(Invitation)
+	err = r.db.Collection("invitations").FindOneAndUpdate(ctx, bson.M{
+		"tenantId": invitation.TenantID,
+		"email":    invitation.Email,
+	}, bson.M{
+		"$set": bson.M{
+			"invitedBy": invitation.InvitedBy,
+			"tokenHash": tokenHash,
+			"createdAt": invitation.CreatedAt,
+			"expiresAt": invitation.ExpiresAt,
+		},
+	}, options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(saved)
+	if err != nil {
+		return nil, err
+	}
+	return toInvitationModel(saved), nil
+}
+
+// Take removes and returns the unexpired invitation with the given token
+// hash, so a token is accepted at most once. Expired invitations are left to
+// the TTL index.
+func (r *InvitationRepository) Take(ctx context.Context, tokenHash string, at time.Time) (_ *domain.Invitation, err error) {
+	ctx, span := tracing.Start(ctx, "InvitationRepository.Take")
+	defer tracing.End(span, &err)
+
+	invitation := new(Invitation)
+	err = r.db.Collection("invitations").FindOneAndDelete(ctx, bson.M{
+		"tokenHash": tokenHash,
+		"expiresAt": bson.M{"$gt": at},
+	}).Decode(invitation)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, domain.ErrInvitationNotFound
+		}
+		return nil, err
+	}
+	return toInvitationModel(invitation), nil
+}
+
+func toInvitationModel(i *Invitation) *domain.Invitation {
+	return &domain.Invitation{
+		ID:        i.ID.Hex(),
+		Email:     i.Email,
+		TenantID:  i.TenantID,
+		InvitedBy: i.InvitedBy,
+		CreatedAt: i.CreatedAt,
+		ExpiresAt: i.ExpiresAt,
+	}
+}
@@ -0,0 +1,70 @@
+package mongo
+
+import (
+	"cleanarch/boiler/internal/user/domain"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestInvitationRepository(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	id := primitive.NewObjectID()
+	at := now()
+
+	mt.Run("save replaces the pending invitation of the email", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{
+			{Key: "_id", Value: id},
+			{Key: "email", Value: "b@example.com"},
+			{Key: "tenantId", Value: "t1"},
+		}}))
+		repo := NewInvitationRepository(mt.DB)
+
+		saved, err := repo.Save(context.Background(), &domain.Invitation{Email: "b@example.com", TenantID: "t1", InvitedBy: "u1", CreatedAt: at, ExpiresAt: at.Add(time.Hour)}, "hash")
+		if err != nil || saved.ID != id.Hex() {
+			mt.Fatalf("Save() = %+v, %v, want the stored invitation", saved, err)
+		}
+		cmd := findAndModify(mt)
+		if q := cmd.Lookup("query"); q.Document().Lookup("tenantId").StringValue() != "t1" || q.Document().Lookup("email").StringValue() != "b@example.com" {
+			mt.Errorf("filter = %v, want the email in the tenant", q)
+		}
+		if hash := cmd.Lookup("update", "$set", "tokenHash").StringValue(); hash != "hash" || !cmd.Lookup("upsert").Boolean() {
+			mt.Errorf("command = %v, want the token hash upserted", cmd)
+		}
+	})
+
+	mt.Run("take removes an unexpired invitation", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{
+			{Key: "_id", Value: id},
+			{Key: "email", Value: "b@example.com"},
+			{Key: "tenantId", Value: "t1"},
+		}}))
+		repo := NewInvitationRepository(mt.DB)
+
+		invitation, err := repo.Take(context.Background(), "hash", at)
+		if err != nil || invitation.TenantID != "t1" {
+			mt.Fatalf("Take() = %+v, %v, want the invitation", invitation, err)
+		}
+		cmd := findAndModify(mt)
+		if !cmd.Lookup("remove").Boolean() {
+			mt.Errorf("command = %v, want the invitation removed", cmd)
+		}
+		if gt := cmd.Lookup("query", "expiresAt", "$gt").Time(); !gt.Equal(at) {
+			mt.Errorf("filter = %v, want unexpired invitations only", cmd.Lookup("query"))
+		}
+	})
+
+	mt.Run("take of an unknown, used or expired token", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil}))
+		repo := NewInvitationRepository(mt.DB)
+
+		if _, err := repo.Take(context.Background(), "hash", at); !errors.Is(err, domain.ErrInvitationNotFound) {
+			mt.Fatalf("Take() error = %v, want ErrInvitationNotFound", err)
+		}
+	})
+}
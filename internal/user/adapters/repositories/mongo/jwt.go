@@ -3,10 +3,13 @@ package mongo
 import (
 	"cleanarch/boiler/internal/user/domain"
 	"cleanarch/boiler/internal/utils/logger"
+	"cleanarch/boiler/internal/utils/tracing"
 	"context"
+	"errors"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type JwtRepository struct {
@@ -21,19 +24,36 @@ func NewJwtRepository(l logger.Interface, db *mongo.Database) *JwtRepository {
 	}
 }
 
-func (r JwtRepository) CreateRefreshJwt(ctx context.Context, jwt *domain.Jwt, currentRefreshId string) error {
-	//TODO : put this in a transaction
-	// TODO : Handle this error well with condition check for ErrNoDocuments
-	r.l.Info("creating refresh token", currentRefreshId)
-	if currentRefreshId != "" {
+// CreateRefreshJwt stores a new refresh token, revoking currentRefreshId when
+// rotating. Callers run it in a unit of work so that the revocation and the
+// insert commit together. A current token that is already gone was rotated
+// or revoked concurrently and fails with domain.ErrInvalidToken.
+func (r JwtRepository) CreateRefreshJwt(ctx context.Context, jwt *domain.Jwt, currentRefreshId string) (err error) {
+	ctx, span := tracing.Start(ctx, "JwtRepository.CreateRefreshJwt")
+	defer tracing.End(span, &err)
 
-		result := r.db.Collection("jwt").FindOneAndDelete(ctx, bson.M{
+	if currentRefreshId != "" {
+		err := r.db.Collection("jwt").FindOneAndDelete(ctx, bson.M{
 			"id": currentRefreshId,
-		})
-		if result.Err() != nil {
-			r.l.Error("unable to delete refresh token", "error", result.Err())
+		}).Err()
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return domain.ErrInvalidToken
 		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err = r.db.Collection("jwt").InsertOne(ctx, jwt)
+	return err
+}
+
+func (r JwtRepository) RefreshJwtExists(ctx context.Context, id string) (_ bool, err error) {
+	ctx, span := tracing.Start(ctx, "JwtRepository.RefreshJwtExists")
+	defer tracing.End(span, &err)
+
+	n, err := r.db.Collection("jwt").CountDocuments(ctx, bson.M{"id": id}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
 	}
-	_, error := r.db.Collection("jwt").InsertOne(ctx, jwt)
-	return error
+	return n > 0, nil
 }
@@ -2,11 +2,29 @@ package mongo
 
 import (
 	"context"
+	"errors"
+	"time"
+
+	"cleanarch/boiler/internal/user/domain"
+	"cleanarch/boiler/internal/utils/tracing"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+type Tenant struct {
+	ID       string            `bson:"_id"`
+	Name     string            `bson:"name,omitempty"`
+	Settings map[string]string `bson:"settings,omitempty"`
+	// AllowedOrigins are stored normalized, see origin.Normalize
+	AllowedOrigins []string   `bson:"allowedOrigins,omitempty"`
+	CreatedAt      time.Time  `bson:"createdAt"`
+	UpdatedAt      time.Time  `bson:"updatedAt"`
+	DeletedAt      *time.Time `bson:"deletedAt,omitempty"`
+	Version        int64      `bson:"version"`
+}
+
 type TenantRepository struct {
 	db *mongo.Database
 }
@@ -17,12 +35,118 @@ func NewTeanantRepository(db *mongo.Database) *TenantRepository {
 	}
 }
 
-func (r *TenantRepository) Create(ctx context.Context, tenantId string) error {
-	_, error := r.db.Collection("tenants").InsertOne(ctx, bson.M{
+func (r *TenantRepository) Create(ctx context.Context, tenantId string) (err error) {
+	ctx, span := tracing.Start(ctx, "TenantRepository.Create")
+	defer tracing.End(span, &err)
+
+	_, error := r.db.Collection("tenants").InsertOne(ctx, withCreateAudit(bson.M{
 		"_id": tenantId,
-	})
+	}))
 	if error != nil {
 		return error
 	}
 	return nil
 }
+
+// GetByID returns the tenant with the given id unless it has been soft deleted.
+func (r *TenantRepository) GetByID(ctx context.Context, tenantId string) (_ *domain.Tenant, err error) {
+	ctx, span := tracing.Start(ctx, "TenantRepository.GetByID")
+	defer tracing.End(span, &err)
+
+	tenant := new(Tenant)
+	err = r.db.Collection("tenants").FindOne(ctx, notDeleted(bson.M{
+		"_id": tenantId,
+	})).Decode(tenant)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, domain.ErrTenantNotFound
+		}
+		return nil, err
+	}
+	return toTenantModel(tenant), nil
+}
+
+// Update applies a partial update to the tenant with the given id using a
+// compare-and-swap on its version. A stale expectedVersion yields
+// domain.ErrConflict.
+func (r *TenantRepository) Update(ctx context.Context, tenantId string, update *domain.UpdateTenantRequest, expectedVersion int64) (_ *domain.Tenant, err error) {
+	ctx, span := tracing.Start(ctx, "TenantRepository.Update")
+	defer tracing.End(span, &err)
+
+	set := bson.M{}
+	if update.Name != nil {
+		set["name"] = *update.Name
+	}
+	if update.Settings != nil {
+		set["settings"] = update.Settings
+	}
+	if update.AllowedOrigins != nil {
+		set["allowedOrigins"] = update.AllowedOrigins
+	}
+
+	tenant := new(Tenant)
+	err = compareAndSwap(ctx, r.db.Collection("tenants"), bson.M{"_id": tenantId}, expectedVersion, set, tenant, nil, domain.ErrTenantNotFound)
+	if err != nil {
+		return nil, err
+	}
+	return toTenantModel(tenant), nil
+}
+
+// HasAllowedOrigin reports whether a tenant that is not deleted registered
+// the normalized origin.
+func (r *TenantRepository) HasAllowedOrigin(ctx context.Context, origin string) (_ bool, err error) {
+	ctx, span := tracing.Start(ctx, "TenantRepository.HasAllowedOrigin")
+	defer tracing.End(span, &err)
+
+	n, err := r.db.Collection("tenants").CountDocuments(ctx, notDeleted(bson.M{
+		"allowedOrigins": origin,
+	}), options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// Delete soft deletes the tenant with the given id.
+func (r *TenantRepository) Delete(ctx context.Context, tenantId string) (err error) {
+	ctx, span := tracing.Start(ctx, "TenantRepository.Delete")
+	defer tracing.End(span, &err)
+
+	found, err := softDelete(ctx, r.db.Collection("tenants"), bson.M{"_id": tenantId}, new(Tenant), nil)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return domain.ErrTenantNotFound
+	}
+	return nil
+}
+
+// Restore brings back a soft deleted tenant.
+func (r *TenantRepository) Restore(ctx context.Context, tenantId string) (err error) {
+	ctx, span := tracing.Start(ctx, "TenantRepository.Restore")
+	defer tracing.End(span, &err)
+
+	found, err := restore(ctx, r.db.Collection("tenants"), bson.M{"_id": tenantId}, new(Tenant), nil)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return domain.ErrTenantNotFound
+	}
+	return nil
+}
+
+func toTenantModel(t *Tenant) *domain.Tenant {
+	return &domain.Tenant{
+		ID:       t.ID,
+		Name:     t.Name,
+		Settings: t.Settings,
+		// omitted when empty, so list it as such rather than null
+		AllowedOrigins: append([]string{}, t.AllowedOrigins...),
+		CreatedAt:      t.CreatedAt,
+		UpdatedAt:      t.UpdatedAt,
+		DeletedAt:      t.DeletedAt,
+		Version:        t.Version,
+	}
+}
@@ -3,9 +3,13 @@ package mongo
 import (
 	"context"
 	"errors"
+	"time"
 
 	"cleanarch/boiler/internal/user/domain"
 	"cleanarch/boiler/internal/utils/logger"
+	"cleanarch/boiler/internal/utils/query"
+	"cleanarch/boiler/internal/utils/query/mongoquery"
+	"cleanarch/boiler/internal/utils/tracing"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -15,9 +19,28 @@ import (
 )
 
 type User struct {
-	ID       primitive.ObjectID `bson:"_id,omitempty"`
-	Email    string             `bson:"email"`
-	Password string             `bson:"password"`
+	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	Email      string             `bson:"email"`
+	Password   string             `bson:"password"`
+	FirstName  string             `bson:"firstName,omitempty"`
+	MiddleName string             `bson:"middleName,omitempty"`
+	LastName   string             `bson:"lastName,omitempty"`
+	TenantID   string             `bson:"tenantId,omitempty"`
+	CreatedAt  time.Time          `bson:"createdAt"`
+	UpdatedAt  time.Time          `bson:"updatedAt"`
+	DeletedAt  *time.Time         `bson:"deletedAt,omitempty"`
+	Version    int64              `bson:"version"`
+}
+
+// userSchema lists the fields users can be filtered and sorted on.
+var userSchema = mongoquery.Schema{
+	Fields: map[string]mongoquery.Field{
+		"id":        {Name: "_id", Convert: mongoquery.ObjectID},
+		"email":     {Name: "email"},
+		"createdAt": {Name: createdAtField},
+		"updatedAt": {Name: updatedAtField},
+	},
+	TieBreaker: "id",
 }
 
 type UserRepository struct {
@@ -36,54 +59,49 @@ func NewUserRepository(l logger.Interface, db *mongo.Database) *UserRepository {
 // using the bcrypt algorithm, then inserts a new user document into the "users" collection
 // with the hashed password. If the password hashing or database insert operation fails,
 // an error is returned.
-func (r UserRepository) CreateUser(ctx context.Context, user *domain.AddUserRequest) error {
+func (r UserRepository) CreateUser(ctx context.Context, user *domain.AddUserRequest) (err error) {
+	ctx, span := tracing.Start(ctx, "UserRepository.CreateUser")
+	defer tracing.End(span, &err)
+
 	hash, err := hashPassword(user.Password)
 	if err != nil {
 		return err
 	}
 	user.Password = string(hash)
-	_, error := r.db.Collection("users").InsertOne(ctx, user)
+	_, error := r.db.Collection("users").InsertOne(ctx, withCreateAudit(bson.M{
+		"email":    user.Email,
+		"password": user.Password,
+	}))
 	if error != nil {
-		return err
+		return error
 	}
 	return nil
 }
 
-// GetUser retrieves a user from the database by their username, and verifies that the provided
-// password matches the stored hashed password for that user. If the user is found and the
-// password is valid, a User model is returned. If the user is not found or the password is
-// invalid, an error is returned.
-func (r UserRepository) GetUser(ctx context.Context, username, password string) (*domain.User, error) {
-	user := new(User)
-	err := r.db.Collection("users").FindOne(ctx, bson.M{
-		"username": username,
-	}).Decode(user)
-
-	if err != nil {
-		return nil, err
-	}
-	isAuthorized := doPasswordsMatch(user.Password, password)
-	if !isAuthorized {
-		return nil, domain.ErrUserNotFound
-	}
-	return toModel(user), nil
-}
-
 // GetUserByID retrieves a user from the database by their unique identifier (userId).
 // It first converts the userId string to a MongoDB ObjectID, then uses that to find the
 // corresponding user document in the "users" collection. The password field is excluded
 // from the returned user data.
 // If the user is found, a UserResponse is returned containing the user's ID and email.
-// If the user is not found, an error is returned.
-func (r UserRepository) GetUserByID(ctx context.Context, userId string) (*domain.UserResponse, error) {
-	user := new(User)
-	objID, _ := primitive.ObjectIDFromHex(userId)
+// If the id is malformed or the user is not found or has been soft deleted,
+// domain.ErrUserNotFound is returned.
+func (r UserRepository) GetUserByID(ctx context.Context, userId string) (_ *domain.UserResponse, err error) {
+	ctx, span := tracing.Start(ctx, "UserRepository.GetUserByID")
+	defer tracing.End(span, &err)
 
-	err := r.db.Collection("users").FindOne(ctx, bson.M{
-		"_id": objID,
-	}, options.FindOne().SetProjection(bson.M{"password": 0})).Decode(user)
+	objID, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil, domain.ErrUserNotFound
+	}
 
+	user := new(User)
+	err = r.db.Collection("users").FindOne(ctx, notDeleted(bson.M{
+		"_id": objID,
+	}), options.FindOne().SetProjection(bson.M{"password": 0})).Decode(user)
 	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, domain.ErrUserNotFound
+		}
 		return nil, err
 	}
 	return toResponse(user), nil
@@ -111,39 +129,46 @@ func doPasswordsMatch(hashedPassword, currPassword string) bool {
 
 // SignUp creates a new user in the database with the provided user information.
 // It first hashes the user's password using the hashPassword function, then
-// inserts the new user into the "users" collection in the database.
+// inserts the new user into the "users" collection in the database and returns
+// the id of the new user. The request is not modified, so the call can be
+// retried as part of a transaction.
 // If any errors occur during the process, they are returned.
-func (r UserRepository) SignUp(ctx context.Context, user *domain.AddUserRequest, tenantId string) error {
+func (r UserRepository) SignUp(ctx context.Context, user *domain.AddUserRequest, tenantId string) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "UserRepository.SignUp")
+	defer tracing.End(span, &err)
+
 	hash, err := hashPassword(user.Password)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	user.Password = string(hash)
-	_, error := r.db.Collection("users").InsertOne(ctx, bson.M{
+	result, error := r.db.Collection("users").InsertOne(ctx, withCreateAudit(bson.M{
 		"email":    user.Email,
-		"password": user.Password,
+		"password": hash,
 		"tenantId": tenantId,
-	})
+	}))
 
 	if error != nil {
 		if IsDup(error) {
-			return domain.ErrUserAlreadyExists
+			return "", domain.ErrUserAlreadyExists
 		}
-		return error
+		return "", error
 	}
-	return nil
+	return result.InsertedID.(primitive.ObjectID).Hex(), nil
 }
 
 // GetAuthenticatedUser retrieves a user from the database based on the provided email and password.
 // If the user is found and the password matches, it returns a UserResponse containing the user's ID and email.
 // If the user is not found or the password does not match, it returns an error.
-func (r UserRepository) GetAuthenticatedUser(ctx context.Context, user *domain.AddUserRequest) (*domain.UserResponse, error) {
+func (r UserRepository) GetAuthenticatedUser(ctx context.Context, user *domain.AddUserRequest) (_ *domain.UserResponse, err error) {
+	ctx, span := tracing.Start(ctx, "UserRepository.GetAuthenticatedUser")
+	defer tracing.End(span, &err)
+
 	dbUser := new(User)
 
-	err := r.db.Collection("users").FindOne(ctx, bson.M{
+	err = r.db.Collection("users").FindOne(ctx, notDeleted(bson.M{
 		"email": user.Email,
-	}).Decode(dbUser)
+	})).Decode(dbUser)
 
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
@@ -152,37 +177,134 @@ func (r UserRepository) GetAuthenticatedUser(ctx context.Context, user *domain.A
 		return nil, err
 	}
 	if !doPasswordsMatch(dbUser.Password, user.Password) {
-		return nil, domain.ErrUserNotFound
+		return nil, domain.ErrUserInvalidCredentials
 	}
 	return toResponse(dbUser), nil
 }
-func toModel(u *User) *domain.User {
-	return &domain.User{
-		ID:       u.ID.Hex(),
-		Email:    u.Email,
-		Password: u.Password,
+
+// ListUsers returns one page of the live users of a tenant matching spec.
+func (r UserRepository) ListUsers(ctx context.Context, tenantId string, spec query.Spec) (_ *query.Page[*domain.UserResponse], err error) {
+	ctx, span := tracing.Start(ctx, "UserRepository.ListUsers")
+	defer tracing.End(span, &err)
+
+	page, err := mongoquery.Find(ctx, r.db.Collection("users"), notDeleted(bson.M{"tenantId": tenantId}), spec, userSchema, func(u *User) map[string]interface{} {
+		return map[string]interface{}{
+			"id":        u.ID.Hex(),
+			"email":     u.Email,
+			"createdAt": u.CreatedAt,
+			"updatedAt": u.UpdatedAt,
+		}
+	}, options.Find().SetProjection(bson.M{"password": 0}))
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]*domain.UserResponse, len(page.Items))
+	for i, u := range page.Items {
+		users[i] = toResponse(u)
+	}
+	return &query.Page[*domain.UserResponse]{Items: users, NextCursor: page.NextCursor}, nil
+}
+
+// UpdateUser applies a partial profile update to the user with the given id.
+// The write only succeeds if the stored document is still at expectedVersion;
+// otherwise domain.ErrConflict is returned so the caller can re-read and retry.
+func (r UserRepository) UpdateUser(ctx context.Context, userId string, update *domain.UpdateUserRequest, expectedVersion int64) (_ *domain.UserResponse, err error) {
+	ctx, span := tracing.Start(ctx, "UserRepository.UpdateUser")
+	defer tracing.End(span, &err)
+
+	objID, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil, domain.ErrUserNotFound
+	}
+
+	set := bson.M{}
+	if update.FirstName != nil {
+		set["firstName"] = *update.FirstName
+	}
+	if update.MiddleName != nil {
+		set["middleName"] = *update.MiddleName
+	}
+	if update.LastName != nil {
+		set["lastName"] = *update.LastName
+	}
+
+	user := new(User)
+	opts := options.FindOneAndUpdate().SetProjection(bson.M{"password": 0})
+	err = compareAndSwap(ctx, r.db.Collection("users"), bson.M{"_id": objID}, expectedVersion, set, user, opts, domain.ErrUserNotFound)
+	if err != nil {
+		return nil, err
 	}
+	return toResponse(user), nil
+}
+
+// DeleteUser soft deletes the user with the given id and returns it. The
+// document is kept with a deletedAt timestamp and is hidden from every other
+// query until it is restored.
+func (r UserRepository) DeleteUser(ctx context.Context, userId string) (_ *domain.UserResponse, err error) {
+	ctx, span := tracing.Start(ctx, "UserRepository.DeleteUser")
+	defer tracing.End(span, &err)
+
+	objID, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil, domain.ErrUserNotFound
+	}
+	user := new(User)
+	opts := options.FindOneAndUpdate().SetProjection(bson.M{"password": 0})
+	found, err := softDelete(ctx, r.db.Collection("users"), bson.M{"_id": objID}, user, opts)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, domain.ErrUserNotFound
+	}
+	return toResponse(user), nil
+}
+
+// RestoreUser brings back a soft deleted user and returns it. It fails with
+// domain.ErrUserAlreadyExists when the email was signed up again meanwhile.
+func (r UserRepository) RestoreUser(ctx context.Context, userId string) (_ *domain.UserResponse, err error) {
+	ctx, span := tracing.Start(ctx, "UserRepository.RestoreUser")
+	defer tracing.End(span, &err)
+
+	objID, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil, domain.ErrUserNotFound
+	}
+	user := new(User)
+	opts := options.FindOneAndUpdate().SetProjection(bson.M{"password": 0})
+	found, err := restore(ctx, r.db.Collection("users"), bson.M{"_id": objID}, user, opts)
+	if err != nil {
+		if IsDup(err) {
+			return nil, domain.ErrUserAlreadyExists
+		}
+		return nil, err
+	}
+	if !found {
+		return nil, domain.ErrUserNotFound
+	}
+	return toResponse(user), nil
 }
 
 // / toResponse converts a User model to a UserResponse model.
 // / It extracts the ID and Email fields from the User and returns a new UserResponse.
 func toResponse(u *User) *domain.UserResponse {
 	return &domain.UserResponse{
-		ID:    u.ID.Hex(),
-		Email: u.Email,
+		ID:         u.ID.Hex(),
+		Email:      u.Email,
+		FirstName:  u.FirstName,
+		MiddleName: u.MiddleName,
+		LastName:   u.LastName,
+		TenantID:   u.TenantID,
+		CreatedAt:  u.CreatedAt,
+		UpdatedAt:  u.UpdatedAt,
+		Version:    u.Version,
 	}
 }
 
-// / IsDup checks if the provided error is a MongoDB duplicate key error.
+// / IsDup checks if the provided error is a MongoDB duplicate key error, as
+// / returned by inserts, updates and findAndModify.
 // / It returns true if the error is a duplicate key error, false otherwise.
 func IsDup(err error) bool {
-	var e mongo.WriteException
-	if errors.As(err, &e) {
-		for _, we := range e.WriteErrors {
-			if we.Code == 11000 {
-				return true
-			}
-		}
-	}
-	return false
+	return mongo.IsDuplicateKeyError(err)
 }
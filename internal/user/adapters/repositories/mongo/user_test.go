@@ -0,0 +1,141 @@
+package mongo
+
+import (
+	"cleanarch/boiler/internal/user/domain"
+	"cleanarch/boiler/internal/utils/logger"
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestUserRepository_GetUserByIDNotFound(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("soft deleted user", func(mt *mtest.T) {
+		// the notDeleted filter leaves no document to return
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "db.users", mtest.FirstBatch))
+		repo := NewUserRepository(logger.NewLogger("error"), mt.DB)
+
+		_, err := repo.GetUserByID(context.Background(), primitive.NewObjectID().Hex())
+		if !errors.Is(err, domain.ErrUserNotFound) {
+			mt.Fatalf("GetUserByID() error = %v, want ErrUserNotFound", err)
+		}
+	})
+
+	mt.Run("malformed id", func(mt *mtest.T) {
+		repo := NewUserRepository(logger.NewLogger("error"), mt.DB)
+
+		_, err := repo.GetUserByID(context.Background(), "not-an-object-id")
+		if !errors.Is(err, domain.ErrUserNotFound) {
+			mt.Fatalf("GetUserByID() error = %v, want ErrUserNotFound", err)
+		}
+	})
+}
+
+func TestUserRepository_SoftDelete(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	id := primitive.NewObjectID()
+	// the document findAndModify hands back
+	found := func() bson.D {
+		return mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{
+			{Key: "_id", Value: id},
+			{Key: "email", Value: "a@example.com"},
+			{Key: "tenantId", Value: "t1"},
+		}})
+	}
+	notFound := mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil})
+
+	mt.Run("delete marks the live user", func(mt *mtest.T) {
+		mt.AddMockResponses(found())
+		repo := NewUserRepository(logger.NewLogger("error"), mt.DB)
+
+		user, err := repo.DeleteUser(context.Background(), id.Hex())
+		if err != nil || user.ID != id.Hex() || user.TenantID != "t1" {
+			mt.Fatalf("DeleteUser() = %+v, %v, want the deleted user", user, err)
+		}
+		cmd := findAndModify(mt)
+		if q := cmd.Lookup("query").Document(); q.Lookup("_id").ObjectID() != id || q.Lookup(deletedAtField).Type != bson.TypeNull {
+			mt.Errorf("filter = %v, want the live user", q)
+		}
+		if _, err := cmd.Lookup("update", "$set").Document().LookupErr(deletedAtField); err != nil {
+			mt.Errorf("update = %v, want deletedAt set", cmd.Lookup("update"))
+		}
+		if inc := cmd.Lookup("update", "$inc", versionField).AsInt64(); inc != 1 {
+			mt.Errorf("version incremented by %d, want 1", inc)
+		}
+		if password := cmd.Lookup("fields", "password"); password.AsInt64() != 0 {
+			mt.Errorf("projection = %v, want the password left out", cmd.Lookup("fields"))
+		}
+	})
+
+	mt.Run("delete of a missing or deleted user", func(mt *mtest.T) {
+		mt.AddMockResponses(notFound)
+		repo := NewUserRepository(logger.NewLogger("error"), mt.DB)
+
+		if _, err := repo.DeleteUser(context.Background(), id.Hex()); !errors.Is(err, domain.ErrUserNotFound) {
+			mt.Fatalf("DeleteUser() error = %v, want ErrUserNotFound", err)
+		}
+	})
+
+	mt.Run("restore clears the mark of a deleted user", func(mt *mtest.T) {
+		mt.AddMockResponses(found())
+		repo := NewUserRepository(logger.NewLogger("error"), mt.DB)
+
+		if user, err := repo.RestoreUser(context.Background(), id.Hex()); err != nil || user.TenantID != "t1" {
+			mt.Fatalf("RestoreUser() = %+v, %v, want the restored user", user, err)
+		}
+		cmd := findAndModify(mt)
+		if ne := cmd.Lookup("query", deletedAtField, "$ne"); ne.Type != bson.TypeNull {
+			mt.Errorf("filter = %v, want only deleted users", cmd.Lookup("query"))
+		}
+		if _, err := cmd.Lookup("update", "$unset").Document().LookupErr(deletedAtField); err != nil {
+			mt.Errorf("update = %v, want deletedAt unset", cmd.Lookup("update"))
+		}
+	})
+
+	mt.Run("restore of a live user", func(mt *mtest.T) {
+		mt.AddMockResponses(notFound)
+		repo := NewUserRepository(logger.NewLogger("error"), mt.DB)
+
+		if _, err := repo.RestoreUser(context.Background(), id.Hex()); !errors.Is(err, domain.ErrUserNotFound) {
+			mt.Fatalf("RestoreUser() error = %v, want ErrUserNotFound", err)
+		}
+	})
+
+	mt.Run("restore after the email signed up again", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 11000, Name: "DuplicateKey", Message: "duplicate key"}))
+		repo := NewUserRepository(logger.NewLogger("error"), mt.DB)
+
+		if _, err := repo.RestoreUser(context.Background(), id.Hex()); !errors.Is(err, domain.ErrUserAlreadyExists) {
+			mt.Fatalf("RestoreUser() error = %v, want ErrUserAlreadyExists", err)
+		}
+	})
+
+	mt.Run("login skips deleted users", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "db.users", mtest.FirstBatch))
+		repo := NewUserRepository(logger.NewLogger("error"), mt.DB)
+
+		_, err := repo.GetAuthenticatedUser(context.Background(), &domain.AddUserRequest{Email: "a@example.com", Password: "secret-password"})
+		if !errors.Is(err, domain.ErrUserNotFound) {
+			mt.Fatalf("GetAuthenticatedUser() error = %v, want ErrUserNotFound", err)
+		}
+		filter := mt.GetStartedEvent().Command.Lookup("filter").Document()
+		if filter.Lookup(deletedAtField).Type != bson.TypeNull {
+			mt.Errorf("filter = %v, want live users only", filter)
+		}
+	})
+}
+
+// findAndModify returns the last command, which must be a findAndModify.
+func findAndModify(mt *mtest.T) bson.Raw {
+	mt.Helper()
+	evt := mt.GetStartedEvent()
+	if evt == nil || evt.CommandName != "findAndModify" {
+		mt.Fatalf("started event = %v, want findAndModify", evt)
+	}
+	return evt.Command
+}
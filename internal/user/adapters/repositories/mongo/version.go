@@ -0,0 +1,70 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+
+	"cleanarch/boiler/internal/user/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// versionField holds the optimistic concurrency version of user and tenant
+// documents. It starts at 1 and is incremented by every write.
+const versionField = "version"
+
+// matchVersion narrows filter to the expected document version. Documents
+// written before versioning was introduced have no version and are treated
+// as version 0.
+func matchVersion(filter bson.M, expected int64) bson.M {
+	switch expected {
+	case domain.AnyVersion:
+	case 0:
+		filter[versionField] = bson.M{"$in": bson.A{int64(0), nil}}
+	default:
+		filter[versionField] = expected
+	}
+	return filter
+}
+
+// compareAndSwap applies set to the live document matching filter, but only if
+// it is still at the expected version. The version is bumped and the updated
+// document decoded into out. When nothing matches it tells a missing document
+// (notFound) apart from a stale version (domain.ErrConflict).
+func compareAndSwap(ctx context.Context, coll *mongo.Collection, filter bson.M, expected int64, set bson.M, out interface{}, opts *options.FindOneAndUpdateOptions, notFound error) error {
+	set[updatedAtField] = now()
+	if opts == nil {
+		opts = options.FindOneAndUpdate()
+	}
+	opts.SetReturnDocument(options.After)
+
+	err := coll.FindOneAndUpdate(ctx, matchVersion(notDeleted(clone(filter)), expected), bson.M{
+		"$set": set,
+		"$inc": bson.M{versionField: 1},
+	}, opts).Decode(out)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return err
+	}
+
+	n, err := coll.CountDocuments(ctx, notDeleted(clone(filter)), options.Count().SetLimit(1))
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return notFound
+	}
+	return domain.ErrConflict
+}
+
+func clone(filter bson.M) bson.M {
+	c := make(bson.M, len(filter))
+	for k, v := range filter {
+		c[k] = v
+	}
+	return c
+}
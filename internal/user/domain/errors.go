@@ -1,7 +1,24 @@
 package domain
 
-import "errors"
+import "cleanarch/boiler/internal/utils/apperr"
 
-var ErrUserNotFound = errors.New("user not found")
-var ErrUserInvalidCredentials = errors.New("user invalid credentials")
-var ErrUserAlreadyExists = errors.New("user already exists")
+var ErrUserNotFound = apperr.New(apperr.NotFound, "user_not_found", "user not found")
+var ErrUserInvalidCredentials = apperr.New(apperr.Unauthorized, "invalid_credentials", "invalid email or password")
+var ErrUserAlreadyExists = apperr.New(apperr.Conflict, "user_already_exists", "a user with this email already exists")
+var ErrTenantNotFound = apperr.New(apperr.NotFound, "tenant_not_found", "tenant not found")
+
+// ErrInvalidToken is returned for missing, malformed, expired or otherwise
+// unusable access and refresh tokens. The wrapped cause says which.
+var ErrInvalidToken = apperr.New(apperr.Unauthorized, "invalid_token", "token is missing, invalid or expired")
+
+// ErrConflict is returned when an update was based on a stale version of the
+// resource because someone else changed it in the meantime.
+var ErrConflict = apperr.New(apperr.PreconditionFailed, "version_conflict", "resource was modified concurrently")
+
+// ErrInvalidClient is returned when a client calling a client authenticated
+// endpoint, like token introspection, presents unknown credentials.
+var ErrInvalidClient = apperr.New(apperr.Unauthorized, "invalid_client", "client authentication failed")
+
+// ErrInvitationNotFound is returned for unknown, already accepted and expired
+// invitation tokens alike.
+var ErrInvitationNotFound = apperr.New(apperr.NotFound, "invitation_not_found", "invitation is invalid or expired")
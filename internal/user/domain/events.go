@@ -0,0 +1,27 @@
+package domain
+
+// Names of the events emitted by the user module.
+const (
+	EventUserSignedUp        = "user.signed_up"
+	EventUserLoggedIn        = "user.logged_in"
+	EventRefreshTokenRotated = "auth.refresh_token_rotated"
+	EventTenantCreated       = "tenant.created"
+	// EventUserStatusChanged carries the new status of the user, one of the
+	// UserStatus values below.
+	EventUserStatusChanged = "user.status_changed"
+	EventUserInvited       = "user.invited"
+)
+
+// Statuses reported by EventUserStatusChanged.
+const (
+	UserStatusActive  = "active"
+	UserStatusDeleted = "deleted"
+)
+
+// WebhookEvents are the events tenants can subscribe webhooks to.
+var WebhookEvents = []string{
+	EventUserSignedUp,
+	EventUserLoggedIn,
+	EventUserStatusChanged,
+	EventUserInvited,
+}
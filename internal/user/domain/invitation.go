@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"time"
+)
+
+// Invitation lets someone join the tenant of the user who invited them. Only
+// a hash of its token is stored; the token itself is handed out once.
+type Invitation struct {
+	ID        string
+	Email     string
+	TenantID  string
+	InvitedBy string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// IssuedInvitation is a new invitation with the token to pass on to the
+// invitee. The token is not shown again.
+type IssuedInvitation struct {
+	Invitation
+	Token string
+}
+
+type InviteUserRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// AcceptInvitationRequest signs the invitee up with the token they were
+// given and the password they chose.
+type AcceptInvitationRequest struct {
+	Token    string `json:"token" validate:"required,max=128"`
+	Password string `json:"password" validate:"required,min=8,max=20"`
+}
@@ -1,5 +1,9 @@
 package domain
 
+import (
+	"time"
+)
+
 type Jwt struct {
 	ID           string `json:"_id"`
 	UserID       string `json:"user_id"`
@@ -7,3 +11,25 @@ type Jwt struct {
 	CreatedAt    string `json:"created_at"`
 	Type         string `json:"token_type"`
 }
+
+// Scopes granted to the tokens. Access tokens call the API, refresh tokens
+// can only be exchanged for new tokens.
+const (
+	ScopeAPI     = "api"
+	ScopeRefresh = "refresh"
+)
+
+// TokenIntrospection describes a token to a resource server that cannot
+// validate it itself. Everything but Active is empty for inactive tokens.
+type TokenIntrospection struct {
+	Active bool
+	// TokenType is AccessTokenKey or RefreshTokenKey.
+	TokenType string
+	Subject   string
+	TokenID   string
+	TenantID  string
+	Scopes    []string
+	Issuer    string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
@@ -1,7 +1,27 @@
 package domain
 
+import (
+	"time"
+)
+
 type Tenant struct {
-	ID        string
-	createdAt string
-	updatedAt string
+	ID       string
+	Name     string
+	Settings map[string]string
+	// AllowedOrigins are the origins of the tenant's apps, allowed to call
+	// the API cross origin with credentials.
+	AllowedOrigins []string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	DeletedAt      *time.Time
+	Version        int64
+}
+
+// UpdateTenantRequest carries a partial tenant update. A nil Name is left
+// untouched, a non nil Settings map or AllowedOrigins list replaces the
+// stored one.
+type UpdateTenantRequest struct {
+	Name           *string           `json:"name" validate:"omitempty,max=100"`
+	Settings       map[string]string `json:"settings" validate:"omitempty,max=50,dive,keys,max=64,endkeys,max=1024"`
+	AllowedOrigins []string          `json:"allowed_origins" validate:"omitempty,max=20,dive,origin"`
 }
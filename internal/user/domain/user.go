@@ -1,6 +1,8 @@
 package domain
 
-import "github.com/go-playground/validator/v10"
+import (
+	"time"
+)
 
 type UserIDKey struct{}
 type UserKey struct{}
@@ -13,9 +15,12 @@ type User struct {
 	Email        string
 	Password     string
 	PasswordSalt string
-	CreatedAt    string
-	UpdatedAt    string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	DeletedAt    *time.Time
 	Status       UserStatus
+	TenantID     string
+	Version      int64
 }
 type UserResponse struct {
 	ID         string
@@ -24,6 +29,10 @@ type UserResponse struct {
 	LastName   string
 	Email      string
 	Status     UserStatus
+	TenantID   string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	Version    int64
 }
 type UserTokens struct {
 	AccessToken  string
@@ -38,9 +47,14 @@ type AddUserRequest struct {
 	Password string `json:"password" validate:"required,min=8,max=20"`
 }
 
-func (u *AddUserRequest) ValidateAddUserRequest() error {
-	return validator.New().Struct(u)
+// UpdateUserRequest carries a partial profile update. Nil fields are left
+// untouched.
+type UpdateUserRequest struct {
+	FirstName  *string `json:"first_name" validate:"omitempty,max=100"`
+	MiddleName *string `json:"middle_name" validate:"omitempty,max=100"`
+	LastName   *string `json:"last_name" validate:"omitempty,max=100"`
 }
+
 func (u *User) FullName() string {
 	return u.FirstName + " " + u.MiddleName + " " + u.LastName
 }
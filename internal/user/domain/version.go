@@ -0,0 +1,5 @@
+package domain
+
+// AnyVersion can be passed as the expected version of an update to skip the
+// optimistic concurrency check, e.g. for `If-Match: *`.
+const AnyVersion int64 = -1
@@ -0,0 +1,8 @@
+package domain
+
+// CreateWebhookRequest subscribes URL to the named events, "*" standing for
+// every event in WebhookEvents.
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" validate:"required,url,max=2048"`
+	Events []string `json:"events" validate:"required,min=1,max=20,dive,required"`
+}
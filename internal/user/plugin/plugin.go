@@ -1,55 +1,341 @@
 package plugin
 
 import (
+	"cleanarch/boiler/internal/config"
+	"cleanarch/boiler/internal/server"
+	grpchandler "cleanarch/boiler/internal/user/adapters/handlers/grpc"
 	"cleanarch/boiler/internal/user/adapters/handlers/http"
+	"cleanarch/boiler/internal/user/adapters/metrics"
+	"cleanarch/boiler/internal/user/adapters/repositories/cache"
 	repositories "cleanarch/boiler/internal/user/adapters/repositories/mongo"
+	"cleanarch/boiler/internal/user/domain"
 	"cleanarch/boiler/internal/user/services"
 	"cleanarch/boiler/internal/user/usecases"
+	utilcache "cleanarch/boiler/internal/utils/cache"
+	"cleanarch/boiler/internal/utils/health"
 	"cleanarch/boiler/internal/utils/logger"
+	"cleanarch/boiler/internal/utils/mongotx"
+	"cleanarch/boiler/internal/utils/openapi"
+	"cleanarch/boiler/internal/utils/ratelimit"
+	"cleanarch/boiler/internal/webhook"
+	webhookmongo "cleanarch/boiler/internal/webhook/mongo"
 	"context"
+	"errors"
+	nethttp "net/http"
+	"strings"
+	"sync/atomic"
 
 	"github.com/go-chi/chi/v5"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc"
 )
 
+// maxCachedOrigins bounds the cached answers to whether a tenant registered
+// an origin.
+const maxCachedOrigins = 10000
+
 type UserPlugin struct {
-	r  chi.Router
-	db *mongo.Database
-	l  logger.Interface
+	db         *mongo.Database
+	l          logger.Interface
+	cfg        config.AuthConfig
+	handler    *http.Handler
+	grpcServer *grpchandler.Server
+	limits     http.RateLimits
+	idempotent func(nethttp.Handler) nethttp.Handler
+	jwtService *services.JwtService
+	tenants    *usecases.TenantUseCases
+	// webhook deliveries are sent by a worker running between Start and Stop
+	webhookStore  *webhookmongo.Store
+	webhookWorker *webhook.Worker
+	stopWebhooks  context.CancelFunc
+	webhooksDone  chan struct{}
+	// indexesReady is set once the collection indexes have been created
+	indexesReady atomic.Bool
 }
 
-func NewUserPlugin(r chi.Router, db *mongo.Database, logger logger.Interface) *UserPlugin {
-	return &UserPlugin{
-		db: db,
-		r:  r,
-		l:  logger,
-	}
+var _ server.Plugin = (*UserPlugin)(nil)
+var _ server.Secured = (*UserPlugin)(nil)
+var _ server.GRPCService = (*UserPlugin)(nil)
+var _ server.OriginPolicy = (*UserPlugin)(nil)
+
+func NewUserPlugin() *UserPlugin {
+	return &UserPlugin{}
+}
+
+func (p *UserPlugin) Name() string {
+	return "user"
 }
-func (p *UserPlugin) Register() {
-	userRepository := repositories.NewUserRepository(p.l, p.db)
-	tenantRepository := repositories.NewTeanantRepository(p.db) //tenantrepository
-	jwtRepository := repositories.NewJwtRepository(p.l, p.db)
-	userService := services.NewUserService(p.l, userRepository)
+
+func (p *UserPlugin) Init(deps server.Deps) error {
+	p.db = deps.DB
+	p.l = deps.Logger
+	p.cfg = deps.Config.Auth
+
+	// repository latency is measured below the caches, per method
+	repoMetrics, err := metrics.NewRepositoryMetrics(deps.Metrics)
+	if err != nil {
+		return err
+	}
+	userRepository := repoMetrics.UserRepository(repositories.NewUserRepository(p.l, p.db))
+	// every cross origin request asks whether a tenant registered its origin,
+	// bounded since clients choose the Origin header
+	tenantRepository := cache.NewTenantRepository(p.l, repoMetrics.TenantRepository(repositories.NewTeanantRepository(p.db)), utilcache.Options{
+		MaxEntries: maxCachedOrigins,
+		DefaultTTL: deps.Config.CORS.TenantOriginsTTL.Std(),
+	})
+	jwtRepository := repoMetrics.JwtRepository(repositories.NewJwtRepository(p.l, p.db))
+	// the auth middleware resolves the user on every request, keep them in memory
+	cachedUserRepository := cache.NewUserRepository(p.l, userRepository, utilcache.Options{
+		MaxEntries: p.cfg.UserCache.MaxEntries,
+		DefaultTTL: p.cfg.UserCache.TTL.Std(),
+	})
+	userService := services.NewUserService(p.l, cachedUserRepository)
 	authService := services.NewAuthService(userRepository)
-	jwtService := services.NewJwtService(p.l, jwtRepository)
+	jwtService := services.NewJwtService(p.l, jwtRepository, services.JwtOptions{
+		Issuer:                p.cfg.Issuer,
+		AccessTokenTTL:        p.cfg.AccessToken.TTL.Std(),
+		RefreshTokenTTL:       p.cfg.RefreshToken.TTL.Std(),
+		AccessPrivateKeyPath:  p.cfg.AccessToken.PrivateKeyPath,
+		AccessPublicKeyPath:   p.cfg.AccessToken.PublicKeyPath,
+		RefreshPrivateKeyPath: p.cfg.RefreshToken.PrivateKeyPath,
+		RefreshPublicKeyPath:  p.cfg.RefreshToken.PublicKeyPath,
+	})
+	p.jwtService = jwtService
 	tenantService := services.NewTeanantService(tenantRepository) //tenantservice create
-	authUsecase := usecases.NewAuthUseCases(p.l, authService, jwtService, userService)
-	tenantUsecase := usecases.NewTenantUseCases(tenantService)
-	userUsecase := usecases.NewUserUsecases(p.l, userService)
-	accountHandler := http.NewHandler(p.l, authUsecase, userUsecase, tenantUsecase)
-	http.RegisterAuthHTTPEndpoints(p.r, accountHandler)
-	createDbIndices(p.db)
-}
-func createDbIndices(db *mongo.Database) error {
-	// Create index on email
-	_, err := db.Collection("users").Indexes().CreateOne(context.TODO(), mongo.IndexModel{
-		Keys:    bson.M{"email": 1},
+	transactor := mongotx.NewTransactor(p.l, p.db)
+	authMetrics, err := metrics.NewAuthMetrics(deps.Metrics)
+	if err != nil {
+		return err
+	}
+	authUsecase := usecases.NewAuthUseCases(p.l, authService, jwtService, userService, tenantService, transactor, deps.Publisher, authMetrics)
+	tenantUsecase := usecases.NewTenantUseCases(tenantService, transactor, deps.Publisher)
+	p.tenants = tenantUsecase
+	userUsecase := usecases.NewUserUsecases(p.l, userService, transactor, deps.Publisher)
+	invitationService := services.NewInvitationService(repoMetrics.InvitationRepository(repositories.NewInvitationRepository(p.db)), p.cfg.InvitationTTL.Std())
+	invitationUsecase := usecases.NewInvitationUseCases(invitationService, authService, transactor, deps.Publisher)
+
+	// tenants' webhooks receive the user events relayed from the outbox
+	hooks := deps.Config.Webhooks
+	p.webhookStore = webhookmongo.NewStore(p.db)
+	webhooks := webhook.NewService(p.l, p.webhookStore, webhook.Options{
+		Events:        domain.WebhookEvents,
+		AllowInsecure: hooks.AllowInsecure,
+	})
+	for _, name := range domain.WebhookEvents {
+		deps.Events.Subscribe(name, webhooks.Handle)
+	}
+	p.webhookWorker = webhook.NewWorker(p.l, p.webhookStore, webhook.WorkerOptions{
+		Interval:             hooks.PollInterval.Std(),
+		BatchSize:            hooks.BatchSize,
+		Timeout:              hooks.Timeout.Std(),
+		MaxAttempts:          hooks.MaxAttempts,
+		InitialBackoff:       hooks.InitialBackoff.Std(),
+		MaxBackoff:           hooks.MaxBackoff.Std(),
+		AllowPrivateNetworks: hooks.AllowInsecure,
+	})
+	limits := deps.Config.RateLimit
+	// gRPC and HTTP logins from one address share the credentials bucket
+	credentials := ratelimit.Policy{
+		Name:  "auth",
+		Limit: server.RateLimit(limits.Auth),
+		Key:   ratelimit.ByIP,
+	}
+	p.grpcServer = grpchandler.NewServer(p.l, authUsecase, userUsecase, func(ctx context.Context, key string) error {
+		return deps.Limiter.Allow(ctx, credentials, key)
+	})
+	p.handler = http.NewHandler(p.l, authUsecase, userUsecase, tenantUsecase, invitationUsecase, http.CookieOptions{
+		// cookies served over TLS are always marked secure
+		Secure:             p.cfg.Cookie.Secure || deps.Config.Server.TLS.Enabled,
+		Domain:             p.cfg.Cookie.Domain,
+		SameSite:           sameSite(p.cfg.Cookie.SameSite),
+		AccessTokenMaxAge:  p.cfg.AccessToken.CookieMaxAge.Std(),
+		RefreshTokenMaxAge: p.cfg.RefreshToken.CookieMaxAge.Std(),
+	}, clientCredentials(p.cfg.Introspection.Clients), webhooks)
+
+	p.idempotent = deps.Idempotency.Middleware(http.IdempotencyPrincipal)
+
+	p.limits = http.RateLimits{
+		Credentials: deps.Limiter.Middleware(credentials),
+		Authenticated: []func(nethttp.Handler) nethttp.Handler{
+			deps.Limiter.Middleware(ratelimit.Policy{
+				Name:  "user",
+				Limit: server.RateLimit(limits.User),
+				Key:   http.RateLimitByUser,
+			}),
+			deps.Limiter.Middleware(ratelimit.Policy{
+				Name:  "tenant",
+				Limit: server.RateLimit(limits.Tenant),
+				Key:   http.RateLimitByTenant,
+			}),
+		},
+	}
+	return nil
+}
+
+func (p *UserPlugin) Routes(r chi.Router) {
+	http.RegisterAuthHTTPEndpoints(r, p.handler, p.limits, p.idempotent)
+}
+
+func (p *UserPlugin) SecuritySchemes() map[string]openapi.SecurityScheme {
+	return http.SecuritySchemes()
+}
+
+// RegisterGRPC exposes login, token refresh and validation and user lookup
+// to internal services.
+func (p *UserPlugin) RegisterGRPC(s grpc.ServiceRegistrar) {
+	grpchandler.RegisterAuthServiceServer(s, p.grpcServer)
+}
+
+// AllowsOrigin lets tenants' apps call the API. Lookup failures deny the
+// origin rather than fail the request.
+func (p *UserPlugin) AllowsOrigin(r *nethttp.Request, origin string) bool {
+	allowed, err := p.tenants.AllowsOrigin(r.Context(), origin)
+	if err != nil {
+		p.l.WithContext(r.Context()).Error("unable to look up tenant origin", "origin", origin, "error", err)
+		return false
+	}
+	return allowed
+}
+
+func (p *UserPlugin) Start(ctx context.Context) error {
+	if err := createDbIndices(ctx, p.db); err != nil {
+		return err
+	}
+	if err := p.webhookStore.CreateIndexes(ctx); err != nil {
+		return err
+	}
+	p.indexesReady.Store(true)
+
+	workerCtx, stop := context.WithCancel(context.Background())
+	p.stopWebhooks = stop
+	p.webhooksDone = make(chan struct{})
+	go func() {
+		defer close(p.webhooksDone)
+		p.webhookWorker.Run(workerCtx)
+	}()
+	return nil
+}
+
+// Stop cancels the webhook worker and waits for it, at most until ctx is
+// done. Deliveries cut short are retried once their lease expires.
+func (p *UserPlugin) Stop(ctx context.Context) error {
+	if p.stopWebhooks == nil {
+		return nil
+	}
+	p.stopWebhooks()
+	select {
+	case <-p.webhooksDone:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *UserPlugin) HealthChecks() []health.Check {
+	return []health.Check{
+		{
+			Name: "user.signing_keys",
+			Func: func(ctx context.Context) error {
+				return p.jwtService.CheckKeys()
+			},
+		},
+		{
+			Name: "user.migrations",
+			Func: func(ctx context.Context) error {
+				if !p.indexesReady.Load() {
+					return errors.New("user indexes not created yet")
+				}
+				return nil
+			},
+		},
+	}
+}
+
+// clientCredentials parses the id:secret pairs validated by the config.
+func clientCredentials(clients []string) http.ClientCredentials {
+	credentials := make(http.ClientCredentials, len(clients))
+	for _, client := range clients {
+		id, secret, _ := strings.Cut(client, ":")
+		credentials[id] = secret
+	}
+	return credentials
+}
+
+func sameSite(mode string) nethttp.SameSite {
+	switch strings.ToLower(mode) {
+	case "strict":
+		return nethttp.SameSiteStrictMode
+	case "none":
+		return nethttp.SameSiteNoneMode
+	default:
+		return nethttp.SameSiteLaxMode
+	}
+}
+
+func createDbIndices(ctx context.Context, db *mongo.Database) error {
+	// Emails are unique among live users only, so a soft deleted account does
+	// not block signing up again. Partial indexes cannot match a missing
+	// field, but a missing deletedAt is indexed as null: live users share it
+	// while every deleted copy carries its own timestamp.
+	_, err := db.Collection("users").Indexes().DropOne(ctx, "email_1")
+	if err != nil && !isIndexNotFound(err) {
+		return err
+	}
+	_, err = db.Collection("users").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "email", Value: 1}, {Key: "deletedAt", Value: 1}},
 		Options: options.Index().SetUnique(true),
 	})
 	if err != nil {
 		return err
 	}
+	// Index backing the tenant user listing
+	_, err = db.Collection("users").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "tenantId", Value: 1}, {Key: "_id", Value: 1}},
+	})
+	if err != nil {
+		return err
+	}
+	// Backing the CORS lookup of tenant origins
+	_, err = db.Collection("tenants").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.M{"allowedOrigins": 1},
+	})
+	if err != nil {
+		return err
+	}
+	// One pending invitation per email and tenant, looked up by token hash
+	// on acceptance and removed by Mongo once expired
+	_, err = db.Collection("invitations").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "tenantId", Value: 1}, {Key: "email", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.M{"tokenHash": 1},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.M{"expiresAt": 1},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	// Refresh tokens are looked up by id on rotation and introspection
+	_, err = db.Collection("jwt").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.M{"id": 1},
+	})
+	if err != nil {
+		return err
+	}
 	return nil
 }
+
+// isIndexNotFound reports whether err is the error of dropping an index that
+// does not exist.
+func isIndexNotFound(err error) bool {
+	var e mongo.CommandError
+	return errors.As(err, &e) && (e.Code == 27 || e.Name == "IndexNotFound")
+}
@@ -0,0 +1,42 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestCreateDbIndices_EmailUniqueAmongLiveUsers(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("replaces the global email index", func(mt *mtest.T) {
+		// the old index is already gone, then the five index commands are sent
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 27, Name: "IndexNotFound", Message: "index not found with name [email_1]"}))
+		for range 5 {
+			mt.AddMockResponses(mtest.CreateSuccessResponse())
+		}
+		if err := createDbIndices(context.Background(), mt.DB); err != nil {
+			mt.Fatalf("createDbIndices() error = %v", err)
+		}
+
+		drop := mt.GetStartedEvent()
+		if drop.CommandName != "dropIndexes" || drop.Command.Lookup("index").StringValue() != "email_1" {
+			mt.Fatalf("first command = %v, want dropping email_1", drop.Command)
+		}
+		index := mt.GetStartedEvent().Command.Lookup("indexes", "0").Document()
+		want, _ := bson.Marshal(bson.D{{Key: "email", Value: int32(1)}, {Key: "deletedAt", Value: int32(1)}})
+		if !bytes.Equal(want, index.Lookup("key").Document()) || !index.Lookup("unique").Boolean() {
+			mt.Errorf("email index = %v, want unique on email and deletedAt", index)
+		}
+	})
+
+	mt.Run("fails on other drop errors", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 13, Name: "Unauthorized", Message: "not authorized"}))
+		if err := createDbIndices(context.Background(), mt.DB); err == nil {
+			mt.Fatal("createDbIndices() error = nil, want the drop error")
+		}
+	})
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"cleanarch/boiler/internal/user/domain"
+	"cleanarch/boiler/internal/utils/tracing"
 	"context"
 )
 
@@ -9,7 +10,7 @@ type AuthSerivce struct {
 	authRepository AuthRepository
 }
 type AuthRepository interface {
-	SignUp(ctx context.Context, user *domain.AddUserRequest, tenantId string) error
+	SignUp(ctx context.Context, user *domain.AddUserRequest, tenantId string) (string, error)
 	GetAuthenticatedUser(ctx context.Context, user *domain.AddUserRequest) (*domain.UserResponse, error)
 }
 
@@ -18,9 +19,15 @@ func NewAuthService(repo AuthRepository) *AuthSerivce {
 		authRepository: repo,
 	}
 }
-func (a *AuthSerivce) SignUp(ctx context.Context, user *domain.AddUserRequest, tenantId string) error {
+func (a *AuthSerivce) SignUp(ctx context.Context, user *domain.AddUserRequest, tenantId string) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "AuthSerivce.SignUp")
+	defer tracing.End(span, &err)
+
 	return a.authRepository.SignUp(ctx, user, tenantId)
 }
-func (a *AuthSerivce) GetAuthenticatedUser(ctx context.Context, user *domain.AddUserRequest) (*domain.UserResponse, error) {
+func (a *AuthSerivce) GetAuthenticatedUser(ctx context.Context, user *domain.AddUserRequest) (_ *domain.UserResponse, err error) {
+	ctx, span := tracing.Start(ctx, "AuthSerivce.GetAuthenticatedUser")
+	defer tracing.End(span, &err)
+
 	return a.authRepository.GetAuthenticatedUser(ctx, user)
 }
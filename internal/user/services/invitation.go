@@ -0,0 +1,77 @@
+package services
+
+import (
+	"cleanarch/boiler/internal/user/domain"
+	"cleanarch/boiler/internal/utils/tracing"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+)
+
+type InvitationService struct {
+	invitationRepository InvitationRepository
+	ttl                  time.Duration
+}
+
+type InvitationRepository interface {
+	Save(ctx context.Context, invitation *domain.Invitation, tokenHash string) (*domain.Invitation, error)
+	Take(ctx context.Context, tokenHash string, at time.Time) (*domain.Invitation, error)
+}
+
+// NewInvitationService issues invitations that can be accepted for ttl.
+func NewInvitationService(invitationRepository InvitationRepository, ttl time.Duration) *InvitationService {
+	return &InvitationService{
+		invitationRepository: invitationRepository,
+		ttl:                  ttl,
+	}
+}
+
+// Invite issues an invitation for email to join the tenant of inviter.
+func (s *InvitationService) Invite(ctx context.Context, inviter *domain.UserResponse, email string) (_ *domain.IssuedInvitation, err error) {
+	ctx, span := tracing.Start(ctx, "InvitationService.Invite")
+	defer tracing.End(span, &err)
+
+	token, err := newInvitationToken()
+	if err != nil {
+		return nil, err
+	}
+	t := time.Now().UTC().Truncate(time.Millisecond)
+	invitation, err := s.invitationRepository.Save(ctx, &domain.Invitation{
+		Email:     email,
+		TenantID:  inviter.TenantID,
+		InvitedBy: inviter.ID,
+		CreatedAt: t,
+		ExpiresAt: t.Add(s.ttl),
+	}, hashInvitationToken(token))
+	if err != nil {
+		return nil, err
+	}
+	return &domain.IssuedInvitation{Invitation: *invitation, Token: token}, nil
+}
+
+// Take consumes the pending invitation of token. Unknown, used and expired
+// tokens yield domain.ErrInvitationNotFound.
+func (s *InvitationService) Take(ctx context.Context, token string) (_ *domain.Invitation, err error) {
+	ctx, span := tracing.Start(ctx, "InvitationService.Take")
+	defer tracing.End(span, &err)
+
+	return s.invitationRepository.Take(ctx, hashInvitationToken(token), time.Now())
+}
+
+func newInvitationToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashInvitationToken is what gets stored, so a leaked collection does not
+// leak usable tokens. The tokens are random, a plain hash is enough.
+func hashInvitationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -3,10 +3,12 @@ package services
 import (
 	"cleanarch/boiler/internal/user/domain"
 	"cleanarch/boiler/internal/utils/logger"
+	"cleanarch/boiler/internal/utils/tracing"
 	"context"
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -14,124 +16,246 @@ import (
 )
 
 type AccessTokenCustomClaims struct {
-	UserID string `json:"user_id"`
-	Type   string `json:"type"`
+	UserID   string `json:"user_id"`
+	Type     string `json:"type"`
+	TenantID string `json:"tenant_id,omitempty"`
+	// Scope is a space separated list as in RFC 8693.
+	Scope string `json:"scope,omitempty"`
 	jwt.RegisteredClaims
 }
 type RefreshTokenCustomClaims struct {
-	UserID string `json:"user_id"`
-	Type   string `json:"type"`
+	UserID   string `json:"user_id"`
+	Type     string `json:"type"`
+	TenantID string `json:"tenant_id,omitempty"`
+	Scope    string `json:"scope,omitempty"`
 	jwt.RegisteredClaims
 }
+
+// errVerificationKey marks parse failures caused by the public key rather than
+// by the token.
+var errVerificationKey = errors.New("verification key unavailable")
+
 type JwtService struct {
 	l             logger.Interface
 	jwtRepository JwtRepository
+	opts          JwtOptions
+}
+
+// JwtOptions configures token signing.
+type JwtOptions struct {
+	Issuer                string
+	AccessTokenTTL        time.Duration
+	RefreshTokenTTL       time.Duration
+	AccessPrivateKeyPath  string
+	AccessPublicKeyPath   string
+	RefreshPrivateKeyPath string
+	RefreshPublicKeyPath  string
 }
 type JwtRepository interface {
 	CreateRefreshJwt(ctx context.Context, jwt *domain.Jwt, currentRefreshId string) error
+	// RefreshJwtExists reports whether a refresh token is still stored, i.e.
+	// has been neither rotated nor revoked.
+	RefreshJwtExists(ctx context.Context, id string) (bool, error)
 }
 
-func NewJwtService(l logger.Interface, jwtRepository JwtRepository) *JwtService {
+func NewJwtService(l logger.Interface, jwtRepository JwtRepository, opts JwtOptions) *JwtService {
 	return &JwtService{
 		l:             l,
 		jwtRepository: jwtRepository,
+		opts:          opts,
 	}
 }
 
-func (s *JwtService) ValidateAccessToken(ctx context.Context, tokenString string) (string, error) {
-
-	token, err := jwt.ParseWithClaims(tokenString, &AccessTokenCustomClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
-			s.l.Error("Unexpected signing method in auth token")
-			return nil, errors.New("UNEXPECTED SIGNING METHOD IN AUTH TOKEN")
+// CheckKeys verifies that all signing and verification keys can be read and
+// parsed. Keys are read from disk on use, so this catches missing or broken
+// key files before requests fail.
+func (s *JwtService) CheckKeys() error {
+	for _, path := range []string{s.opts.AccessPrivateKeyPath, s.opts.RefreshPrivateKeyPath} {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return err
 		}
-		verifyBytes, err := os.ReadFile("./keys/auth-public.pem")
+		if _, err := jwt.ParseRSAPrivateKeyFromPEM(raw); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	for _, path := range []string{s.opts.AccessPublicKeyPath, s.opts.RefreshPublicKeyPath} {
+		raw, err := os.ReadFile(path)
 		if err != nil {
-			s.l.Error("unable to read public key", "error", err)
-			return nil, err
+			return err
+		}
+		if _, err := jwt.ParseRSAPublicKeyFromPEM(raw); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
 		}
+	}
+	return nil
+}
 
-		verifyKey, err := jwt.ParseRSAPublicKeyFromPEM(verifyBytes)
+func (s *JwtService) ValidateAccessToken(ctx context.Context, tokenString string) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "JwtService.ValidateAccessToken")
+	defer tracing.End(span, &err)
+
+	claims, err := s.parseAccessTokenWithClaims(ctx, tokenString)
+	if err != nil {
+		return "", err
+	}
+	return claims.UserID, nil
+}
+
+// Introspect describes tokenString, which may be an access or a refresh
+// token. hint is domain.AccessTokenKey or domain.RefreshTokenKey and only
+// decides which kind is tried first. Tokens that are invalid, expired or, for
+// refresh tokens, no longer stored are reported inactive rather than as an
+// error.
+func (s *JwtService) Introspect(ctx context.Context, tokenString, hint string) (_ *domain.TokenIntrospection, err error) {
+	ctx, span := tracing.Start(ctx, "JwtService.Introspect")
+	defer tracing.End(span, &err)
+
+	introspectors := []func(context.Context, string) (*domain.TokenIntrospection, error){s.introspectAccessToken, s.introspectRefreshToken}
+	if hint == domain.RefreshTokenKey {
+		introspectors[0], introspectors[1] = introspectors[1], introspectors[0]
+	}
+	for _, introspect := range introspectors {
+		info, err := introspect(ctx, tokenString)
+		if errors.Is(err, domain.ErrInvalidToken) {
+			continue
+		}
 		if err != nil {
-			s.l.Error("unable to parse public key", "error", err)
 			return nil, err
 		}
+		return info, nil
+	}
+	return &domain.TokenIntrospection{}, nil
+}
 
-		return verifyKey, nil
-	})
+func (s *JwtService) introspectAccessToken(ctx context.Context, tokenString string) (*domain.TokenIntrospection, error) {
+	claims, err := s.parseAccessTokenWithClaims(ctx, tokenString)
+	if err != nil {
+		return nil, err
+	}
+	return introspection(domain.AccessTokenKey, claims.UserID, claims.TenantID, claims.Scope, claims.RegisteredClaims), nil
+}
 
+func (s *JwtService) introspectRefreshToken(ctx context.Context, tokenString string) (*domain.TokenIntrospection, error) {
+	claims, err := s.activeRefreshToken(ctx, tokenString)
 	if err != nil {
-		s.l.Error("unable to parse claims", "error", err)
-		return "", err
+		return nil, err
 	}
+	return introspection(domain.RefreshTokenKey, claims.UserID, claims.TenantID, claims.Scope, claims.RegisteredClaims), nil
+}
 
-	claims, ok := token.Claims.(*AccessTokenCustomClaims)
-	if !ok || !token.Valid || claims.UserID == "" || claims.Type != "access" {
-		return "", errors.New("invalid token: authentication failed")
+// activeRefreshToken parses a refresh token and checks that it is still
+// stored. Refresh and introspection both go through it, so a token reported
+// inactive can never be used.
+func (s *JwtService) activeRefreshToken(ctx context.Context, tokenString string) (*RefreshTokenCustomClaims, error) {
+	claims, err := s.parseRefreshTokenWithClaims(ctx, tokenString)
+	if err != nil {
+		return nil, err
 	}
-	return claims.UserID, nil
+	if claims.Type != "refresh" || claims.UserID == "" {
+		return nil, domain.ErrInvalidToken
+	}
+	// rotation and revocation delete the token from the jwt collection
+	stored, err := s.jwtRepository.RefreshJwtExists(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !stored {
+		return nil, domain.ErrInvalidToken.WithMessage("refresh token was rotated or revoked")
+	}
+	return claims, nil
 }
-func (s *JwtService) GenerateAccessToken(ctx context.Context, user *domain.UserResponse) (string, error) {
-	userID := user.ID
-	tokenType := "access"
 
+func introspection(tokenType, userID, tenantID, scope string, claims jwt.RegisteredClaims) *domain.TokenIntrospection {
+	info := &domain.TokenIntrospection{
+		Active:    true,
+		TokenType: tokenType,
+		Subject:   userID,
+		TokenID:   claims.ID,
+		TenantID:  tenantID,
+		Scopes:    strings.Fields(scope),
+		Issuer:    claims.Issuer,
+	}
+	if claims.IssuedAt != nil {
+		info.IssuedAt = claims.IssuedAt.Time
+	}
+	if claims.ExpiresAt != nil {
+		info.ExpiresAt = claims.ExpiresAt.Time
+	}
+	return info
+}
+
+func (s *JwtService) GenerateAccessToken(ctx context.Context, user *domain.UserResponse) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "JwtService.GenerateAccessToken")
+	defer tracing.End(span, &err)
+
+	issuedAt := time.Now()
 	claims := AccessTokenCustomClaims{
-		userID,
-		tokenType,
-		jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute * time.Duration(10))),
-			Issuer:    "cleanarch.service",
+		UserID:   user.ID,
+		Type:     "access",
+		TenantID: user.TenantID,
+		Scope:    domain.ScopeAPI,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			ExpiresAt: jwt.NewNumericDate(issuedAt.Add(s.opts.AccessTokenTTL)),
+			Issuer:    s.opts.Issuer,
 			ID:        uuid.NewString(),
 		},
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
 
-	signBytes, err := os.ReadFile("./keys/auth-private.pem")
+	signBytes, err := os.ReadFile(s.opts.AccessPrivateKeyPath)
 	if err != nil {
-		s.l.Error("unable to read private key", "error", err)
+		s.l.WithContext(ctx).Error("unable to read private key", "error", err)
 		return "", errors.New("could not generate access token. please try again later")
 	}
 
 	signKey, err := jwt.ParseRSAPrivateKeyFromPEM(signBytes)
 	if err != nil {
-		s.l.Error("unable to read private key", "error", err)
+		s.l.WithContext(ctx).Error("unable to read private key", "error", err)
 		return "", errors.New("could not generate access token. please try again later")
 	}
 
 	return token.SignedString(signKey)
 }
 
-func (s *JwtService) GenerateRefreshToken(ctx context.Context, user *domain.UserResponse, currentRefreshTokenId string) (string, error) {
+func (s *JwtService) GenerateRefreshToken(ctx context.Context, user *domain.UserResponse, currentRefreshTokenId string) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "JwtService.GenerateRefreshToken")
+	defer tracing.End(span, &err)
+
 	tokenType := "refresh"
 	tokenId := uuid.NewString()
 	issuedAt := time.Now()
-	expiresAt := issuedAt.Add(time.Minute * time.Duration(100))
+	expiresAt := issuedAt.Add(s.opts.RefreshTokenTTL)
 	claims := RefreshTokenCustomClaims{
-		user.ID,
-		tokenType,
-		jwt.RegisteredClaims{
+		UserID:   user.ID,
+		Type:     tokenType,
+		TenantID: user.TenantID,
+		Scope:    domain.ScopeRefresh,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
-			Issuer:    "cleanarch.service",
+			Issuer:    s.opts.Issuer,
 			ID:        tokenId,
 		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
 
-	signBytes, err := os.ReadFile("./keys/auth-refresh-private.pem")
+	signBytes, err := os.ReadFile(s.opts.RefreshPrivateKeyPath)
 	if err != nil {
-		s.l.Error("unable to read private key", "error", err)
+		s.l.WithContext(ctx).Error("unable to read private key", "error", err)
 		return "", errors.New("could not generate access token. please try again later")
 	}
 
 	signKey, err := jwt.ParseRSAPrivateKeyFromPEM(signBytes)
 	if err != nil {
-		s.l.Error("unable to read private key", "error", err)
+		s.l.WithContext(ctx).Error("unable to read private key", "error", err)
 		return "", errors.New("could not generate access token. please try again later")
 	}
 	signedToken, err := token.SignedString(signKey)
 	if err != nil {
-		s.l.Error("unable to read private key", "error", err)
+		s.l.WithContext(ctx).Error("unable to read private key", "error", err)
 		return "", errors.New("could not generate access token. please try again later")
 	}
 	jwtToken := domain.Jwt{
@@ -142,22 +266,25 @@ func (s *JwtService) GenerateRefreshToken(ctx context.Context, user *domain.User
 		CreatedAt:    fmt.Sprint(issuedAt.Unix()),
 	}
 	err = s.jwtRepository.CreateRefreshJwt(ctx, &jwtToken, currentRefreshTokenId)
+	if errors.Is(err, domain.ErrInvalidToken) {
+		// rotated concurrently, the caller must log in again
+		return "", err
+	}
 	if err != nil {
-		s.l.Error("unable to read private key", "error", err)
+		s.l.WithContext(ctx).Error("unable to store refresh token", "error", err)
 		return "", errors.New("could not generate access token. please try again later")
 	}
 	return signedToken, nil
 }
 
-func (s *JwtService) RefreshTokenAccess(ctx context.Context, refreshToken string) (string, string, error) {
+func (s *JwtService) RefreshTokenAccess(ctx context.Context, refreshToken string) (_, _ string, err error) {
+	ctx, span := tracing.Start(ctx, "JwtService.RefreshTokenAccess")
+	defer tracing.End(span, &err)
 
-	oldClaims, err := s.parseRefreshTokenWithClaims(refreshToken)
+	oldClaims, err := s.activeRefreshToken(ctx, refreshToken)
 	if err != nil {
 		return "", "", err
 	}
-	if oldClaims.Type != "refresh" {
-		return "", "", errors.New("INVALID TOKEN TYPE")
-	}
 	return oldClaims.UserID, oldClaims.ID, nil
 	// tokenType := "refresh"
 	// tokenId := uuid.NewString()
@@ -205,30 +332,70 @@ func (s *JwtService) RefreshTokenAccess(ctx context.Context, refreshToken string
 	// }
 	// return signedToken, nil
 }
-func (s *JwtService) parseRefreshTokenWithClaims(token string) (*RefreshTokenCustomClaims, error) {
+func (s *JwtService) parseAccessTokenWithClaims(ctx context.Context, tokenString string) (*AccessTokenCustomClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &AccessTokenCustomClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			s.l.WithContext(ctx).Error("Unexpected signing method in auth token")
+			return nil, errors.New("UNEXPECTED SIGNING METHOD IN AUTH TOKEN")
+		}
+		verifyBytes, err := os.ReadFile(s.opts.AccessPublicKeyPath)
+		if err != nil {
+			s.l.WithContext(ctx).Error("unable to read public key", "error", err)
+			return nil, fmt.Errorf("%w: %w", errVerificationKey, err)
+		}
+
+		verifyKey, err := jwt.ParseRSAPublicKeyFromPEM(verifyBytes)
+		if err != nil {
+			s.l.WithContext(ctx).Error("unable to parse public key", "error", err)
+			return nil, fmt.Errorf("%w: %w", errVerificationKey, err)
+		}
+
+		return verifyKey, nil
+	})
+
+	if err != nil {
+		return nil, tokenError(err)
+	}
+
+	claims, ok := token.Claims.(*AccessTokenCustomClaims)
+	if !ok || !token.Valid || claims.UserID == "" || claims.Type != "access" {
+		return nil, domain.ErrInvalidToken
+	}
+	return claims, nil
+}
+func (s *JwtService) parseRefreshTokenWithClaims(ctx context.Context, token string) (*RefreshTokenCustomClaims, error) {
 	parsedToken, err := jwt.ParseWithClaims(token, &RefreshTokenCustomClaims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
-			s.l.Error("UNEXPECTED SIGNING METHOD IN AUTH TOKEN")
+			s.l.WithContext(ctx).Error("UNEXPECTED SIGNING METHOD IN AUTH TOKEN")
 			return nil, errors.New("UNEXPECTED SIGNING METHOD IN AUTH TOKEN")
 		}
-		verifyBytes, err := os.ReadFile("./keys/auth-refresh-public.pem")
+		verifyBytes, err := os.ReadFile(s.opts.RefreshPublicKeyPath)
 		if err != nil {
-			s.l.Error("UNABLE TO READ PUBLIC KEY", "error", err)
-			return nil, err
+			s.l.WithContext(ctx).Error("UNABLE TO READ PUBLIC KEY", "error", err)
+			return nil, fmt.Errorf("%w: %w", errVerificationKey, err)
 		}
 
 		verifyKey, err := jwt.ParseRSAPublicKeyFromPEM(verifyBytes)
 		if err != nil {
-			s.l.Error("UNABLE TO PARSE PUBLIC KEY", "error", err)
-			return nil, err
+			s.l.WithContext(ctx).Error("UNABLE TO PARSE PUBLIC KEY", "error", err)
+			return nil, fmt.Errorf("%w: %w", errVerificationKey, err)
 		}
 
 		return verifyKey, nil
 	})
 	if err != nil {
-		s.l.Error("UNABLE TO PARSE TOKEN", "error", err)
-		return nil, err
+		return nil, tokenError(err)
 	}
 	return parsedToken.Claims.(*RefreshTokenCustomClaims), nil
 
 }
+
+// tokenError classifies a parse failure. Tokens that could not be verified
+// because a key is unreadable are a server fault; everything else is the
+// client's token being invalid.
+func tokenError(err error) error {
+	if errors.Is(err, errVerificationKey) {
+		return err
+	}
+	return domain.ErrInvalidToken.Wrap(err)
+}
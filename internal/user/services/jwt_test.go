@@ -0,0 +1,105 @@
+package services
+
+import (
+	"cleanarch/boiler/internal/user/domain"
+	"cleanarch/boiler/internal/utils/logger"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// memoryJwts stores refresh token ids like the jwt collection.
+type memoryJwts map[string]bool
+
+func (m memoryJwts) CreateRefreshJwt(ctx context.Context, jwt *domain.Jwt, currentRefreshId string) error {
+	if currentRefreshId != "" {
+		if !m[currentRefreshId] {
+			return domain.ErrInvalidToken
+		}
+		delete(m, currentRefreshId)
+	}
+	m[jwt.ID] = true
+	return nil
+}
+
+func (m memoryJwts) RefreshJwtExists(ctx context.Context, id string) (bool, error) {
+	return m[id], nil
+}
+
+// writeKeyPair writes a throwaway RSA key pair and returns the paths of the
+// private and public keys.
+func writeKeyPair(t *testing.T, dir, name string) (string, string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	private := filepath.Join(dir, name+"-private.pem")
+	public := filepath.Join(dir, name+"-public.pem")
+	if err := os.WriteFile(private, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(public, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return private, public
+}
+
+func newTestJwtService(t *testing.T, repo JwtRepository) *JwtService {
+	t.Helper()
+	dir := t.TempDir()
+	accessPrivate, accessPublic := writeKeyPair(t, dir, "access")
+	refreshPrivate, refreshPublic := writeKeyPair(t, dir, "refresh")
+	return NewJwtService(logger.NewLogger("error"), repo, JwtOptions{
+		Issuer:                "test",
+		AccessTokenTTL:        time.Minute,
+		RefreshTokenTTL:       time.Hour,
+		AccessPrivateKeyPath:  accessPrivate,
+		AccessPublicKeyPath:   accessPublic,
+		RefreshPrivateKeyPath: refreshPrivate,
+		RefreshPublicKeyPath:  refreshPublic,
+	})
+}
+
+func TestJwtService_RotatedRefreshTokenRejected(t *testing.T) {
+	ctx := context.Background()
+	repo := memoryJwts{}
+	s := newTestJwtService(t, repo)
+	user := &domain.UserResponse{ID: "u1", TenantID: "t1"}
+
+	first, err := s.GenerateRefreshToken(ctx, user, "")
+	if err != nil {
+		t.Fatalf("GenerateRefreshToken() error = %v", err)
+	}
+	userID, tokenID, err := s.RefreshTokenAccess(ctx, first)
+	if err != nil || userID != "u1" {
+		t.Fatalf("RefreshTokenAccess() = %q, %v", userID, err)
+	}
+	if _, err := s.GenerateRefreshToken(ctx, user, tokenID); err != nil {
+		t.Fatalf("GenerateRefreshToken() rotating error = %v", err)
+	}
+
+	// refresh and introspection agree that the rotated token is dead
+	if _, _, err := s.RefreshTokenAccess(ctx, first); !errors.Is(err, domain.ErrInvalidToken) {
+		t.Errorf("RefreshTokenAccess() with a rotated token error = %v, want ErrInvalidToken", err)
+	}
+	info, err := s.Introspect(ctx, first, domain.RefreshTokenKey)
+	if err != nil || info.Active {
+		t.Errorf("Introspect() of a rotated token = %+v, %v, want inactive", info, err)
+	}
+	// rotating the same token twice fails rather than minting a second one
+	if _, err := s.GenerateRefreshToken(ctx, user, tokenID); !errors.Is(err, domain.ErrInvalidToken) {
+		t.Errorf("GenerateRefreshToken() rotating twice error = %v, want ErrInvalidToken", err)
+	}
+}
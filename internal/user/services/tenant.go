@@ -1,6 +1,12 @@
 package services
 
-import "context"
+import (
+	"cleanarch/boiler/internal/user/domain"
+	"cleanarch/boiler/internal/utils/origin"
+	"cleanarch/boiler/internal/utils/tracing"
+	"context"
+	"slices"
+)
 
 type TenantService struct {
 	tenantRepository TenantRepository
@@ -8,6 +14,11 @@ type TenantService struct {
 
 type TenantRepository interface {
 	Create(ctx context.Context, tenantId string) error
+	GetByID(ctx context.Context, tenantId string) (*domain.Tenant, error)
+	Update(ctx context.Context, tenantId string, update *domain.UpdateTenantRequest, expectedVersion int64) (*domain.Tenant, error)
+	Delete(ctx context.Context, tenantId string) error
+	Restore(ctx context.Context, tenantId string) error
+	HasAllowedOrigin(ctx context.Context, origin string) (bool, error)
 }
 
 func NewTeanantService(tenantRepository TenantRepository) *TenantService {
@@ -16,6 +27,60 @@ func NewTeanantService(tenantRepository TenantRepository) *TenantService {
 	}
 }
 
-func (t *TenantService) Create(ctx context.Context, tenantId string) error {
+func (t *TenantService) Create(ctx context.Context, tenantId string) (err error) {
+	ctx, span := tracing.Start(ctx, "TenantService.Create")
+	defer tracing.End(span, &err)
+
 	return t.tenantRepository.Create(ctx, tenantId)
 }
+
+func (t *TenantService) GetByID(ctx context.Context, tenantId string) (_ *domain.Tenant, err error) {
+	ctx, span := tracing.Start(ctx, "TenantService.GetByID")
+	defer tracing.End(span, &err)
+
+	return t.tenantRepository.GetByID(ctx, tenantId)
+}
+
+func (t *TenantService) Update(ctx context.Context, tenantId string, update *domain.UpdateTenantRequest, expectedVersion int64) (_ *domain.Tenant, err error) {
+	ctx, span := tracing.Start(ctx, "TenantService.Update")
+	defer tracing.End(span, &err)
+
+	if update.AllowedOrigins != nil {
+		// store origins the way browsers send them, so lookups match exactly
+		normalized := *update
+		normalized.AllowedOrigins = make([]string, 0, len(update.AllowedOrigins))
+		for _, o := range update.AllowedOrigins {
+			n, err := origin.Normalize(o)
+			if err != nil {
+				return nil, err
+			}
+			if !slices.Contains(normalized.AllowedOrigins, n) {
+				normalized.AllowedOrigins = append(normalized.AllowedOrigins, n)
+			}
+		}
+		update = &normalized
+	}
+	return t.tenantRepository.Update(ctx, tenantId, update, expectedVersion)
+}
+
+// AllowsOrigin reports whether any tenant registered the normalized origin.
+func (t *TenantService) AllowsOrigin(ctx context.Context, origin string) (_ bool, err error) {
+	ctx, span := tracing.Start(ctx, "TenantService.AllowsOrigin")
+	defer tracing.End(span, &err)
+
+	return t.tenantRepository.HasAllowedOrigin(ctx, origin)
+}
+
+func (t *TenantService) Delete(ctx context.Context, tenantId string) (err error) {
+	ctx, span := tracing.Start(ctx, "TenantService.Delete")
+	defer tracing.End(span, &err)
+
+	return t.tenantRepository.Delete(ctx, tenantId)
+}
+
+func (t *TenantService) Restore(ctx context.Context, tenantId string) (err error) {
+	ctx, span := tracing.Start(ctx, "TenantService.Restore")
+	defer tracing.End(span, &err)
+
+	return t.tenantRepository.Restore(ctx, tenantId)
+}
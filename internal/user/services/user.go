@@ -3,6 +3,8 @@ package services
 import (
 	"cleanarch/boiler/internal/user/domain"
 	"cleanarch/boiler/internal/utils/logger"
+	"cleanarch/boiler/internal/utils/query"
+	"cleanarch/boiler/internal/utils/tracing"
 	"context"
 )
 
@@ -13,6 +15,11 @@ type UserService struct {
 
 type UserRepository interface {
 	GetUserByID(ctx context.Context, id string) (*domain.UserResponse, error)
+	ReloadUserByID(ctx context.Context, id string) (*domain.UserResponse, error)
+	ListUsers(ctx context.Context, tenantId string, spec query.Spec) (*query.Page[*domain.UserResponse], error)
+	UpdateUser(ctx context.Context, id string, update *domain.UpdateUserRequest, expectedVersion int64) (*domain.UserResponse, error)
+	DeleteUser(ctx context.Context, id string) (*domain.UserResponse, error)
+	RestoreUser(ctx context.Context, id string) (*domain.UserResponse, error)
 }
 
 func NewUserService(l logger.Interface, userRepository UserRepository) *UserService {
@@ -21,6 +28,39 @@ func NewUserService(l logger.Interface, userRepository UserRepository) *UserServ
 		userRepository: userRepository,
 	}
 }
-func (s *UserService) GetUserByID(ctx context.Context, id string) (*domain.UserResponse, error) {
+func (s *UserService) GetUserByID(ctx context.Context, id string) (_ *domain.UserResponse, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUserByID")
+	defer tracing.End(span, &err)
+
 	return s.userRepository.GetUserByID(ctx, id)
 }
+func (s *UserService) ReloadUserByID(ctx context.Context, id string) (_ *domain.UserResponse, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.ReloadUserByID")
+	defer tracing.End(span, &err)
+
+	return s.userRepository.ReloadUserByID(ctx, id)
+}
+func (s *UserService) ListUsers(ctx context.Context, tenantId string, spec query.Spec) (_ *query.Page[*domain.UserResponse], err error) {
+	ctx, span := tracing.Start(ctx, "UserService.ListUsers")
+	defer tracing.End(span, &err)
+
+	return s.userRepository.ListUsers(ctx, tenantId, spec)
+}
+func (s *UserService) UpdateUser(ctx context.Context, id string, update *domain.UpdateUserRequest, expectedVersion int64) (_ *domain.UserResponse, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.UpdateUser")
+	defer tracing.End(span, &err)
+
+	return s.userRepository.UpdateUser(ctx, id, update, expectedVersion)
+}
+func (s *UserService) DeleteUser(ctx context.Context, id string) (_ *domain.UserResponse, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.DeleteUser")
+	defer tracing.End(span, &err)
+
+	return s.userRepository.DeleteUser(ctx, id)
+}
+func (s *UserService) RestoreUser(ctx context.Context, id string) (_ *domain.UserResponse, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.RestoreUser")
+	defer tracing.End(span, &err)
+
+	return s.userRepository.RestoreUser(ctx, id)
+}
@@ -1,20 +1,36 @@
 package usecases
 
 import (
+	"cleanarch/boiler/internal/events"
 	"cleanarch/boiler/internal/user/domain"
 	"cleanarch/boiler/internal/utils/logger"
+	"cleanarch/boiler/internal/utils/tracing"
 	"context"
+	"errors"
 )
 
 type AuthUseCases struct {
-	l           logger.Interface
-	authService AuthService
-	jwtService  JwtService
-	userService UserService
+	l             logger.Interface
+	authService   AuthService
+	jwtService    JwtService
+	userService   UserService
+	tenantService TenantService
+	tx            Transactor
+	publisher     EventPublisher
+	metrics       AuthMetrics
+}
+
+// AuthMetrics records authentication outcomes. Failures are passed as errors
+// so the implementation can classify them.
+type AuthMetrics interface {
+	LoginSucceeded()
+	LoginFailed(err error)
+	RefreshRotated()
+	TokenValidationFailed(err error)
 }
 
 type AuthService interface {
-	SignUp(ctx context.Context, user *domain.AddUserRequest, tenantId string) error
+	SignUp(ctx context.Context, user *domain.AddUserRequest, tenantId string) (string, error)
 	GetAuthenticatedUser(ctx context.Context, user *domain.AddUserRequest) (*domain.UserResponse, error)
 }
 type JwtService interface {
@@ -22,27 +38,49 @@ type JwtService interface {
 	GenerateRefreshToken(ctx context.Context, user *domain.UserResponse, currentRefreshTokenID string) (string, error)
 	ValidateAccessToken(ctx context.Context, accessToken string) (string, error)
 	RefreshTokenAccess(ctx context.Context, refreshToken string) (string, string, error)
+	Introspect(ctx context.Context, token, hint string) (*domain.TokenIntrospection, error)
 }
 
-func NewAuthUseCases(l logger.Interface, authService AuthService, jwtService JwtService, userService UserService) *AuthUseCases {
+func NewAuthUseCases(l logger.Interface, authService AuthService, jwtService JwtService, userService UserService, tenantService TenantService, tx Transactor, publisher EventPublisher, metrics AuthMetrics) *AuthUseCases {
 	return &AuthUseCases{
-		l:           l,
-		authService: authService,
-		jwtService:  jwtService,
-		userService: userService,
+		l:             l,
+		authService:   authService,
+		jwtService:    jwtService,
+		userService:   userService,
+		tenantService: tenantService,
+		tx:            tx,
+		publisher:     publisher,
+		metrics:       metrics,
 	}
 }
 
-func (a *AuthUseCases) Login(ctx context.Context, user *domain.AddUserRequest) (*domain.UserTokens, error) {
-	dbUser, error := a.authService.GetAuthenticatedUser(ctx, user)
-	if error != nil {
-		return nil, error
+func (a *AuthUseCases) Login(ctx context.Context, user *domain.AddUserRequest) (tokens *domain.UserTokens, err error) {
+	ctx, span := tracing.Start(ctx, "AuthUseCases.Login")
+	defer tracing.End(span, &err)
+
+	defer func() {
+		if err != nil {
+			a.metrics.LoginFailed(err)
+		} else {
+			a.metrics.LoginSucceeded()
+		}
+	}()
+	dbUser, err := a.authService.GetAuthenticatedUser(ctx, user)
+	if err != nil {
+		return nil, err
 	}
 	accessToken, err := a.jwtService.GenerateAccessToken(ctx, dbUser)
 	if err != nil {
 		return nil, err
 	}
-	refreshToken, err := a.jwtService.GenerateRefreshToken(ctx, dbUser, "")
+	var refreshToken string
+	err = a.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		refreshToken, err = a.jwtService.GenerateRefreshToken(ctx, dbUser, "")
+		if err != nil {
+			return err
+		}
+		return a.publisher.Publish(ctx, events.New(domain.EventUserLoggedIn, dbUser.ID, dbUser.TenantID, nil))
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -53,28 +91,51 @@ func (a *AuthUseCases) Login(ctx context.Context, user *domain.AddUserRequest) (
 	}, nil
 
 }
-func (a *AuthUseCases) ValidateAccessToken(ctx context.Context, token string) (string, error) {
-	return a.jwtService.ValidateAccessToken(ctx, token)
+func (a *AuthUseCases) ValidateAccessToken(ctx context.Context, token string) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "AuthUseCases.ValidateAccessToken")
+	defer tracing.End(span, &err)
+
+	userId, err := a.jwtService.ValidateAccessToken(ctx, token)
+	if err != nil {
+		a.metrics.TokenValidationFailed(err)
+	}
+	return userId, err
 }
 
-func (a *AuthUseCases) RefreshTokenAccess(ctx context.Context, token string) (*domain.UserTokens, error) {
+func (a *AuthUseCases) RefreshTokenAccess(ctx context.Context, token string) (_ *domain.UserTokens, err error) {
+	ctx, span := tracing.Start(ctx, "AuthUseCases.RefreshTokenAccess")
+	defer tracing.End(span, &err)
+
 	userId, tokenId, err := a.jwtService.RefreshTokenAccess(ctx, token)
 	if err != nil {
 		return nil, err
 	}
-	dbUser, error := a.userService.GetUserByID(ctx, userId)
-	if error != nil {
-		return nil, error
+	dbUser, err := a.userService.GetUserByID(ctx, userId)
+	if err != nil {
+		return nil, err
 	}
 	accessToken, err := a.jwtService.GenerateAccessToken(ctx, dbUser)
 	if err != nil {
 		return nil, err
 	}
-	refreshToken, err := a.jwtService.GenerateRefreshToken(ctx, dbUser, tokenId)
+	// the old refresh token is revoked in the same unit of work that issues
+	// the new one and records the rotation
+	var refreshToken string
+	err = a.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		refreshToken, err = a.jwtService.GenerateRefreshToken(ctx, dbUser, tokenId)
+		if err != nil {
+			return err
+		}
+		return a.publisher.Publish(ctx, events.New(domain.EventRefreshTokenRotated, dbUser.ID, dbUser.TenantID, map[string]string{
+			"previous_token_id": tokenId,
+		}))
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	a.metrics.RefreshRotated()
+
 	return &domain.UserTokens{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
@@ -82,6 +143,51 @@ func (a *AuthUseCases) RefreshTokenAccess(ctx context.Context, token string) (*d
 
 }
 
-func (a *AuthUseCases) SignUp(ctx context.Context, user *domain.AddUserRequest, tenantId string) error {
-	return a.authService.SignUp(ctx, user, tenantId)
+// Introspect describes a token for resource servers. Besides the checks of
+// the token itself, a token is inactive once its user is deleted or has moved
+// to another tenant. Tokens issued before the tenant claim existed are
+// reported with the user's tenant.
+func (a *AuthUseCases) Introspect(ctx context.Context, token, hint string) (_ *domain.TokenIntrospection, err error) {
+	ctx, span := tracing.Start(ctx, "AuthUseCases.Introspect")
+	defer tracing.End(span, &err)
+
+	info, err := a.jwtService.Introspect(ctx, token, hint)
+	if err != nil || !info.Active {
+		return info, err
+	}
+	user, err := a.userService.GetUserByID(ctx, info.Subject)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		return &domain.TokenIntrospection{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info.TenantID == "" {
+		info.TenantID = user.TenantID
+	}
+	if info.TenantID != user.TenantID {
+		return &domain.TokenIntrospection{}, nil
+	}
+	return info, nil
+}
+
+// SignUp creates the tenant and its first user as one unit of work, so a
+// failed sign up does not leave an orphan tenant behind.
+func (a *AuthUseCases) SignUp(ctx context.Context, user *domain.AddUserRequest, tenantId string) (err error) {
+	ctx, span := tracing.Start(ctx, "AuthUseCases.SignUp")
+	defer tracing.End(span, &err)
+
+	return a.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := a.tenantService.Create(ctx, tenantId); err != nil {
+			return err
+		}
+		userId, err := a.authService.SignUp(ctx, user, tenantId)
+		if err != nil {
+			return err
+		}
+		return a.publisher.Publish(ctx,
+			events.New(domain.EventTenantCreated, tenantId, tenantId, nil),
+			events.New(domain.EventUserSignedUp, userId, tenantId, map[string]string{"email": user.Email}),
+		)
+	})
 }
@@ -0,0 +1,61 @@
+package usecases
+
+import (
+	"cleanarch/boiler/internal/user/domain"
+	"cleanarch/boiler/internal/utils/logger"
+	"cleanarch/boiler/internal/utils/query"
+	"context"
+	"testing"
+)
+
+// activeTokens reports every token active for the user named by the token.
+type activeTokens struct {
+	JwtService
+}
+
+func (activeTokens) Introspect(ctx context.Context, token, hint string) (*domain.TokenIntrospection, error) {
+	return &domain.TokenIntrospection{Active: true, TokenType: domain.AccessTokenKey, Subject: token, TenantID: "t1"}, nil
+}
+
+// liveUsers finds only u1, like the repository once a user is deleted.
+type liveUsers struct{}
+
+func (liveUsers) GetUserByID(ctx context.Context, id string) (*domain.UserResponse, error) {
+	if id == "u1" {
+		return &domain.UserResponse{ID: "u1", TenantID: "t1"}, nil
+	}
+	return nil, domain.ErrUserNotFound
+}
+
+func (u liveUsers) ReloadUserByID(ctx context.Context, id string) (*domain.UserResponse, error) {
+	return u.GetUserByID(ctx, id)
+}
+
+func (liveUsers) ListUsers(ctx context.Context, tenantId string, spec query.Spec) (*query.Page[*domain.UserResponse], error) {
+	return nil, nil
+}
+
+func (liveUsers) UpdateUser(ctx context.Context, id string, update *domain.UpdateUserRequest, expectedVersion int64) (*domain.UserResponse, error) {
+	return nil, nil
+}
+
+func (liveUsers) DeleteUser(ctx context.Context, id string) (*domain.UserResponse, error) {
+	return nil, nil
+}
+
+func (liveUsers) RestoreUser(ctx context.Context, id string) (*domain.UserResponse, error) {
+	return nil, nil
+}
+
+func TestAuthUseCases_IntrospectDeletedUser(t *testing.T) {
+	a := NewAuthUseCases(logger.NewLogger("error"), nil, activeTokens{}, liveUsers{}, nil, nil, nil, nil)
+
+	info, err := a.Introspect(context.Background(), "u1", "")
+	if err != nil || !info.Active {
+		t.Fatalf("Introspect() of a live user = %+v, %v, want active", info, err)
+	}
+	info, err = a.Introspect(context.Background(), "u-deleted", "")
+	if err != nil || info.Active {
+		t.Fatalf("Introspect() of a deleted user = %+v, %v, want inactive without error", info, err)
+	}
+}
@@ -0,0 +1,18 @@
+package usecases
+
+import (
+	"cleanarch/boiler/internal/events"
+	"context"
+)
+
+// EventPublisher emits domain events. Publishing inside a Transactor unit of
+// work stores the events atomically with the state change.
+type EventPublisher interface {
+	Publish(ctx context.Context, events ...events.Event) error
+}
+
+// Transactor runs fn as a single unit of work. Repositories and the publisher
+// must be called with the ctx passed to fn to take part in it.
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
@@ -0,0 +1,77 @@
+package usecases
+
+import (
+	"cleanarch/boiler/internal/events"
+	"cleanarch/boiler/internal/user/domain"
+	"cleanarch/boiler/internal/utils/tracing"
+	"context"
+)
+
+type InvitationUseCases struct {
+	invitationService InvitationService
+	authService       AuthService
+	tx                Transactor
+	publisher         EventPublisher
+}
+
+type InvitationService interface {
+	Invite(ctx context.Context, inviter *domain.UserResponse, email string) (*domain.IssuedInvitation, error)
+	Take(ctx context.Context, token string) (*domain.Invitation, error)
+}
+
+func NewInvitationUseCases(invitationService InvitationService, authService AuthService, tx Transactor, publisher EventPublisher) *InvitationUseCases {
+	return &InvitationUseCases{
+		invitationService: invitationService,
+		authService:       authService,
+		tx:                tx,
+		publisher:         publisher,
+	}
+}
+
+// Invite invites someone into the tenant of inviter, publishing
+// user.invited in the same unit of work. The returned token is the only copy.
+func (i *InvitationUseCases) Invite(ctx context.Context, inviter *domain.UserResponse, req *domain.InviteUserRequest) (issued *domain.IssuedInvitation, err error) {
+	ctx, span := tracing.Start(ctx, "InvitationUseCases.Invite")
+	defer tracing.End(span, &err)
+
+	err = i.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		issued, err = i.invitationService.Invite(ctx, inviter, req.Email)
+		if err != nil {
+			return err
+		}
+		return i.publisher.Publish(ctx, events.New(domain.EventUserInvited, issued.ID, inviter.TenantID, map[string]string{
+			"email":      issued.Email,
+			"invited_by": inviter.ID,
+		}))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return issued, nil
+}
+
+// Accept signs the invitee up in the inviting tenant. The invitation is
+// consumed with the sign up, so a failed sign up, e.g. because the email is
+// taken, leaves it pending.
+func (i *InvitationUseCases) Accept(ctx context.Context, req *domain.AcceptInvitationRequest) (err error) {
+	ctx, span := tracing.Start(ctx, "InvitationUseCases.Accept")
+	defer tracing.End(span, &err)
+
+	return i.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		invitation, err := i.invitationService.Take(ctx, req.Token)
+		if err != nil {
+			return err
+		}
+		userId, err := i.authService.SignUp(ctx, &domain.AddUserRequest{
+			Email:    invitation.Email,
+			Password: req.Password,
+		}, invitation.TenantID)
+		if err != nil {
+			return err
+		}
+		return i.publisher.Publish(ctx, events.New(domain.EventUserSignedUp, userId, invitation.TenantID, map[string]string{
+			"email":         invitation.Email,
+			"invitation_id": invitation.ID,
+		}))
+	})
+}
@@ -0,0 +1,89 @@
+package usecases
+
+import (
+	"cleanarch/boiler/internal/user/domain"
+	"context"
+	"errors"
+	"testing"
+)
+
+// pendingInvitations hands out each token's invitation once.
+type pendingInvitations map[string]*domain.Invitation
+
+func (p pendingInvitations) Invite(ctx context.Context, inviter *domain.UserResponse, email string) (*domain.IssuedInvitation, error) {
+	invitation := &domain.Invitation{ID: "i1", Email: email, TenantID: inviter.TenantID, InvitedBy: inviter.ID}
+	p["token"] = invitation
+	return &domain.IssuedInvitation{Invitation: *invitation, Token: "token"}, nil
+}
+
+func (p pendingInvitations) Take(ctx context.Context, token string) (*domain.Invitation, error) {
+	invitation, ok := p[token]
+	if !ok {
+		return nil, domain.ErrInvitationNotFound
+	}
+	delete(p, token)
+	return invitation, nil
+}
+
+// signUps records the users signed up, refusing taken emails.
+type signUps struct {
+	AuthService
+	taken  string
+	tenant string
+}
+
+func (s *signUps) SignUp(ctx context.Context, user *domain.AddUserRequest, tenantId string) (string, error) {
+	if user.Email == s.taken {
+		return "", domain.ErrUserAlreadyExists
+	}
+	s.tenant = tenantId
+	return "u2", nil
+}
+
+func TestInvitationUseCases_InviteAndAccept(t *testing.T) {
+	ctx := context.Background()
+	publisher := &recordingPublisher{}
+	auth := &signUps{}
+	i := NewInvitationUseCases(pendingInvitations{}, auth, fakeTransactor{}, publisher)
+
+	issued, err := i.Invite(ctx, &domain.UserResponse{ID: "u1", TenantID: "t1"}, &domain.InviteUserRequest{Email: "b@example.com"})
+	if err != nil || issued.Token != "token" {
+		t.Fatalf("Invite() = %+v, %v, want the issued token", issued, err)
+	}
+	if err := i.Accept(ctx, &domain.AcceptInvitationRequest{Token: issued.Token, Password: "secret-password"}); err != nil {
+		t.Fatalf("Accept() error = %v", err)
+	}
+	if auth.tenant != "t1" {
+		t.Errorf("signed up in tenant %q, want the inviting tenant t1", auth.tenant)
+	}
+	if err := i.Accept(ctx, &domain.AcceptInvitationRequest{Token: issued.Token, Password: "secret-password"}); !errors.Is(err, domain.ErrInvitationNotFound) {
+		t.Errorf("Accept() twice error = %v, want ErrInvitationNotFound", err)
+	}
+
+	if len(publisher.events) != 2 {
+		t.Fatalf("published %+v, want user.invited and user.signed_up", publisher.events)
+	}
+	invited, signedUp := publisher.events[0], publisher.events[1]
+	if invited.Name != domain.EventUserInvited || invited.TenantID != "t1" || invited.Payload["email"] != "b@example.com" || invited.Payload["invited_by"] != "u1" {
+		t.Errorf("invite event = %+v", invited)
+	}
+	if signedUp.Name != domain.EventUserSignedUp || signedUp.AggregateID != "u2" || signedUp.TenantID != "t1" || signedUp.Payload["invitation_id"] != "i1" {
+		t.Errorf("sign up event = %+v", signedUp)
+	}
+}
+
+func TestInvitationUseCases_AcceptTakenEmail(t *testing.T) {
+	ctx := context.Background()
+	publisher := &recordingPublisher{}
+	i := NewInvitationUseCases(pendingInvitations{
+		"token": {ID: "i1", Email: "a@example.com", TenantID: "t1"},
+	}, &signUps{taken: "a@example.com"}, fakeTransactor{}, publisher)
+
+	err := i.Accept(ctx, &domain.AcceptInvitationRequest{Token: "token", Password: "secret-password"})
+	if !errors.Is(err, domain.ErrUserAlreadyExists) {
+		t.Fatalf("Accept() error = %v, want ErrUserAlreadyExists", err)
+	}
+	if len(publisher.events) != 0 {
+		t.Errorf("published %+v after a failed sign up", publisher.events)
+	}
+}
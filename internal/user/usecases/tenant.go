@@ -1,23 +1,84 @@
 package usecases
 
 import (
+	"cleanarch/boiler/internal/events"
+	"cleanarch/boiler/internal/user/domain"
+	"cleanarch/boiler/internal/utils/tracing"
 	"context"
 )
 
 type TenantUseCases struct {
 	tenantService TenantService
+	tx            Transactor
+	publisher     EventPublisher
 }
 
 type TenantService interface {
 	Create(ctx context.Context, tenantId string) error
+	GetByID(ctx context.Context, tenantId string) (*domain.Tenant, error)
+	Update(ctx context.Context, tenantId string, update *domain.UpdateTenantRequest, expectedVersion int64) (*domain.Tenant, error)
+	Delete(ctx context.Context, tenantId string) error
+	Restore(ctx context.Context, tenantId string) error
+	AllowsOrigin(ctx context.Context, origin string) (bool, error)
 }
 
-func NewTenantUseCases(tService TenantService) *TenantUseCases {
+func NewTenantUseCases(tService TenantService, tx Transactor, publisher EventPublisher) *TenantUseCases {
 	return &TenantUseCases{
 		tenantService: tService,
+		tx:            tx,
+		publisher:     publisher,
 	}
 }
 
-func (t *TenantUseCases) Create(ctx context.Context, tenantId string) error {
-	return t.tenantService.Create(ctx, tenantId)
+func (t *TenantUseCases) Create(ctx context.Context, tenantId string) (err error) {
+	ctx, span := tracing.Start(ctx, "TenantUseCases.Create")
+	defer tracing.End(span, &err)
+
+	return t.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := t.tenantService.Create(ctx, tenantId); err != nil {
+			return err
+		}
+		return t.publisher.Publish(ctx, events.New(domain.EventTenantCreated, tenantId, tenantId, nil))
+	})
+}
+
+func (t *TenantUseCases) GetByID(ctx context.Context, tenantId string) (_ *domain.Tenant, err error) {
+	ctx, span := tracing.Start(ctx, "TenantUseCases.GetByID")
+	defer tracing.End(span, &err)
+
+	return t.tenantService.GetByID(ctx, tenantId)
+}
+
+// Update changes the tenant settings if the tenant is still at
+// expectedVersion, otherwise domain.ErrConflict is returned.
+func (t *TenantUseCases) Update(ctx context.Context, tenantId string, update *domain.UpdateTenantRequest, expectedVersion int64) (_ *domain.Tenant, err error) {
+	ctx, span := tracing.Start(ctx, "TenantUseCases.Update")
+	defer tracing.End(span, &err)
+
+	return t.tenantService.Update(ctx, tenantId, update, expectedVersion)
+}
+
+// AllowsOrigin reports whether a tenant registered origin as one of its app
+// origins.
+func (t *TenantUseCases) AllowsOrigin(ctx context.Context, origin string) (_ bool, err error) {
+	ctx, span := tracing.Start(ctx, "TenantUseCases.AllowsOrigin")
+	defer tracing.End(span, &err)
+
+	return t.tenantService.AllowsOrigin(ctx, origin)
+}
+
+// Delete soft deletes a tenant.
+func (t *TenantUseCases) Delete(ctx context.Context, tenantId string) (err error) {
+	ctx, span := tracing.Start(ctx, "TenantUseCases.Delete")
+	defer tracing.End(span, &err)
+
+	return t.tenantService.Delete(ctx, tenantId)
+}
+
+// Restore is an admin operation that undoes Delete.
+func (t *TenantUseCases) Restore(ctx context.Context, tenantId string) (err error) {
+	ctx, span := tracing.Start(ctx, "TenantUseCases.Restore")
+	defer tracing.End(span, &err)
+
+	return t.tenantService.Restore(ctx, tenantId)
 }
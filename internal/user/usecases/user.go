@@ -1,27 +1,103 @@
 package usecases
 
 import (
+	"cleanarch/boiler/internal/events"
 	"cleanarch/boiler/internal/user/domain"
 	"cleanarch/boiler/internal/utils/logger"
+	"cleanarch/boiler/internal/utils/query"
+	"cleanarch/boiler/internal/utils/tracing"
 	"context"
 )
 
 type UserUsecases struct {
 	l           logger.Interface
 	userService UserService
+	tx          Transactor
+	publisher   EventPublisher
 }
 
 type UserService interface {
 	GetUserByID(ctx context.Context, id string) (*domain.UserResponse, error)
+	ReloadUserByID(ctx context.Context, id string) (*domain.UserResponse, error)
+	ListUsers(ctx context.Context, tenantId string, spec query.Spec) (*query.Page[*domain.UserResponse], error)
+	UpdateUser(ctx context.Context, id string, update *domain.UpdateUserRequest, expectedVersion int64) (*domain.UserResponse, error)
+	DeleteUser(ctx context.Context, id string) (*domain.UserResponse, error)
+	RestoreUser(ctx context.Context, id string) (*domain.UserResponse, error)
 }
 
-func NewUserUsecases(l logger.Interface, userService UserService) *UserUsecases {
+func NewUserUsecases(l logger.Interface, userService UserService, tx Transactor, publisher EventPublisher) *UserUsecases {
 	return &UserUsecases{
 		l:           l,
 		userService: userService,
+		tx:          tx,
+		publisher:   publisher,
 	}
 }
 
-func (u *UserUsecases) GetUserByID(ctx context.Context, id string) (*domain.UserResponse, error) {
+func (u *UserUsecases) GetUserByID(ctx context.Context, id string) (_ *domain.UserResponse, err error) {
+	ctx, span := tracing.Start(ctx, "UserUsecases.GetUserByID")
+	defer tracing.End(span, &err)
+
 	return u.userService.GetUserByID(ctx, id)
 }
+
+// ReloadUserByID returns the stored user, skipping the cache, so its version
+// is current even when another instance updated it.
+func (u *UserUsecases) ReloadUserByID(ctx context.Context, id string) (_ *domain.UserResponse, err error) {
+	ctx, span := tracing.Start(ctx, "UserUsecases.ReloadUserByID")
+	defer tracing.End(span, &err)
+
+	return u.userService.ReloadUserByID(ctx, id)
+}
+
+// ListUsers pages through the users of a tenant.
+func (u *UserUsecases) ListUsers(ctx context.Context, tenantId string, spec query.Spec) (_ *query.Page[*domain.UserResponse], err error) {
+	ctx, span := tracing.Start(ctx, "UserUsecases.ListUsers")
+	defer tracing.End(span, &err)
+
+	return u.userService.ListUsers(ctx, tenantId, spec)
+}
+
+// UpdateUser updates the profile of a user if it is still at expectedVersion.
+// A concurrent modification results in domain.ErrConflict.
+func (u *UserUsecases) UpdateUser(ctx context.Context, id string, update *domain.UpdateUserRequest, expectedVersion int64) (_ *domain.UserResponse, err error) {
+	ctx, span := tracing.Start(ctx, "UserUsecases.UpdateUser")
+	defer tracing.End(span, &err)
+
+	return u.userService.UpdateUser(ctx, id, update, expectedVersion)
+}
+
+// DeleteUser soft deletes a user. The account stops resolving everywhere but
+// can be brought back with RestoreUser. The status change is published in the
+// same unit of work.
+func (u *UserUsecases) DeleteUser(ctx context.Context, id string) (err error) {
+	ctx, span := tracing.Start(ctx, "UserUsecases.DeleteUser")
+	defer tracing.End(span, &err)
+
+	return u.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		user, err := u.userService.DeleteUser(ctx, id)
+		if err != nil {
+			return err
+		}
+		return u.publisher.Publish(ctx, statusChanged(user, domain.UserStatusDeleted))
+	})
+}
+
+// RestoreUser is an admin operation that undoes DeleteUser, published like
+// it.
+func (u *UserUsecases) RestoreUser(ctx context.Context, id string) (err error) {
+	ctx, span := tracing.Start(ctx, "UserUsecases.RestoreUser")
+	defer tracing.End(span, &err)
+
+	return u.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		user, err := u.userService.RestoreUser(ctx, id)
+		if err != nil {
+			return err
+		}
+		return u.publisher.Publish(ctx, statusChanged(user, domain.UserStatusActive))
+	})
+}
+
+func statusChanged(user *domain.UserResponse, status string) events.Event {
+	return events.New(domain.EventUserStatusChanged, user.ID, user.TenantID, map[string]string{"status": status})
+}
@@ -1,5 +1,79 @@
 package usecases
 
+import (
+	"cleanarch/boiler/internal/events"
+	"cleanarch/boiler/internal/user/domain"
+	"cleanarch/boiler/internal/utils/logger"
+	"context"
+	"errors"
+	"testing"
+)
+
+type inTransactionKey struct{}
+
+// fakeTransactor runs the unit of work, marking its context.
+type fakeTransactor struct{}
+
+func (fakeTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(context.WithValue(ctx, inTransactionKey{}, true))
+}
+
+// recordingPublisher keeps the events published inside a unit of work and
+// fails those published outside of one.
+type recordingPublisher struct {
+	events []events.Event
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, evts ...events.Event) error {
+	if ctx.Value(inTransactionKey{}) == nil {
+		return errors.New("published outside of the transaction")
+	}
+	p.events = append(p.events, evts...)
+	return nil
+}
+
+// statusUsers deletes and restores u1 only.
+type statusUsers struct {
+	liveUsers
+}
+
+func (statusUsers) DeleteUser(ctx context.Context, id string) (*domain.UserResponse, error) {
+	if id != "u1" {
+		return nil, domain.ErrUserNotFound
+	}
+	return &domain.UserResponse{ID: "u1", TenantID: "t1"}, nil
+}
+
+func (u statusUsers) RestoreUser(ctx context.Context, id string) (*domain.UserResponse, error) {
+	return u.DeleteUser(ctx, id)
+}
+
+func TestUserUsecases_PublishStatusChanges(t *testing.T) {
+	ctx := context.Background()
+	publisher := &recordingPublisher{}
+	u := NewUserUsecases(logger.NewLogger("error"), statusUsers{}, fakeTransactor{}, publisher)
+
+	if err := u.DeleteUser(ctx, "u1"); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+	if err := u.RestoreUser(ctx, "u1"); err != nil {
+		t.Fatalf("RestoreUser() error = %v", err)
+	}
+	if err := u.DeleteUser(ctx, "missing"); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Fatalf("DeleteUser() of a missing user error = %v, want ErrUserNotFound", err)
+	}
+
+	want := []string{domain.UserStatusDeleted, domain.UserStatusActive}
+	if len(publisher.events) != len(want) {
+		t.Fatalf("published %d events, want %d", len(publisher.events), len(want))
+	}
+	for i, evt := range publisher.events {
+		if evt.Name != domain.EventUserStatusChanged || evt.AggregateID != "u1" || evt.TenantID != "t1" || evt.Payload["status"] != want[i] {
+			t.Errorf("event %d = %+v, want status %s of u1 in t1", i, evt, want[i])
+		}
+	}
+}
+
 // import (
 // 	"context"
 // 	"errors"
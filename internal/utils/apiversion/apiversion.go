@@ -0,0 +1,93 @@
+// Package apiversion serves API versions side by side. Every version has its
+// own path prefix, e.g. /v1/me; unversioned paths are served by the version
+// named in the Accept header (application/vnd.cleanarch.v2+json) or by the
+// default version.
+package apiversion
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"cleanarch/boiler/internal/utils/apperr"
+	"cleanarch/boiler/internal/utils/problem"
+)
+
+// MediaTypePrefix is followed by the version name and a structured syntax
+// suffix in Accept headers selecting a version.
+const MediaTypePrefix = "application/vnd.cleanarch."
+
+var ErrUnknownVersion = apperr.New(apperr.NotAcceptable, "unknown_api_version", "the requested API version is not served")
+
+// Version describes an API version.
+type Version struct {
+	// Name is the path prefix of the version, e.g. "v1".
+	Name string
+	// Deprecation, when set, is when the version was deprecated.
+	Deprecation time.Time
+	// Sunset, when set, is when the version stops being served.
+	Sunset time.Time
+	// Successor names the version replacing a deprecated one.
+	Successor string
+}
+
+// Headers marks the responses of a deprecated version with the Deprecation
+// (RFC 9745) and Sunset (RFC 8594) headers and links the successor version.
+func (v Version) Headers(next http.Handler) http.Handler {
+	if v.Deprecation.IsZero() && v.Sunset.IsZero() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		if !v.Deprecation.IsZero() {
+			h.Set("Deprecation", fmt.Sprintf("@%d", v.Deprecation.Unix()))
+		}
+		if !v.Sunset.IsZero() {
+			h.Set("Sunset", v.Sunset.UTC().Format(http.TimeFormat))
+		}
+		if v.Successor != "" {
+			h.Add("Link", fmt.Sprintf(`</%s>; rel="successor-version"`, v.Successor))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// FromAccept returns the version named by a vendor media type in an Accept
+// header, e.g. "v2" for `application/vnd.cleanarch.v2+json`.
+func FromAccept(accept string) (string, bool) {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		rest, ok := strings.CutPrefix(mediaType, MediaTypePrefix)
+		if !ok {
+			continue
+		}
+		if name, _, _ := strings.Cut(rest, "+"); name != "" {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// Negotiate serves unversioned requests with the handler of the version
+// named in the Accept header, or of def when none is named. Versions not in
+// handlers are answered with 406.
+func Negotiate(handlers map[string]http.Handler, def string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept")
+		name, ok := FromAccept(r.Header.Get("Accept"))
+		if !ok {
+			name = def
+		}
+		h, ok := handlers[name]
+		if !ok {
+			problem.Write(w, r, ErrUnknownVersion)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,102 @@
+// Package apperr is the error taxonomy shared by all layers. An Error has a
+// Kind, which decides how it is surfaced (e.g. the HTTP status), a stable Code
+// clients can rely on and a Message safe to show them. The underlying cause
+// is kept for logs only.
+package apperr
+
+import "errors"
+
+type Kind string
+
+const (
+	Internal             Kind = "internal"
+	NotFound             Kind = "not_found"
+	Conflict             Kind = "conflict"
+	Unauthorized         Kind = "unauthorized"
+	Forbidden            Kind = "forbidden"
+	Validation           Kind = "validation"
+	RateLimited          Kind = "rate_limited"
+	PreconditionFailed   Kind = "precondition_failed"
+	PreconditionRequired Kind = "precondition_required"
+	UnsupportedMediaType Kind = "unsupported_media_type"
+	TooLarge             Kind = "too_large"
+	NotAcceptable        Kind = "not_acceptable"
+	// Unprocessable is a well formed request that cannot be processed, e.g.
+	// one reusing an idempotency key with a different body.
+	Unprocessable Kind = "unprocessable"
+)
+
+// FieldError describes one invalid field of a request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Fields  []FieldError
+	cause   error
+}
+
+// New creates an error, typically a package level sentinel such as
+// domain.ErrUserNotFound.
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return e.Message + ": " + e.cause.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is matches any error with the same kind and code, so copies made by Wrap,
+// WithMessage and WithFields still match their sentinel.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Kind == e.Kind && t.Code == e.Code
+}
+
+// Wrap returns a copy of e caused by err.
+func (e *Error) Wrap(err error) *Error {
+	c := *e
+	c.cause = err
+	return &c
+}
+
+// WithMessage returns a copy of e with a more specific client message.
+func (e *Error) WithMessage(message string) *Error {
+	c := *e
+	c.Message = message
+	return &c
+}
+
+// WithFields returns a copy of e describing the invalid fields.
+func (e *Error) WithFields(fields ...FieldError) *Error {
+	c := *e
+	c.Fields = append(append([]FieldError(nil), e.Fields...), fields...)
+	return &c
+}
+
+// As returns the first *Error in err's chain.
+func As(err error) (*Error, bool) {
+	var e *Error
+	ok := errors.As(err, &e)
+	return e, ok
+}
+
+// KindOf returns the kind of the first *Error in err's chain, Internal if
+// there is none.
+func KindOf(err error) Kind {
+	if e, ok := As(err); ok {
+		return e.Kind
+	}
+	return Internal
+}
@@ -0,0 +1,78 @@
+// Package bind decodes and validates request bodies for handlers. Every
+// failure is an apperr error, so handlers hand it to their error mapper as is.
+package bind
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"cleanarch/boiler/internal/utils/apperr"
+	"cleanarch/boiler/internal/utils/validate"
+)
+
+// MaxBytes bounds the size of a request body.
+const MaxBytes = 1 << 20
+
+var ErrUnsupportedMediaType = apperr.New(apperr.UnsupportedMediaType, "unsupported_media_type", "Content-Type must be application/json")
+var ErrBodyTooLarge = apperr.New(apperr.TooLarge, "body_too_large", fmt.Sprintf("request body must not exceed %d bytes", MaxBytes))
+var ErrMalformedBody = apperr.New(apperr.Validation, "malformed_body", "request body is not valid JSON")
+
+// JSON decodes the body of r into dst and validates dst against its
+// `validate` tags.
+func JSON(w http.ResponseWriter, r *http.Request, dst interface{}) error {
+	if err := DecodeJSON(w, r, dst); err != nil {
+		return err
+	}
+	return validate.Struct(dst)
+}
+
+// DecodeJSON decodes the body of r into dst without validating it. The body
+// must be a single JSON value of at most MaxBytes sent as application/json
+// (or a +json type), and must not contain fields dst does not have.
+func DecodeJSON(w http.ResponseWriter, r *http.Request, dst interface{}) error {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json")) {
+		return ErrUnsupportedMediaType
+	}
+
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		return decodeError(err)
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return ErrMalformedBody.WithMessage("request body must contain a single JSON value")
+	}
+	return nil
+}
+
+func decodeError(err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var maxErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxErr):
+		return ErrBodyTooLarge.Wrap(err)
+	case errors.Is(err, io.EOF):
+		return ErrMalformedBody.WithMessage("request body is empty")
+	case errors.As(err, &syntaxErr):
+		return ErrMalformedBody.WithMessage(fmt.Sprintf("request body is not valid JSON (at offset %d)", syntaxErr.Offset)).Wrap(err)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return ErrMalformedBody.WithMessage("request body is truncated").Wrap(err)
+	case errors.As(err, &typeErr):
+		return validate.ErrInvalid.WithFields(apperr.FieldError{
+			Field:   typeErr.Field,
+			Message: "must be a " + typeErr.Type.String(),
+		}).Wrap(err)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no typed error for unknown fields
+		name := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return validate.ErrInvalid.WithFields(apperr.FieldError{Field: name, Message: "is not a known field"}).Wrap(err)
+	}
+	return ErrMalformedBody.Wrap(err)
+}
@@ -0,0 +1,83 @@
+package bind
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"cleanarch/boiler/internal/utils/apperr"
+	"cleanarch/boiler/internal/utils/validate"
+)
+
+type signUp struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required,min=8,max=20"`
+}
+
+func request(contentType, body string) (*httptest.ResponseRecorder, *http.Request) {
+	r := httptest.NewRequest(http.MethodPost, "/signup", strings.NewReader(body))
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	return httptest.NewRecorder(), r
+}
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        error
+		fields      map[string]string
+	}{
+		{name: "valid", contentType: "application/json; charset=utf-8", body: `{"email":"a@b.co","password":"longenough"}`},
+		{name: "missing content type", body: `{}`, want: ErrUnsupportedMediaType},
+		{name: "form content type", contentType: "application/x-www-form-urlencoded", body: `{}`, want: ErrUnsupportedMediaType},
+		{name: "empty body", contentType: "application/json", body: ``, want: ErrMalformedBody},
+		{name: "syntax error", contentType: "application/json", body: `{"email":`, want: ErrMalformedBody},
+		{name: "trailing data", contentType: "application/json", body: `{"email":"a@b.co","password":"longenough"}{}`, want: ErrMalformedBody},
+		{name: "too large", contentType: "application/json", body: `{"email":"` + strings.Repeat("a", MaxBytes) + `"}`, want: ErrBodyTooLarge},
+		{
+			name: "unknown field", contentType: "application/json", body: `{"email":"a@b.co","password":"longenough","admin":true}`,
+			want: validate.ErrInvalid, fields: map[string]string{"admin": "is not a known field"},
+		},
+		{
+			name: "wrong type", contentType: "application/json", body: `{"email":1}`,
+			want: validate.ErrInvalid, fields: map[string]string{"email": "must be a string"},
+		},
+		{
+			name: "rules", contentType: "application/json", body: `{"email":"nope","password":"short"}`,
+			want: validate.ErrInvalid, fields: map[string]string{
+				"email":    "must be a valid email address",
+				"password": "must be at least 8 characters long",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, r := request(tt.contentType, tt.body)
+			err := JSON(w, r, new(signUp))
+			if !errors.Is(err, tt.want) && !(err == nil && tt.want == nil) {
+				t.Fatalf("JSON() error = %v, want %v", err, tt.want)
+			}
+			if tt.fields == nil {
+				return
+			}
+			e, _ := apperr.As(err)
+			got := map[string]string{}
+			for _, f := range e.Fields {
+				got[f.Field] = f.Message
+			}
+			if len(got) != len(tt.fields) {
+				t.Fatalf("fields = %v, want %v", got, tt.fields)
+			}
+			for field, message := range tt.fields {
+				if got[field] != message {
+					t.Errorf("field %s = %q, want %q", field, got[field], message)
+				}
+			}
+		})
+	}
+}
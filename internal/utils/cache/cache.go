@@ -0,0 +1,249 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Options configures a Cache.
+type Options struct {
+	// MaxEntries bounds the number of entries kept in the cache. When the
+	// limit is reached the least recently used entry is evicted. Zero means
+	// no limit.
+	MaxEntries int
+	// DefaultTTL is the time to live applied by Set. Zero means entries never
+	// expire on their own.
+	DefaultTTL time.Duration
+	// LoadTimeout bounds each load run by GetOrLoad. Loads are shared by
+	// every caller of a key, so they do not stop when the caller that
+	// started them gives up. Zero means 10 seconds.
+	LoadTimeout time.Duration
+	// Now overrides the clock, mainly for tests.
+	Now func() time.Time
+}
+
+const defaultLoadTimeout = 10 * time.Second
+
+// Stats is a point in time snapshot of the cache counters.
+type Stats struct {
+	Hits        uint64
+	Misses      uint64
+	Evictions   uint64
+	Expirations uint64
+	Entries     int
+}
+
+// LoadFunc loads the value for a key on a cache miss.
+type LoadFunc[V any] func(ctx context.Context) (V, error)
+
+type entry[K comparable, V any] struct {
+	key       K
+	value     V
+	expiresAt time.Time
+}
+
+// Cache is a concurrency safe, size bounded LRU cache with per entry TTL.
+// Concurrent loads of the same key through GetOrLoad are collapsed into a
+// single call.
+type Cache[K comparable, V any] struct {
+	mu         sync.Mutex
+	ll         *list.List
+	items      map[K]*list.Element
+	calls      map[K]*call[V]
+	maxEntries int
+	ttl        time.Duration
+	timeout    time.Duration
+	now        func() time.Time
+	stats      Stats
+}
+
+// New creates a cache configured with opts.
+func New[K comparable, V any](opts Options) *Cache[K, V] {
+	now := opts.Now
+	if now == nil {
+		now = time.Now
+	}
+	timeout := opts.LoadTimeout
+	if timeout <= 0 {
+		timeout = defaultLoadTimeout
+	}
+	return &Cache[K, V]{
+		ll:         list.New(),
+		items:      make(map[K]*list.Element),
+		calls:      make(map[K]*call[V]),
+		maxEntries: opts.MaxEntries,
+		ttl:        opts.DefaultTTL,
+		timeout:    timeout,
+		now:        now,
+	}
+}
+
+// Get returns the value stored for key and whether it was found.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.get(key)
+}
+
+// Set stores value for key using the default TTL.
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, c.ttl)
+}
+
+// SetWithTTL stores value for key. A ttl of zero or less keeps the entry until
+// it is evicted or deleted.
+func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(key, value, ttl)
+}
+
+// Delete removes key from the cache and detaches any in-flight load for it,
+// so the next GetOrLoad goes back to the source.
+func (c *Cache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.removeElement(el)
+	}
+	delete(c.calls, key)
+}
+
+// Purge removes every entry from the cache.
+func (c *Cache[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ll.Init()
+	c.items = make(map[K]*list.Element)
+	c.calls = make(map[K]*call[V])
+}
+
+// DeleteExpired removes all expired entries. Expired entries are otherwise
+// dropped lazily when they are read or pushed out by LRU eviction.
+func (c *Cache[K, V]) DeleteExpired() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	for el := c.ll.Back(); el != nil; {
+		prev := el.Prev()
+		if e := el.Value.(*entry[K, V]); e.expired(now) {
+			c.removeElement(el)
+			c.stats.Expirations++
+		}
+		el = prev
+	}
+}
+
+// Len returns the number of entries, including expired ones not yet removed.
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// Stats returns a snapshot of the cache counters.
+func (c *Cache[K, V]) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.stats
+	s.Entries = c.ll.Len()
+	return s
+}
+
+// GetOrLoad returns the cached value for key or calls load to fetch it. Only
+// one load per key runs at a time; concurrent callers wait for its result.
+// Errors are returned to every waiting caller and are never cached.
+//
+// The load keeps the values of ctx but not its cancellation, so a caller
+// giving up, the first one included, only stops its own wait.
+func (c *Cache[K, V]) GetOrLoad(ctx context.Context, key K, load LoadFunc[V]) (V, error) {
+	c.mu.Lock()
+	if v, ok := c.get(key); ok {
+		c.mu.Unlock()
+		return v, nil
+	}
+	cl, ok := c.calls[key]
+	if !ok {
+		cl = newCall[V]()
+		c.calls[key] = cl
+		go c.load(context.WithoutCancel(ctx), key, cl, load)
+	}
+	c.mu.Unlock()
+	return cl.wait(ctx)
+}
+
+// load runs the shared load of key and stores its result, unless the key was
+// invalidated meanwhile: Delete and Purge detach the call from c.calls.
+func (c *Cache[K, V]) load(ctx context.Context, key K, cl *call[V], load LoadFunc[V]) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			var zero V
+			cl.val, cl.err = zero, fmt.Errorf("%w: %v\n%s", errLoadPanicked, r, debug.Stack())
+		}
+		c.mu.Lock()
+		if c.calls[key] == cl {
+			delete(c.calls, key)
+			if cl.err == nil {
+				c.set(key, cl.val, c.ttl)
+			}
+		}
+		c.mu.Unlock()
+		close(cl.done)
+	}()
+
+	cl.val, cl.err = load(ctx)
+}
+
+func (c *Cache[K, V]) get(key K) (V, bool) {
+	el, ok := c.items[key]
+	if !ok {
+		c.stats.Misses++
+		var zero V
+		return zero, false
+	}
+	e := el.Value.(*entry[K, V])
+	if e.expired(c.now()) {
+		c.removeElement(el)
+		c.stats.Expirations++
+		c.stats.Misses++
+		var zero V
+		return zero, false
+	}
+	c.ll.MoveToFront(el)
+	c.stats.Hits++
+	return e.value, true
+}
+
+func (c *Cache[K, V]) set(key K, value V, ttl time.Duration) {
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = c.now().Add(ttl)
+	}
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*entry[K, V])
+		e.value = value
+		e.expiresAt = expiresAt
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&entry[K, V]{key: key, value: value, expiresAt: expiresAt})
+	if c.maxEntries > 0 && c.ll.Len() > c.maxEntries {
+		c.removeElement(c.ll.Back())
+		c.stats.Evictions++
+	}
+}
+
+func (c *Cache[K, V]) removeElement(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.items, el.Value.(*entry[K, V]).key)
+}
+
+func (e *entry[K, V]) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
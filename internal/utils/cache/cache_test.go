@@ -0,0 +1,236 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCache_TTL(t *testing.T) {
+	now := time.Unix(0, 0)
+	c := New[string, int](Options{DefaultTTL: time.Minute, Now: func() time.Time { return now }})
+
+	c.Set("a", 1)
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get() = %v, %v, want 1, true", v, ok)
+	}
+	now = now.Add(time.Minute)
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("expected entry to be expired")
+	}
+	if s := c.Stats(); s.Hits != 1 || s.Misses != 1 || s.Expirations != 1 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+}
+
+func TestCache_LRUEviction(t *testing.T) {
+	c := New[string, int](Options{MaxEntries: 2})
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Get("a")
+	c.Set("c", 3)
+
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("expected least recently used entry to be evicted")
+	}
+	for _, k := range []string{"a", "c"} {
+		if _, ok := c.Get(k); !ok {
+			t.Fatalf("expected %q to be cached", k)
+		}
+	}
+	if s := c.Stats(); s.Evictions != 1 || s.Entries != 2 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+}
+
+func TestCache_GetOrLoadSingleflight(t *testing.T) {
+	c := New[string, int](Options{})
+	var loads int32
+	release := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := c.GetOrLoad(context.Background(), "k", func(ctx context.Context) (int, error) {
+				atomic.AddInt32(&loads, 1)
+				<-release
+				return 42, nil
+			})
+			if err != nil || v != 42 {
+				t.Errorf("GetOrLoad() = %v, %v", v, err)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if loads != 1 {
+		t.Fatalf("loader called %d times, want 1", loads)
+	}
+}
+
+func TestCache_GetOrLoadErrorNotCached(t *testing.T) {
+	c := New[string, int](Options{})
+	errLoad := errors.New("boom")
+
+	if _, err := c.GetOrLoad(context.Background(), "k", func(ctx context.Context) (int, error) {
+		return 0, errLoad
+	}); !errors.Is(err, errLoad) {
+		t.Fatalf("GetOrLoad() error = %v, want %v", err, errLoad)
+	}
+	if c.Len() != 0 {
+		t.Fatal("errors must not be cached")
+	}
+}
+
+func TestCache_DeleteDuringLoad(t *testing.T) {
+	c := New[string, int](Options{})
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		c.GetOrLoad(context.Background(), "k", func(ctx context.Context) (int, error) {
+			close(started)
+			<-release
+			return 1, nil
+		})
+	}()
+	<-started
+	c.Delete("k")
+	close(release)
+	<-done
+
+	if _, ok := c.Get("k"); ok {
+		t.Fatal("value loaded before invalidation must not be cached")
+	}
+}
+
+func TestCache_GetOrLoadPanicReleasesWaiters(t *testing.T) {
+	c := New[string, int](Options{})
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	go c.GetOrLoad(context.Background(), "k", func(ctx context.Context) (int, error) {
+		close(started)
+		<-release
+		panic("boom")
+	})
+	<-started
+
+	waited := make(chan error, 1)
+	go func() {
+		_, err := c.GetOrLoad(context.Background(), "k", func(ctx context.Context) (int, error) {
+			return 2, nil
+		})
+		waited <- err
+	}()
+	// let the second caller join the in-flight load
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	select {
+	case err := <-waited:
+		if !errors.Is(err, errLoadPanicked) {
+			t.Fatalf("waiter error = %v, want errLoadPanicked", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waiter blocked after the load panicked")
+	}
+	if v, err := c.GetOrLoad(context.Background(), "k", func(ctx context.Context) (int, error) {
+		return 3, nil
+	}); err != nil || v != 3 {
+		t.Fatalf("GetOrLoad() after panic = %d, %v, want 3, nil", v, err)
+	}
+}
+
+func TestCache_DeleteOtherKeyDuringLoad(t *testing.T) {
+	c := New[string, int](Options{})
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		c.GetOrLoad(context.Background(), "a", func(ctx context.Context) (int, error) {
+			close(started)
+			<-release
+			return 1, nil
+		})
+	}()
+	<-started
+	c.Delete("b")
+	close(release)
+	<-done
+
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) = %d, %v; invalidating b must not drop the load of a", v, ok)
+	}
+}
+
+func TestCache_GetOrLoadFirstCallerCancelled(t *testing.T) {
+	c := New[string, int](Options{})
+	started := make(chan struct{})
+	release := make(chan struct{})
+	loadErr := make(chan error, 1)
+	load := func(ctx context.Context) (int, error) {
+		close(started)
+		<-release
+		loadErr <- ctx.Err()
+		return 7, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := c.GetOrLoad(ctx, "k", load)
+		first <- err
+	}()
+	<-started
+
+	waited := make(chan int, 1)
+	go func() {
+		v, err := c.GetOrLoad(context.Background(), "k", load)
+		if err != nil {
+			t.Errorf("waiter error = %v", err)
+		}
+		waited <- v
+	}()
+	// let the second caller join the in-flight load
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled caller error = %v, want context.Canceled", err)
+	}
+	close(release)
+
+	if v := <-waited; v != 7 {
+		t.Fatalf("waiter value = %d, want 7", v)
+	}
+	if err := <-loadErr; err != nil {
+		t.Fatalf("load context error = %v, the load must outlive the caller", err)
+	}
+	if v, ok := c.Get("k"); !ok || v != 7 {
+		t.Fatalf("Get() = %d, %v, want 7, true", v, ok)
+	}
+}
+
+func TestCache_GetOrLoadTimeout(t *testing.T) {
+	c := New[string, int](Options{LoadTimeout: 10 * time.Millisecond})
+
+	_, err := c.GetOrLoad(context.Background(), "k", func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GetOrLoad() error = %v, want context.DeadlineExceeded", err)
+	}
+}
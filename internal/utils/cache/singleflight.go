@@ -0,0 +1,31 @@
+package cache
+
+import (
+	"context"
+	"errors"
+)
+
+// errLoadPanicked is returned to the callers waiting on a load that panicked.
+var errLoadPanicked = errors.New("cache: load panicked")
+
+// call is an in-flight or completed GetOrLoad invocation.
+type call[V any] struct {
+	done chan struct{}
+	val  V
+	err  error
+}
+
+func newCall[V any]() *call[V] {
+	return &call[V]{done: make(chan struct{})}
+}
+
+// wait blocks until the call completes or ctx is done.
+func (c *call[V]) wait(ctx context.Context) (V, error) {
+	select {
+	case <-c.done:
+		return c.val, c.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
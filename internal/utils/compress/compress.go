@@ -0,0 +1,195 @@
+// Package compress negotiates and applies response compression.
+package compress
+
+import (
+	"bufio"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+)
+
+type Options struct {
+	// MinSize is the body size below which responses are sent uncompressed,
+	// compressing tiny bodies costs more than it saves.
+	MinSize int
+	// ContentTypes lists the media types worth compressing. An entry ending
+	// in "/" matches the whole type, e.g. "text/".
+	ContentTypes []string
+}
+
+// Middleware compresses responses with the best encoding the client accepts
+// among br, zstd and gzip. Responses are buffered up to MinSize to decide;
+// a handler that flushes before that gets a compressed stream that is
+// flushed through as well.
+func Middleware(opts Options) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			encoding := negotiate(r.Header.Get("Accept-Encoding"), defaultEncodings)
+			if encoding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &responseWriter{ResponseWriter: w, opts: &opts, encoding: encoding}
+			defer cw.close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// responseWriter holds the body back until it knows whether to compress.
+type responseWriter struct {
+	http.ResponseWriter
+	opts     *Options
+	encoding string
+
+	status      int
+	wroteHeader bool
+	buf         []byte
+	// decided is set once the response goes out, enc is set if compressed
+	decided bool
+	enc     encoder
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	// informational responses are sent straight away
+	if status >= 100 && status < 200 {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.decided {
+		w.buf = append(w.buf, p...)
+		if len(w.buf) < w.opts.MinSize {
+			return len(p), nil
+		}
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if w.enc != nil {
+		return w.enc.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush sends what has been written so far. A streaming response is
+// compressed if its type qualifies, whatever its size so far.
+func (w *responseWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.decided {
+		if err := w.decide(true); err != nil {
+			return
+		}
+	}
+	if w.enc != nil {
+		if err := w.enc.Flush(); err != nil {
+			return
+		}
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets websocket upgrades through untouched.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.decided = true
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// decide writes the header and buffered body, compressed or not. Bodies
+// under MinSize are only compressed when large is set.
+func (w *responseWriter) decide(large bool) error {
+	w.decided = true
+	// sniff like net/http would, it cannot once the body is compressed
+	if len(w.buf) > 0 && w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	if large && w.compressible() {
+		h := w.Header()
+		h.Set("Content-Encoding", w.encoding)
+		h.Del("Content-Length")
+		// the strong validator describes the uncompressed representation
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			h.Set("ETag", "W/"+etag)
+		}
+		w.enc = getEncoder(w.encoding, w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.enc != nil {
+		_, err = w.enc.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+func (w *responseWriter) compressible() bool {
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+	switch w.status {
+	case http.StatusNoContent, http.StatusNotModified:
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	for _, allowed := range w.opts.ContentTypes {
+		if mediaType == allowed || strings.HasSuffix(allowed, "/") && strings.HasPrefix(mediaType, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// close finishes the response once the handler has returned.
+func (w *responseWriter) close() {
+	if w.decided && w.enc == nil {
+		return
+	}
+	if !w.decided {
+		if !w.wroteHeader {
+			// nothing written, let net/http send its default response
+			w.decided = true
+			return
+		}
+		// the encoder is released below even if the write failed
+		_ = w.decide(len(w.buf) >= w.opts.MinSize)
+	}
+	if w.enc != nil {
+		w.enc.Close()
+		putEncoder(w.encoding, w.enc)
+		w.enc = nil
+	}
+}
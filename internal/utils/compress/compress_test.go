@@ -0,0 +1,139 @@
+package compress
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", "gzip"},
+		{"gzip, deflate, br", "br"},
+		{"gzip;q=1, br;q=0.5", "gzip"},
+		{"br;q=0, zstd;q=0.1", "zstd"},
+		{"*", "br"},
+		{"*;q=0.5, br;q=0", "zstd"},
+		{"GZIP;Q=0.8", "gzip"},
+		{"gzip;q=bogus", ""},
+	}
+	for _, tt := range tests {
+		if got := negotiate(tt.accept, defaultEncodings); got != tt.want {
+			t.Errorf("negotiate(%q) = %q, want %q", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func decode(t *testing.T, encoding string, body io.Reader) string {
+	t.Helper()
+	var r io.Reader
+	switch encoding {
+	case "":
+		r = body
+	case Brotli:
+		r = brotli.NewReader(body)
+	case Zstd:
+		dec, err := zstd.NewReader(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer dec.Close()
+		r = dec
+	case Gzip:
+		dec, err := gzip.NewReader(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = dec
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("decoding %s: %v", encoding, err)
+	}
+	return string(b)
+}
+
+func TestMiddleware(t *testing.T) {
+	large := strings.Repeat(`{"id":"42","email":"a@example.com"}`, 100)
+	tests := []struct {
+		name        string
+		accept      string
+		contentType string
+		body        string
+		want        string
+	}{
+		{"brotli", "br, gzip", "application/json", large, Brotli},
+		{"zstd", "zstd", "application/json; charset=utf-8", large, Zstd},
+		{"gzip", "gzip", "text/plain", large, Gzip},
+		{"below min size", "gzip", "application/json", `{"id":"42"}`, ""},
+		{"type not allowed", "gzip", "image/png", large, ""},
+		{"not accepted", "", "application/json", large, ""},
+	}
+	mw := Middleware(Options{MinSize: 256, ContentTypes: []string{"application/json", "text/"}})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Header().Set("Content-Length", "123")
+				// written in pieces to cross the threshold mid body
+				io.WriteString(w, tt.body[:len(tt.body)/2])
+				io.WriteString(w, tt.body[len(tt.body)/2:])
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Encoding", tt.accept)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Content-Encoding"); got != tt.want {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.want)
+			}
+			if tt.want != "" && rec.Header().Get("Content-Length") != "" {
+				t.Error("Content-Length of the uncompressed body was kept")
+			}
+			if got := decode(t, tt.want, rec.Body); got != tt.body {
+				t.Errorf("body round trip mismatch, got %d bytes", len(got))
+			}
+			if rec.Header().Get("Vary") != "Accept-Encoding" {
+				t.Errorf("Vary = %q", rec.Header().Get("Vary"))
+			}
+		})
+	}
+}
+
+func TestMiddleware_Streaming(t *testing.T) {
+	mw := Middleware(Options{MinSize: 1024, ContentTypes: []string{"text/"}})
+	srv := httptest.NewServer(mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: first\n\n")
+		w.(http.Flusher).Flush()
+		io.WriteString(w, "data: second\n\n")
+	})))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	// set explicitly so the transport does not decompress for us
+	req.Header.Set("Accept-Encoding", "gzip")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if res.Header.Get("Content-Encoding") != Gzip {
+		t.Fatalf("flushed stream not compressed, Content-Encoding = %q", res.Header.Get("Content-Encoding"))
+	}
+	if got := decode(t, Gzip, res.Body); got != "data: first\n\ndata: second\n\n" {
+		t.Errorf("got %q", got)
+	}
+}
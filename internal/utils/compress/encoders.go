@@ -0,0 +1,68 @@
+package compress
+
+import (
+	"io"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// encoder is a compressing writer that can be reused for another response.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// zstdEncoder adapts zstd.Encoder, whose Reset returns nothing useful here.
+type zstdEncoder struct {
+	*zstd.Encoder
+}
+
+func (e zstdEncoder) Reset(w io.Writer) {
+	e.Encoder.Reset(w)
+}
+
+// Supported encodings in order of preference, used to break q-value ties.
+const (
+	Brotli = "br"
+	Zstd   = "zstd"
+	Gzip   = "gzip"
+)
+
+var defaultEncodings = []string{Brotli, Zstd, Gzip}
+
+// pools holds one pool of encoders per encoding. Levels favour speed, these
+// compress dynamic responses on the request path.
+var pools = map[string]*sync.Pool{
+	Brotli: {New: func() any {
+		return brotli.NewWriterLevel(io.Discard, 4)
+	}},
+	Zstd: {New: func() any {
+		enc, _ := zstd.NewWriter(io.Discard, zstd.WithEncoderLevel(zstd.SpeedDefault), zstd.WithEncoderConcurrency(1))
+		return zstdEncoder{enc}
+	}},
+	Gzip: {New: func() any {
+		enc, _ := gzip.NewWriterLevel(io.Discard, gzip.DefaultCompression)
+		return enc
+	}},
+}
+
+func getEncoder(encoding string, w io.Writer) encoder {
+	enc := pools[encoding].Get().(encoder)
+	enc.Reset(w)
+	return enc
+}
+
+func putEncoder(encoding string, enc encoder) {
+	enc.Reset(io.Discard)
+	pools[encoding].Put(enc)
+}
+
+var (
+	_ encoder = (*brotli.Writer)(nil)
+	_ encoder = zstdEncoder{}
+	_ encoder = (*gzip.Writer)(nil)
+)
@@ -0,0 +1,60 @@
+package compress
+
+import (
+	"strconv"
+	"strings"
+)
+
+// negotiate picks the encoding to use for an Accept-Encoding header, or ""
+// for identity. The client's highest q-value wins; on a tie the order of
+// supported, which is the server's preference, decides.
+func negotiate(acceptEncoding string, supported []string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+	weights := map[string]float64{}
+	wildcard, hasWildcard := 0.0, false
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, q := parseCoding(part)
+		if name == "" {
+			continue
+		}
+		if name == "*" {
+			wildcard, hasWildcard = q, true
+			continue
+		}
+		weights[name] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, enc := range supported {
+		q, ok := weights[enc]
+		if !ok && hasWildcard {
+			q, ok = wildcard, true
+		}
+		if ok && q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+	return best
+}
+
+// parseCoding parses one "name;q=0.5" element of Accept-Encoding. A missing
+// or malformed q-value counts as 1 and 0 respectively.
+func parseCoding(s string) (string, float64) {
+	name, params, _ := strings.Cut(s, ";")
+	name = strings.ToLower(strings.TrimSpace(name))
+	q := 1.0
+	for _, param := range strings.Split(params, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok || strings.ToLower(strings.TrimSpace(key)) != "q" {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || v < 0 || v > 1 {
+			v = 0
+		}
+		q = v
+	}
+	return name, q
+}
@@ -0,0 +1,111 @@
+// Package csrf protects cookie authenticated requests against cross site
+// request forgery with the double submit pattern: a random token is set in a
+// cookie readable by the page's scripts, which echo it in the X-CSRF-Token
+// header of every state changing request. Other origins can make the browser
+// send the cookie but can neither read it nor set the header.
+package csrf
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"time"
+
+	"cleanarch/boiler/internal/utils/apperr"
+	"cleanarch/boiler/internal/utils/problem"
+)
+
+const (
+	CookieName = "csrf_token"
+	HeaderName = "X-CSRF-Token"
+)
+
+var ErrInvalidToken = apperr.New(apperr.Forbidden, "csrf_token_invalid", "missing or invalid CSRF token")
+
+// CookieOptions mirror the options of the authentication cookies the token
+// protects.
+type CookieOptions struct {
+	Secure   bool
+	Domain   string
+	SameSite http.SameSite
+	MaxAge   time.Duration
+}
+
+// NewToken returns a random token.
+func NewToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Issue sets a new token cookie on w and returns the token.
+func Issue(w http.ResponseWriter, opts CookieOptions) (string, error) {
+	token, err := NewToken()
+	if err != nil {
+		return "", err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:   CookieName,
+		Value:  token,
+		Path:   "/",
+		Domain: opts.Domain,
+		MaxAge: int(opts.MaxAge.Seconds()),
+		// scripts must read it to echo it in the header
+		HttpOnly: false,
+		Secure:   opts.Secure,
+		SameSite: opts.SameSite,
+	})
+	return token, nil
+}
+
+// Verify checks that the header of r repeats its token cookie.
+func Verify(r *http.Request) error {
+	cookie, err := r.Cookie(CookieName)
+	if err != nil || cookie.Value == "" {
+		return ErrInvalidToken.WithMessage("CSRF cookie is missing, fetch one from /auth/csrf")
+	}
+	header := r.Header.Get(HeaderName)
+	if header == "" {
+		return ErrInvalidToken.WithMessage(HeaderName + " header is missing")
+	}
+	if subtle.ConstantTimeCompare([]byte(header), []byte(cookie.Value)) != 1 {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+// Protect verifies the token of state changing requests authenticated by
+// the authCookie cookie. Safe methods, requests with an Authorization header
+// and requests without the cookie are passed through: browsers never attach
+// an Authorization header on their own, so bearer clients are not exposed.
+func Protect(authCookie string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if safe(r.Method) || r.Header.Get("Authorization") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if _, err := r.Cookie(authCookie); err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if err := Verify(r); err != nil {
+				problem.Write(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// safe reports whether method must not change state (RFC 9110, 9.2.1).
+func safe(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
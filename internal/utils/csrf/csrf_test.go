@@ -0,0 +1,60 @@
+package csrf
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProtect(t *testing.T) {
+	h := Protect("access_token")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	w := httptest.NewRecorder()
+	token, err := Issue(w, CookieOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	csrfCookie := w.Result().Cookies()[0]
+	if csrfCookie.Name != CookieName || csrfCookie.HttpOnly {
+		t.Fatalf("cookie = %+v", csrfCookie)
+	}
+	session := &http.Cookie{Name: "access_token", Value: "jwt"}
+
+	tests := []struct {
+		name   string
+		method string
+		setup  func(r *http.Request)
+		want   int
+	}{
+		{"safe method", http.MethodGet, func(r *http.Request) { r.AddCookie(session) }, http.StatusOK},
+		{"no session cookie", http.MethodPatch, func(r *http.Request) {}, http.StatusOK},
+		{"bearer client", http.MethodPatch, func(r *http.Request) {
+			r.AddCookie(session)
+			r.Header.Set("Authorization", "Bearer jwt")
+		}, http.StatusOK},
+		{"missing token", http.MethodPatch, func(r *http.Request) { r.AddCookie(session) }, http.StatusForbidden},
+		{"header only", http.MethodPatch, func(r *http.Request) {
+			r.AddCookie(session)
+			r.Header.Set(HeaderName, token)
+		}, http.StatusForbidden},
+		{"mismatch", http.MethodPost, func(r *http.Request) {
+			r.AddCookie(session)
+			r.AddCookie(csrfCookie)
+			r.Header.Set(HeaderName, token+"x")
+		}, http.StatusForbidden},
+		{"match", http.MethodDelete, func(r *http.Request) {
+			r.AddCookie(session)
+			r.AddCookie(csrfCookie)
+			r.Header.Set(HeaderName, token)
+		}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, "/me", nil)
+		tt.setup(r)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+}
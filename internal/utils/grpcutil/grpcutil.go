@@ -0,0 +1,210 @@
+// Package grpcutil hosts gRPC services next to the HTTP API: a server with
+// the interceptors shared by every service and the mapping of apperr errors
+// to gRPC status codes. Services are generated from their .proto files.
+package grpcutil
+
+import (
+	"context"
+	"errors"
+	"net"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+
+	"cleanarch/boiler/internal/utils/apperr"
+	"cleanarch/boiler/internal/utils/logger"
+	"cleanarch/boiler/internal/utils/requestlog"
+)
+
+// ErrorDomain is the domain of the ErrorInfo detail attached to errors.
+const ErrorDomain = "cleanarch"
+
+// MetadataRequestID carries the request id in both directions.
+var MetadataRequestID = strings.ToLower(requestlog.HeaderRequestID)
+
+// Authenticator is implemented by services whose methods require a caller.
+// Authenticate returns the context the method runs with, or an error to
+// reject the call; it decides itself which methods are public.
+type Authenticator interface {
+	Authenticate(ctx context.Context, fullMethod string) (context.Context, error)
+}
+
+// Options configures NewServer.
+type Options struct {
+	// Credentials, when set, serve TLS.
+	Credentials credentials.TransportCredentials
+}
+
+// NewServer creates a server whose unary calls are logged, authenticated
+// through the service's Authenticator, recovered from panics and answered
+// with status codes mapped from apperr kinds.
+func NewServer(l logger.Interface, opts Options) *grpc.Server {
+	serverOpts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(Logging(l), Errors(l), Recovery(l), Auth()),
+	}
+	if opts.Credentials != nil {
+		serverOpts = append(serverOpts, grpc.Creds(opts.Credentials))
+	}
+	return grpc.NewServer(serverOpts...)
+}
+
+// Logging assigns the request id, from the x-request-id metadata when the
+// caller sent one, and logs one record per call like requestlog does for
+// HTTP requests.
+func Logging(l logger.Interface) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		var id string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(MetadataRequestID); len(values) > 0 {
+				id = values[0]
+			}
+		}
+		ctx, id = requestlog.NewContext(ctx, id)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(MetadataRequestID, id))
+
+		resp, err := handler(ctx, req)
+
+		code := status.Code(err)
+		args := []interface{}{
+			"request_id", id,
+			"method", info.FullMethod,
+			"code", code.String(),
+			"latency_ms", float64(time.Since(start).Microseconds()) / 1000,
+		}
+		if userID, tenantID := requestlog.User(ctx); userID != "" {
+			args = append(args, "user_id", userID, "tenant_id", tenantID)
+		}
+		switch code {
+		case codes.OK:
+			l.Info("rpc", args...)
+		case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+			l.Error("rpc", args...)
+		default:
+			l.Warn("rpc", args...)
+		}
+		return resp, err
+	}
+}
+
+// Errors converts the errors returned by methods to statuses. Internal
+// errors are logged with their cause and reach the caller without it.
+func Errors(l logger.Interface) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+		st := Status(err)
+		if st.Code() == codes.Internal {
+			l.WithContext(ctx).Error("rpc failed", "method", info.FullMethod, "error", err)
+		}
+		return resp, st.Err()
+	}
+}
+
+// Recovery turns a panic in a method into an internal error.
+func Recovery(l logger.Interface) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				l.WithContext(ctx).Error("rpc panicked", "method", info.FullMethod, "panic", p, "stack", string(debug.Stack()))
+				err = errors.New("panic in rpc handler")
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// Auth lets services implementing Authenticator vet every call.
+func Auth() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if a, ok := info.Server.(Authenticator); ok {
+			var err error
+			if ctx, err = a.Authenticate(ctx, info.FullMethod); err != nil {
+				return nil, err
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+// BearerToken returns the token of the `authorization: Bearer <token>`
+// metadata.
+func BearerToken(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+	for _, value := range md.Get("authorization") {
+		scheme, token, found := strings.Cut(value, " ")
+		if found && strings.EqualFold(scheme, "bearer") && token != "" {
+			return token, true
+		}
+	}
+	return "", false
+}
+
+// PeerHost returns the address of the caller without its port.
+func PeerHost(ctx context.Context) (string, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "", false
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		host = p.Addr.String()
+	}
+	return host, host != ""
+}
+
+var codesByKind = map[apperr.Kind]codes.Code{
+	apperr.NotFound:             codes.NotFound,
+	apperr.Conflict:             codes.AlreadyExists,
+	apperr.Unauthorized:         codes.Unauthenticated,
+	apperr.Forbidden:            codes.PermissionDenied,
+	apperr.Validation:           codes.InvalidArgument,
+	apperr.RateLimited:          codes.ResourceExhausted,
+	apperr.PreconditionFailed:   codes.FailedPrecondition,
+	apperr.PreconditionRequired: codes.FailedPrecondition,
+	apperr.UnsupportedMediaType: codes.InvalidArgument,
+	apperr.TooLarge:             codes.InvalidArgument,
+	apperr.NotAcceptable:        codes.InvalidArgument,
+	apperr.Unprocessable:        codes.FailedPrecondition,
+}
+
+// Status maps err to a gRPC status. The apperr code is attached as the
+// reason of an ErrorInfo detail and invalid fields as a BadRequest detail.
+// Errors already carrying a status are kept, anything else is Internal.
+func Status(err error) *status.Status {
+	if st, ok := status.FromError(err); ok {
+		return st
+	}
+	e, ok := apperr.As(err)
+	code, known := codesByKind[apperr.KindOf(err)]
+	if !ok || !known {
+		return status.New(codes.Internal, "internal error")
+	}
+	st := status.New(code, e.Message)
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: e.Code, Domain: ErrorDomain}}
+	if len(e.Fields) > 0 {
+		br := &errdetails.BadRequest{}
+		for _, f := range e.Fields {
+			br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{Field: f.Field, Description: f.Message})
+		}
+		details = append(details, br)
+	}
+	if withDetails, err := st.WithDetails(details...); err == nil {
+		return withDetails
+	}
+	return st
+}
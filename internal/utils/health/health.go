@@ -0,0 +1,99 @@
+// Package health runs readiness checks and reports their outcome.
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout bounds a check that does not set its own timeout.
+const DefaultTimeout = 2 * time.Second
+
+type Status string
+
+const (
+	StatusUp   Status = "up"
+	StatusDown Status = "down"
+)
+
+// Check is a single named readiness probe.
+type Check struct {
+	Name    string
+	Timeout time.Duration
+	Func    func(ctx context.Context) error
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	Name      string  `json:"name"`
+	Status    Status  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report aggregates check results. It is up only if every check is up.
+type Report struct {
+	Status Status   `json:"status"`
+	Checks []Result `json:"checks,omitempty"`
+}
+
+// Run executes all checks concurrently, each with its own timeout, and
+// returns the results in the order of checks.
+func Run(ctx context.Context, checks []Check) Report {
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusUp, Checks: results}
+	for _, r := range results {
+		if r.Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+func run(ctx context.Context, check Check) Result {
+	timeout := check.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	result := Result{Name: check.Name, Status: StatusUp}
+
+	// the check runs on its own goroutine so that one ignoring ctx cannot
+	// hold the probe past its timeout
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				done <- fmt.Errorf("check panicked: %v", rec)
+			}
+		}()
+		done <- check.Func(ctx)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			result.Status = StatusDown
+			result.Error = err.Error()
+		}
+	case <-ctx.Done():
+		result.Status = StatusDown
+		result.Error = ctx.Err().Error()
+	}
+	result.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+	return result
+}
@@ -0,0 +1,33 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	report := Run(context.Background(), []Check{
+		{Name: "ok", Func: func(ctx context.Context) error { return nil }},
+		{Name: "failing", Func: func(ctx context.Context) error { return errors.New("boom") }},
+		{Name: "slow", Timeout: 10 * time.Millisecond, Func: func(ctx context.Context) error {
+			time.Sleep(time.Second)
+			return nil
+		}},
+		{Name: "panicking", Func: func(ctx context.Context) error { panic("oops") }},
+	})
+
+	if report.Status != StatusDown {
+		t.Fatalf("report status = %s, want down", report.Status)
+	}
+	want := []Status{StatusUp, StatusDown, StatusDown, StatusDown}
+	for i, r := range report.Checks {
+		if r.Status != want[i] {
+			t.Errorf("check %s status = %s, want %s (%s)", r.Name, r.Status, want[i], r.Error)
+		}
+	}
+	if slow := report.Checks[2]; slow.LatencyMs >= 500 {
+		t.Errorf("slow check was not cut off by its timeout, latency %vms", slow.LatencyMs)
+	}
+}
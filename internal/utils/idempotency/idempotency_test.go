@@ -0,0 +1,109 @@
+package idempotency
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cleanarch/boiler/internal/utils/logger"
+)
+
+func TestMiddleware(t *testing.T) {
+	now := time.Unix(0, 0)
+	store := NewMemoryStore(func() time.Time { return now })
+	guard := NewGuard(logger.NewLogger("error"), store, time.Hour)
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	h := guard.Middleware(func(r *http.Request) string { return r.Header.Get("X-User") })(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		if r.URL.Path == "/slow" {
+			<-release
+		}
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: fmt.Sprint(n)})
+		}
+		w.Header().Set("Location", fmt.Sprint("/users/", n))
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, "call %d", n)
+	}))
+
+	send := func(path, key, user, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		if key != "" {
+			r.Header.Set(HeaderName, key)
+		}
+		r.Header.Set("X-User", user)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	first := send("/signup", "k1", "", `{"email":"a"}`)
+	retry := send("/signup", "k1", "", `{"email":"a"}`)
+	if first.Code != http.StatusCreated || retry.Code != http.StatusCreated || retry.Body.String() != "call 1" {
+		t.Fatalf("first = %d %q, retry = %d %q", first.Code, first.Body, retry.Code, retry.Body)
+	}
+	if retry.Header().Get(ReplayedHeader) != "true" || retry.Header().Get("Location") != "/users/1" {
+		t.Errorf("replayed headers = %v", retry.Header())
+	}
+
+	if w := send("/signup", "k1", "", `{"email":"b"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("reused key: status = %d", w.Code)
+	}
+	// the same key on another route or from another principal is unrelated
+	if w := send("/login", "k1", "", `{"email":"a"}`); w.Body.String() != "call 2" {
+		t.Errorf("other route: body = %q", w.Body)
+	}
+	if w := send("/signup", "k1", "user-2", `{"email":"a"}`); w.Body.String() != "call 3" {
+		t.Errorf("other principal: body = %q", w.Body)
+	}
+	if w := send("/signup", "", "", `{"email":"a"}`); w.Body.String() != "call 4" || w.Header().Get(ReplayedHeader) != "" {
+		t.Errorf("no key: body = %q", w.Body)
+	}
+
+	// server errors are not kept
+	send("/fail", "k2", "", "")
+	if w := send("/fail", "k2", "", ""); w.Header().Get(ReplayedHeader) != "" || calls.Load() != 6 {
+		t.Errorf("server error was replayed, calls = %d", calls.Load())
+	}
+
+	// a duplicate of a running request is rejected
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- send("/slow", "k3", "", "") }()
+	for calls.Load() < 7 {
+		time.Sleep(time.Millisecond)
+	}
+	if w := send("/slow", "k3", "", ""); w.Code != http.StatusConflict {
+		t.Errorf("concurrent duplicate: status = %d", w.Code)
+	}
+	close(release)
+	if w := <-done; w.Code != http.StatusCreated {
+		t.Errorf("slow request: status = %d", w.Code)
+	}
+
+	// records expire
+	now = now.Add(2 * time.Hour)
+	if w := send("/signup", "k1", "", `{"email":"a"}`); w.Header().Get(ReplayedHeader) != "" {
+		t.Errorf("expired record was replayed")
+	}
+
+	if w := send("/signup", strings.Repeat("k", MaxKeyLength+1), "", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("long key: status = %d", w.Code)
+	}
+
+	// responses setting cookies, such as credentials, are never kept
+	send("/login", "k4", "", `{"email":"a"}`)
+	before := calls.Load()
+	if w := send("/login", "k4", "", `{"email":"a"}`); w.Header().Get(ReplayedHeader) != "" || calls.Load() != before+1 {
+		t.Errorf("response setting a cookie was replayed")
+	}
+}
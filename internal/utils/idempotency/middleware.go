@@ -0,0 +1,185 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"cleanarch/boiler/internal/utils/apperr"
+	"cleanarch/boiler/internal/utils/bind"
+	"cleanarch/boiler/internal/utils/logger"
+	"cleanarch/boiler/internal/utils/problem"
+)
+
+const (
+	HeaderName = "Idempotency-Key"
+	// ReplayedHeader marks replayed responses.
+	ReplayedHeader = "Idempotent-Replayed"
+	// MaxKeyLength bounds the keys clients may send.
+	MaxKeyLength = 255
+)
+
+// maxStoredBytes bounds the body of a stored response, larger responses
+// are not stored and retries run again.
+const maxStoredBytes = 1 << 20
+
+var ErrInvalidKey = apperr.New(apperr.Validation, "invalid_idempotency_key", "Idempotency-Key must be between 1 and 255 characters long")
+var ErrInProgress = apperr.New(apperr.Conflict, "idempotency_key_in_use", "a request with this Idempotency-Key is still being processed")
+var ErrKeyReused = apperr.New(apperr.Unprocessable, "idempotency_key_reused", "Idempotency-Key was already used for a different request")
+
+// PrincipalFunc returns who sends a request, e.g. the user id, so that the
+// keys of different callers never collide. Anonymous requests return "".
+type PrincipalFunc func(r *http.Request) string
+
+// Guard applies idempotency keys to routes with records from a Store.
+type Guard struct {
+	l     logger.Interface
+	store Store
+	ttl   time.Duration
+}
+
+// NewGuard creates a Guard keeping responses for ttl.
+func NewGuard(l logger.Interface, store Store, ttl time.Duration) *Guard {
+	return &Guard{l: l, store: store, ttl: ttl}
+}
+
+// Middleware makes POST requests carrying an Idempotency-Key idempotent.
+// Records are keyed by key, method, path and principal. A retry gets the
+// stored status, headers and body with Idempotent-Replayed: true; a duplicate
+// arriving while the first request runs gets 409, and a key reused with a
+// different body 422. Server errors and responses setting cookies are not
+// stored, so they run again on retry.
+// If the store fails the request runs as if it had no key.
+func (g *Guard) Middleware(principal PrincipalFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(HeaderName)
+			if r.Method != http.MethodPost || key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > MaxKeyLength {
+				problem.Write(w, r, ErrInvalidKey)
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, bind.MaxBytes))
+			if err != nil {
+				var maxErr *http.MaxBytesError
+				if errors.As(err, &maxErr) {
+					err = bind.ErrBodyTooLarge.Wrap(err)
+				}
+				problem.Write(w, r, err)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			storeKey := principal(r) + "\x00" + r.Method + " " + r.URL.Path + "\x00" + key
+			sum := sha256.Sum256(body)
+			fingerprint := hex.EncodeToString(sum[:])
+			rec, err := g.store.Begin(r.Context(), storeKey, fingerprint, g.ttl)
+			if err != nil {
+				g.l.WithContext(r.Context()).Error("idempotency store failed, running request", "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			switch {
+			case rec == nil:
+				g.run(w, r, next, storeKey)
+			case rec.Fingerprint != fingerprint:
+				problem.Write(w, r, ErrKeyReused)
+			case rec.Response == nil:
+				w.Header().Set("Retry-After", "1")
+				problem.Write(w, r, ErrInProgress)
+			default:
+				replay(w, rec.Response)
+			}
+		})
+	}
+}
+
+// run serves the first request with a key and stores its response.
+func (g *Guard) run(w http.ResponseWriter, r *http.Request, next http.Handler, storeKey string) {
+	rec := &recorder{ResponseWriter: w}
+	completed := false
+	defer func() {
+		if completed {
+			return
+		}
+		// a panic or a response not worth keeping frees the key for retries
+		if err := g.store.Release(r.Context(), storeKey); err != nil {
+			g.l.WithContext(r.Context()).Error("unable to release idempotency key", "error", err)
+		}
+	}()
+
+	next.ServeHTTP(rec, r)
+
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	// responses setting cookies carry credentials or session state that
+	// must not be handed to whoever resends the key
+	if rec.status >= http.StatusInternalServerError || rec.overflow || len(rec.header.Values("Set-Cookie")) > 0 {
+		return
+	}
+	resp := &Response{Status: rec.status, Header: rec.header, Body: rec.body.Bytes()}
+	if err := g.store.Complete(r.Context(), storeKey, resp, g.ttl); err != nil {
+		g.l.WithContext(r.Context()).Error("unable to store idempotent response", "error", err)
+		return
+	}
+	completed = true
+}
+
+// replay writes a stored response. Headers already set by the middlewares
+// serving the retry, such as its request id, are kept.
+func replay(w http.ResponseWriter, resp *Response) {
+	h := w.Header()
+	for name, values := range resp.Header {
+		if _, ok := h[name]; !ok {
+			h[name] = values
+		}
+	}
+	h.Set(ReplayedHeader, "true")
+	w.WriteHeader(resp.Status)
+	w.Write(resp.Body)
+}
+
+// recorder passes the response through while keeping a copy of it.
+type recorder struct {
+	http.ResponseWriter
+	status   int
+	header   http.Header
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+		r.header = r.ResponseWriter.Header().Clone()
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.WriteHeader(http.StatusOK)
+	}
+	if !r.overflow {
+		if r.body.Len()+len(b) > maxStoredBytes {
+			r.overflow = true
+			r.body.Reset()
+		} else {
+			r.body.Write(b)
+		}
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
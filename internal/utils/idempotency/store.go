@@ -0,0 +1,37 @@
+// Package idempotency makes retried requests safe. A client sends an
+// Idempotency-Key header with an unsafe request; the first response for the
+// key is stored and replayed to retries instead of running the handler again.
+// Records live in a Store so that instances can share them.
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Response is a stored response.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Record is what a Store keeps per key. Fingerprint identifies the request
+// the key was first used with, Response is nil while it runs.
+type Record struct {
+	Fingerprint string
+	Response    *Response
+}
+
+// Store holds the records.
+//
+// Begin reserves key for ttl and returns nil, unless a record exists, in
+// which case it returns that record. Complete stores the response of a
+// reserved key for ttl, Release drops the reservation so that the request can
+// be retried.
+type Store interface {
+	Begin(ctx context.Context, key, fingerprint string, ttl time.Duration) (*Record, error)
+	Complete(ctx context.Context, key string, resp *Response, ttl time.Duration) error
+	Release(ctx context.Context, key string) error
+}
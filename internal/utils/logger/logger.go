@@ -3,6 +3,7 @@ package logger
 // refered from https://github.com/evrone/go-clean-template/blob/master/pkg/logger/logger.go
 // got from https://www.reddit.com/r/golang/comments/17yu8n4/best_practice_passing_around_central_logger/?share_id=7cXrYdVQC_Jp-xEsmxmsh&utm_content=2&utm_medium=android_app&utm_name=androidcss&utm_source=share&utm_term=1
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"os"
@@ -16,6 +17,22 @@ type Interface interface {
 	Warn(message string, args ...interface{})
 	Error(message interface{}, args ...interface{})
 	Fatal(message interface{}, args ...interface{})
+	// With returns a logger adding args to every record.
+	With(args ...interface{}) Interface
+	// WithContext returns a logger adding the fields stored in ctx with
+	// NewContext, such as the request id.
+	WithContext(ctx context.Context) Interface
+}
+
+type fieldsKey struct{}
+
+// NewContext returns a copy of ctx carrying args, on top of those already in
+// ctx, for the loggers obtained through WithContext.
+func NewContext(ctx context.Context, args ...interface{}) context.Context {
+	prev, _ := ctx.Value(fieldsKey{}).([]interface{})
+	fields := make([]interface{}, 0, len(prev)+len(args))
+	fields = append(append(fields, prev...), args...)
+	return context.WithValue(ctx, fieldsKey{}, fields)
 }
 
 // Logger -.
@@ -57,50 +74,59 @@ func NewLogger(level string) *Logger {
 
 // Debug -.
 func (l *Logger) Debug(message interface{}, args ...interface{}) {
-	l.msg("debug", message, args...)
+	l.msg(slog.LevelDebug, message, args...)
 }
 
 // Info -.
 func (l *Logger) Info(message string, args ...interface{}) {
-	l.log(message, args...)
+	l.log(slog.LevelInfo, message, args...)
 }
 
 // Warn -.
 func (l *Logger) Warn(message string, args ...interface{}) {
-	l.log(message, args...)
+	l.log(slog.LevelWarn, message, args...)
 }
 
 // Error -.
 func (l *Logger) Error(message interface{}, args ...interface{}) {
-	if l.level == slog.LevelDebug {
-		l.Debug(message, args...)
-	}
-
-	l.msg("error", message, args...)
+	l.msg(slog.LevelError, message, args...)
 }
 
 // Fatal -.
 func (l *Logger) Fatal(message interface{}, args ...interface{}) {
-	l.msg("fatal", message, args...)
+	l.msg(slog.LevelError, message, args...)
 
 	os.Exit(1)
 }
 
-func (l *Logger) log(message string, args ...interface{}) {
-	if len(args) == 0 {
-		l.logger.Info(message)
-	} else {
-		l.logger.Info(message, args...)
+// With -.
+func (l *Logger) With(args ...interface{}) Interface {
+	return &Logger{
+		logger: l.logger.With(args...),
+		level:  l.level,
 	}
 }
 
-func (l *Logger) msg(level string, message interface{}, args ...interface{}) {
+// WithContext -.
+func (l *Logger) WithContext(ctx context.Context) Interface {
+	fields, _ := ctx.Value(fieldsKey{}).([]interface{})
+	if len(fields) == 0 {
+		return l
+	}
+	return l.With(fields...)
+}
+
+func (l *Logger) log(level slog.Level, message string, args ...interface{}) {
+	l.logger.Log(context.Background(), level, message, args...)
+}
+
+func (l *Logger) msg(level slog.Level, message interface{}, args ...interface{}) {
 	switch msg := message.(type) {
 	case error:
-		l.log(msg.Error(), args...)
+		l.log(level, msg.Error(), args...)
 	case string:
-		l.log(msg, args...)
+		l.log(level, msg, args...)
 	default:
-		l.log(fmt.Sprintf("%s message %v has unknown type %v", level, message, msg), args...)
+		l.log(level, fmt.Sprintf("%s message %v has unknown type %v", level, message, msg), args...)
 	}
 }
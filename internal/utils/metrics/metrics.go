@@ -0,0 +1,34 @@
+// Package metrics exposes application metrics in the Prometheus text format.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Namespace prefixes every metric of the application.
+const Namespace = "cleanarch"
+
+// Registry holds the collectors served on /metrics. Plugins register their
+// own collectors on it through the prometheus.Registerer interface.
+type Registry struct {
+	*prometheus.Registry
+}
+
+// NewRegistry creates a registry with the Go runtime and process collectors.
+func NewRegistry() *Registry {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return &Registry{Registry: reg}
+}
+
+// Handler serves the registered metrics.
+func (r *Registry) Handler() http.Handler {
+	return promhttp.HandlerFor(r.Registry, promhttp.HandlerOpts{Registry: r.Registry})
+}
@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// Mongo records the latency of every command sent to Mongo, labelled by
+// command and collection, which is what repository operations boil down to.
+type Mongo struct {
+	duration *prometheus.HistogramVec
+	// inflight maps request ids to the labels of started commands, the
+	// finished events do not carry the collection.
+	inflight sync.Map
+}
+
+type mongoCommand struct {
+	name       string
+	collection string
+}
+
+func NewMongo(reg prometheus.Registerer) *Mongo {
+	m := &Mongo{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Subsystem: "mongo",
+			Name:      "command_duration_seconds",
+			Help:      "Mongo command latency by command, collection and outcome.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"command", "collection", "status"}),
+	}
+	reg.MustRegister(m.duration)
+	return m
+}
+
+// Monitor returns the driver command monitor feeding the histogram.
+func (m *Mongo) Monitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			cmd := mongoCommand{name: e.CommandName}
+			// the value of the first element is the collection for CRUD commands
+			if elem, err := e.Command.IndexErr(0); err == nil {
+				if coll, ok := elem.Value().StringValueOK(); ok {
+					cmd.collection = coll
+				}
+			}
+			m.inflight.Store(e.RequestID, cmd)
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			m.observe(e.RequestID, e.Duration, "ok")
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			m.observe(e.RequestID, e.Duration, "error")
+		},
+	}
+}
+
+func (m *Mongo) observe(requestID int64, d time.Duration, status string) {
+	v, ok := m.inflight.LoadAndDelete(requestID)
+	if !ok {
+		return
+	}
+	cmd := v.(mongoCommand)
+	m.duration.WithLabelValues(cmd.name, cmd.collection, status).Observe(d.Seconds())
+}
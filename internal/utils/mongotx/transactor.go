@@ -0,0 +1,86 @@
+package mongotx
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"cleanarch/boiler/internal/utils/logger"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Transactor runs a unit of work inside a Mongo transaction. Repositories
+// join the transaction simply by using the context handed to the callback.
+//
+// Transactions need a replica set or a sharded cluster. Against a server
+// identified as standalone, as commonly used in development, the work runs
+// without a transaction and a warning is logged once. Until the topology is
+// known units of work fail rather than silently lose their atomicity.
+type Transactor struct {
+	l  logger.Interface
+	db *mongo.Database
+
+	mu        sync.Mutex
+	detected  bool
+	supported bool
+}
+
+func NewTransactor(l logger.Interface, db *mongo.Database) *Transactor {
+	return &Transactor{
+		l:  l,
+		db: db,
+	}
+}
+
+// WithinTransaction calls fn in a transaction that commits when fn returns nil
+// and aborts otherwise. Transient errors are retried by the driver, so fn may
+// run more than once.
+func (t *Transactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	supported, err := t.transactionsSupported(ctx)
+	if err != nil {
+		return err
+	}
+	if !supported {
+		return fn(ctx)
+	}
+	// nested calls reuse the outer transaction
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+
+	session, err := t.db.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	})
+	return err
+}
+
+// transactionsSupported asks the server whether it is part of a replica set
+// or a sharded cluster. Failed probes are not remembered, the next unit of
+// work tries again.
+func (t *Transactor) transactionsSupported(ctx context.Context) (bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.detected {
+		return t.supported, nil
+	}
+
+	var hello bson.M
+	if err := t.db.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return false, fmt.Errorf("detect mongo topology: %w", err)
+	}
+	_, replicaSet := hello["setName"]
+	t.supported = replicaSet || hello["msg"] == "isdbgrid"
+	t.detected = true
+	if !t.supported {
+		t.l.WithContext(ctx).Warn("mongo is running standalone, units of work are not transactional")
+	}
+	return t.supported, nil
+}
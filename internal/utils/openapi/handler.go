@@ -0,0 +1,32 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+)
+
+//go:embed ui/index.html
+var uiPage []byte
+
+// Handler serves the document as JSON.
+func (d *Document) Handler() (http.Handler, error) {
+	body, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}), nil
+}
+
+// UI serves a page rendering the document found at openapi.json next to it,
+// so it is mounted as a sibling of the document, e.g. /docs and
+// /openapi.json. The page has no external dependencies.
+func UI() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(uiPage)
+	})
+}
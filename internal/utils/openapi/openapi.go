@@ -0,0 +1,277 @@
+// Package openapi builds an OpenAPI 3.1 document from the routes of a chi
+// router. Handlers are documented where they are registered by wrapping them
+// with Handle; Build walks the router and collects the operations under
+// their full paths, so mount prefixes and API versions are always accurate.
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"cleanarch/boiler/internal/utils/problem"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const Version = "3.1.0"
+
+// Operation documents one route.
+type Operation struct {
+	Summary     string
+	Description string
+	Tags        []string
+	// Security names the schemes accepted by the route, any one of them
+	// suffices. Empty means the route is public.
+	Security   []string
+	Parameters []Parameter
+	// Request is a value whose type describes the request body,
+	// RequestContentType defaults to application/json.
+	Request            interface{}
+	RequestContentType string
+	Responses          map[int]Response
+	Deprecated         bool
+}
+
+// Parameter is a query, header or cookie parameter. Path parameters are
+// taken from the route pattern.
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+// Response documents one status of an operation. Body is a value whose type
+// describes the response body, ContentType defaults to application/json.
+type Response struct {
+	Description string
+	Body        interface{}
+	ContentType string
+}
+
+// ProblemResponse documents an error answered with application/problem+json.
+func ProblemResponse(description string) Response {
+	return Response{Description: description, Body: problem.Problem{}, ContentType: problem.ContentType}
+}
+
+// Handle returns h carrying op. Routes registered with it are included in
+// the document built by Build.
+func Handle(h http.HandlerFunc, op Operation) http.Handler {
+	return &documented{HandlerFunc: h, op: op}
+}
+
+type documented struct {
+	http.HandlerFunc
+	op Operation
+}
+
+// Info describes the API.
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// SecurityScheme is an OpenAPI security scheme object.
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Name         string `json:"name,omitempty"`
+	In           string `json:"in,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// Document is the OpenAPI document.
+type Document struct {
+	OpenAPI    string                        `json:"openapi"`
+	Info       Info                          `json:"info"`
+	Paths      map[string]map[string]*pathOp `json:"paths"`
+	Components *components                   `json:"components,omitempty"`
+}
+
+type components struct {
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type pathOp struct {
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	OperationID string                `json:"operationId"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *requestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+}
+
+type requestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type response struct {
+	Description string               `json:"description"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type mediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Route is a route as registered on the router.
+type Route struct {
+	Method string
+	Path   string
+	op     *Operation
+}
+
+// Routes lists the routes of r with their full paths. Catch-all routes such
+// as mounted non-chi handlers are left out.
+func Routes(r chi.Routes) ([]Route, error) {
+	var routes []Route
+	err := chi.Walk(r, func(method, route string, handler http.Handler, _ ...func(http.Handler) http.Handler) error {
+		path := normalize(route)
+		if strings.HasSuffix(path, "*") {
+			return nil
+		}
+		rt := Route{Method: method, Path: path}
+		if d, ok := handler.(*documented); ok {
+			rt.op = &d.op
+		}
+		routes = append(routes, rt)
+		return nil
+	})
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes, err
+}
+
+// Undocumented returns the routes of r registered without Handle, as
+// "METHOD /path".
+func Undocumented(r chi.Routes) ([]string, error) {
+	routes, err := Routes(r)
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	for _, rt := range routes {
+		if rt.op == nil {
+			missing = append(missing, rt.Method+" "+rt.Path)
+		}
+	}
+	return missing, nil
+}
+
+// Build documents the routes of r registered with Handle.
+func Build(info Info, schemes map[string]SecurityScheme, r chi.Routes) (*Document, error) {
+	routes, err := Routes(r)
+	if err != nil {
+		return nil, err
+	}
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   map[string]map[string]*pathOp{},
+	}
+	if len(schemes) > 0 {
+		doc.Components = &components{SecuritySchemes: schemes}
+	}
+	for _, rt := range routes {
+		if rt.op == nil {
+			continue
+		}
+		for _, name := range rt.op.Security {
+			if _, ok := schemes[name]; !ok {
+				return nil, fmt.Errorf("%s %s: unknown security scheme %q", rt.Method, rt.Path, name)
+			}
+		}
+		if doc.Paths[rt.Path] == nil {
+			doc.Paths[rt.Path] = map[string]*pathOp{}
+		}
+		doc.Paths[rt.Path][strings.ToLower(rt.Method)] = operation(rt)
+	}
+	return doc, nil
+}
+
+var pathParam = regexp.MustCompile(`\{([^}:]+)(:[^}]+)?\}`)
+
+func operation(rt Route) *pathOp {
+	op := rt.op
+	out := &pathOp{
+		Summary:     op.Summary,
+		Description: op.Description,
+		OperationID: operationID(rt.Method, rt.Path),
+		Tags:        op.Tags,
+		Responses:   map[string]*response{},
+		Deprecated:  op.Deprecated,
+	}
+	for _, m := range pathParam.FindAllStringSubmatch(rt.Path, -1) {
+		out.Parameters = append(out.Parameters, Parameter{Name: m[1], In: "path", Required: true, Schema: &Schema{Type: "string"}})
+	}
+	out.Parameters = append(out.Parameters, op.Parameters...)
+	if op.Request != nil {
+		contentType := op.RequestContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		out.RequestBody = &requestBody{
+			Required: true,
+			Content:  map[string]mediaType{contentType: {Schema: SchemaOf(op.Request)}},
+		}
+	}
+	for status, resp := range op.Responses {
+		r := &response{Description: resp.Description}
+		if r.Description == "" {
+			r.Description = http.StatusText(status)
+		}
+		if resp.Body != nil {
+			contentType := resp.ContentType
+			if contentType == "" {
+				contentType = "application/json"
+			}
+			r.Content = map[string]mediaType{contentType: {Schema: SchemaOf(resp.Body)}}
+		}
+		out.Responses[fmt.Sprint(status)] = r
+	}
+	for _, name := range op.Security {
+		out.Security = append(out.Security, map[string][]string{name: {}})
+	}
+	return out
+}
+
+// normalize turns a chi route into an OpenAPI path: mount wildcards are
+// dropped and regexp path parameters lose their pattern.
+func normalize(route string) string {
+	for strings.Contains(route, "/*/") {
+		route = strings.ReplaceAll(route, "/*/", "/")
+	}
+	if route != "/" {
+		route = strings.TrimSuffix(route, "/")
+	}
+	return pathParam.ReplaceAllString(route, "{$1}")
+}
+
+// operationID derives a stable id such as `post_v1_auth_login`.
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.Split(path, "/") {
+		part = strings.Trim(part, "{}")
+		if part == "" {
+			continue
+		}
+		b.WriteByte('_')
+		b.WriteString(strings.NewReplacer("-", "_", ".", "_").Replace(part))
+	}
+	return b.String()
+}
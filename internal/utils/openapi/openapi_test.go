@@ -0,0 +1,78 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type createItem struct {
+	Name    string            `json:"name" validate:"required,min=2,max=50"`
+	Email   *string           `json:"email" validate:"omitempty,email"`
+	Labels  map[string]string `json:"labels" validate:"omitempty,max=5,dive,max=10"`
+	Hidden  string            `json:"-"`
+	Created time.Time
+}
+
+func noop(w http.ResponseWriter, r *http.Request) {}
+
+func TestBuild(t *testing.T) {
+	items := chi.NewRouter()
+	items.Method(http.MethodPost, "/", Handle(noop, Operation{Summary: "create", Request: createItem{}, Security: []string{"bearer"}}))
+	items.Method(http.MethodGet, "/{id}", Handle(noop, Operation{Summary: "get"}))
+	items.Delete("/{id}", noop)
+	r := chi.NewRouter()
+	r.Mount("/v1/items", items)
+
+	missing, err := Undocumented(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(missing, []string{"DELETE /v1/items/{id}"}) {
+		t.Errorf("Undocumented() = %v", missing)
+	}
+
+	if _, err := Build(Info{}, nil, r); err == nil {
+		t.Error("Build() accepted an unknown security scheme")
+	}
+	doc, err := Build(Info{Title: "test"}, map[string]SecurityScheme{"bearer": {Type: "http", Scheme: "bearer"}}, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := doc.Paths["/v1/items/{id}"]["get"]
+	if get == nil || len(get.Parameters) != 1 || get.Parameters[0].In != "path" || get.OperationID != "get_v1_items_id" {
+		t.Fatalf("get operation = %+v", get)
+	}
+	if _, ok := doc.Paths["/v1/items/{id}"]["delete"]; ok {
+		t.Error("undocumented route in document")
+	}
+	post := doc.Paths["/v1/items"]["post"]
+	if post == nil || post.RequestBody == nil || len(post.Security) != 1 {
+		t.Fatalf("post operation = %+v", post)
+	}
+}
+
+func TestSchemaOf(t *testing.T) {
+	s := SchemaOf(createItem{})
+	if !reflect.DeepEqual(s.Required, []string{"name"}) {
+		t.Errorf("required = %v", s.Required)
+	}
+	if name := s.Properties["name"]; name.Type != "string" || *name.MinLength != 2 || *name.MaxLength != 50 {
+		t.Errorf("name = %+v", name)
+	}
+	if email := s.Properties["email"]; email.Format != "email" {
+		t.Errorf("email = %+v", email)
+	}
+	if labels := s.Properties["labels"]; *labels.MaxProperties != 5 || labels.AdditionalProperties.MaxLength != nil {
+		t.Errorf("labels = %+v", labels)
+	}
+	if _, ok := s.Properties["Hidden"]; ok {
+		t.Error("json:\"-\" field documented")
+	}
+	if created := s.Properties["Created"]; created.Format != "date-time" {
+		t.Errorf("Created = %+v", created)
+	}
+}
@@ -0,0 +1,117 @@
+// Package origin parses and matches web origins (RFC 6454) such as
+// https://app.example.com or http://localhost:5173, and patterns allowing
+// every subdomain of a host, such as https://*.example.com.
+package origin
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Normalize returns s as a lower case scheme://host[:port] origin. s must be
+// an http or https URL without user info, path, query or fragment.
+func Normalize(s string) (string, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", fmt.Errorf("origin %q: %w", s, err)
+	}
+	scheme := strings.ToLower(u.Scheme)
+	switch {
+	case scheme != "http" && scheme != "https":
+		return "", fmt.Errorf("origin %q: scheme must be http or https", s)
+	case u.Host == "" || u.Hostname() == "":
+		return "", fmt.Errorf("origin %q: host is missing", s)
+	case u.User != nil || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" || u.Opaque != "":
+		return "", fmt.Errorf("origin %q: must not have user info, path, query or fragment", s)
+	}
+	return scheme + "://" + strings.ToLower(u.Host), nil
+}
+
+// Pattern matches one origin exactly, or every subdomain of a host when its
+// host starts with "*.". The wildcard does not match the host itself.
+type Pattern struct {
+	scheme string
+	// host is the exact host[:port], or the suffix after "*" including the
+	// leading dot
+	host     string
+	wildcard bool
+}
+
+// ParsePattern parses an origin or a wildcard subdomain pattern. Wildcards
+// must name at least a registrable looking domain, so https://* and
+// https://*.com are rejected.
+func ParsePattern(s string) (Pattern, error) {
+	scheme, host, ok := strings.Cut(s, "://")
+	if ok && strings.HasPrefix(host, "*.") {
+		suffix := host[1:]
+		normalized, err := Normalize(scheme + "://" + suffix[1:])
+		if err != nil {
+			return Pattern{}, err
+		}
+		_, rest, _ := strings.Cut(normalized, "://")
+		name, _, _ := strings.Cut(rest, ":")
+		if !strings.Contains(name, ".") {
+			return Pattern{}, fmt.Errorf("origin pattern %q: wildcard must be followed by a domain with at least two labels", s)
+		}
+		return Pattern{scheme: strings.ToLower(scheme), host: "." + rest, wildcard: true}, nil
+	}
+	if strings.Contains(s, "*") {
+		return Pattern{}, fmt.Errorf("origin pattern %q: only a leading *. subdomain wildcard is supported", s)
+	}
+	normalized, err := Normalize(s)
+	if err != nil {
+		return Pattern{}, err
+	}
+	scheme, host, _ = strings.Cut(normalized, "://")
+	return Pattern{scheme: scheme, host: host}, nil
+}
+
+// Match reports whether the normalized origin o matches p.
+func (p Pattern) Match(o string) bool {
+	scheme, host, ok := strings.Cut(o, "://")
+	if !ok || scheme != p.scheme {
+		return false
+	}
+	if !p.wildcard {
+		return host == p.host
+	}
+	return len(host) > len(p.host) && strings.HasSuffix(host, p.host)
+}
+
+func (p Pattern) String() string {
+	if p.wildcard {
+		return p.scheme + "://*" + p.host
+	}
+	return p.scheme + "://" + p.host
+}
+
+// Matcher matches origins against a list of patterns.
+type Matcher []Pattern
+
+// NewMatcher parses patterns with ParsePattern.
+func NewMatcher(patterns []string) (Matcher, error) {
+	m := make(Matcher, 0, len(patterns))
+	for _, s := range patterns {
+		p, err := ParsePattern(s)
+		if err != nil {
+			return nil, err
+		}
+		m = append(m, p)
+	}
+	return m, nil
+}
+
+// Match reports whether origin, as sent by a browser, matches any pattern.
+func (m Matcher) Match(origin string) bool {
+	o, err := Normalize(origin)
+	if err != nil {
+		return false
+	}
+	for _, p := range m {
+		if p.Match(o) {
+			return true
+		}
+	}
+	return false
+}
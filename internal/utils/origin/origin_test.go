@@ -0,0 +1,49 @@
+package origin
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"https://App.Example.com":    "https://app.example.com",
+		"http://localhost:5173":      "http://localhost:5173",
+		"https://app.example.com/":   "https://app.example.com",
+		"ftp://example.com":          "",
+		"https://example.com/path":   "",
+		"https://user@example.com":   "",
+		"https://example.com?x=1":    "",
+		"app.example.com":            "",
+		"https://":                   "",
+		"https://example.com#anchor": "",
+	}
+	for in, want := range tests {
+		got, err := Normalize(in)
+		if (err != nil) != (want == "") || got != want {
+			t.Errorf("Normalize(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	m, err := NewMatcher([]string{"https://app.example.com", "https://*.tenant.io", "http://localhost:5173"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	allowed := []string{"https://app.example.com", "https://APP.example.com", "https://a.tenant.io", "https://a.b.tenant.io", "http://localhost:5173"}
+	denied := []string{"http://app.example.com", "https://tenant.io", "https://eviltenant.io", "https://a.tenant.io.evil.com", "http://localhost:3000", "null", ""}
+	for _, o := range allowed {
+		if !m.Match(o) {
+			t.Errorf("%q should match", o)
+		}
+	}
+	for _, o := range denied {
+		if m.Match(o) {
+			t.Errorf("%q should not match", o)
+		}
+	}
+
+	for _, p := range []string{"*", "https://*", "https://*.com", "https://app.*.com", "https://example.com/path"} {
+		if _, err := ParsePattern(p); err == nil {
+			t.Errorf("ParsePattern(%q) should fail", p)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Package problem writes errors as RFC 7807 application/problem+json
+// responses.
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"cleanarch/boiler/internal/utils/apperr"
+	"cleanarch/boiler/internal/utils/requestlog"
+)
+
+const ContentType = "application/problem+json"
+
+// Problem is the response body. Code is the stable identifier clients should
+// branch on, Detail is meant for humans.
+type Problem struct {
+	Type      string              `json:"type"`
+	Title     string              `json:"title"`
+	Status    int                 `json:"status"`
+	Detail    string              `json:"detail,omitempty"`
+	Instance  string              `json:"instance,omitempty"`
+	Code      string              `json:"code"`
+	RequestID string              `json:"request_id,omitempty"`
+	Errors    []apperr.FieldError `json:"errors,omitempty"`
+}
+
+var statuses = map[apperr.Kind]int{
+	apperr.NotFound:             http.StatusNotFound,
+	apperr.Conflict:             http.StatusConflict,
+	apperr.Unauthorized:         http.StatusUnauthorized,
+	apperr.Forbidden:            http.StatusForbidden,
+	apperr.Validation:           http.StatusBadRequest,
+	apperr.RateLimited:          http.StatusTooManyRequests,
+	apperr.PreconditionFailed:   http.StatusPreconditionFailed,
+	apperr.PreconditionRequired: http.StatusPreconditionRequired,
+	apperr.UnsupportedMediaType: http.StatusUnsupportedMediaType,
+	apperr.TooLarge:             http.StatusRequestEntityTooLarge,
+	apperr.NotAcceptable:        http.StatusNotAcceptable,
+	apperr.Unprocessable:        http.StatusUnprocessableEntity,
+}
+
+// Status returns the HTTP status of an error kind.
+func Status(kind apperr.Kind) int {
+	if status, ok := statuses[kind]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// From maps err to a problem. Errors outside the taxonomy, and internal ones,
+// become an opaque 500 so that driver or library messages never reach the
+// client.
+func From(r *http.Request, err error) *Problem {
+	p := &Problem{
+		Type:      "about:blank",
+		Instance:  r.URL.Path,
+		RequestID: requestlog.RequestID(r.Context()),
+	}
+	e, ok := apperr.As(err)
+	if !ok || e.Kind == apperr.Internal {
+		p.Status = http.StatusInternalServerError
+		p.Title = http.StatusText(p.Status)
+		p.Code = string(apperr.Internal)
+		p.Detail = "an unexpected error occurred"
+		return p
+	}
+	p.Status = Status(e.Kind)
+	p.Title = http.StatusText(p.Status)
+	p.Code = e.Code
+	p.Detail = e.Message
+	p.Errors = e.Fields
+	return p
+}
+
+// Write sends p.
+func (p *Problem) Write(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+// Write maps err and sends it, returning the problem sent.
+func Write(w http.ResponseWriter, r *http.Request, err error) *Problem {
+	p := From(r, err)
+	p.Write(w)
+	return p
+}
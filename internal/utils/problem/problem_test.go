@@ -0,0 +1,65 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"cleanarch/boiler/internal/utils/apperr"
+)
+
+var errExists = apperr.New(apperr.Conflict, "user_already_exists", "a user with this email already exists")
+
+func TestWriteMapsKindToStatus(t *testing.T) {
+	cause := errors.New("E11000 duplicate key error collection: users")
+	err := fmt.Errorf("sign up: %w", errExists.Wrap(cause))
+	if !errors.Is(err, errExists) {
+		t.Fatal("wrapped error does not match its sentinel")
+	}
+
+	rec := httptest.NewRecorder()
+	Write(rec, httptest.NewRequest(http.MethodPost, "/signup", nil), err)
+
+	if rec.Code != http.StatusConflict {
+		t.Errorf("status = %d, want 409", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != ContentType {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	var p Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Code != "user_already_exists" || p.Status != http.StatusConflict || p.Instance != "/signup" {
+		t.Errorf("problem = %+v", p)
+	}
+	if strings.Contains(rec.Body.String(), "E11000") {
+		t.Errorf("cause leaked to the client: %s", rec.Body)
+	}
+}
+
+func TestWriteHidesInternalErrors(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Write(rec, httptest.NewRequest(http.MethodGet, "/me", nil), errors.New("connection refused"))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", rec.Code)
+	}
+	if strings.Contains(rec.Body.String(), "connection refused") {
+		t.Errorf("internal error leaked to the client: %s", rec.Body)
+	}
+}
+
+func TestWriteIncludesFieldErrors(t *testing.T) {
+	err := apperr.New(apperr.Validation, "invalid_request", "request is invalid").
+		WithFields(apperr.FieldError{Field: "Email", Message: "failed the email rule"})
+
+	p := From(httptest.NewRequest(http.MethodPost, "/signup", nil), err)
+	if p.Status != http.StatusBadRequest || len(p.Errors) != 1 || p.Errors[0].Field != "Email" {
+		t.Errorf("problem = %+v", p)
+	}
+}
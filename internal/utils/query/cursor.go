@@ -0,0 +1,146 @@
+package query
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// cursor is the decoded form of an opaque page cursor. It remembers the sort
+// it was created for so a cursor cannot be replayed against another order.
+type cursor struct {
+	Order  string        `json:"o"`
+	Values []cursorValue `json:"v"`
+}
+
+// cursorValue keeps the Go type of a sort key value across the JSON round
+// trip.
+type cursorValue struct {
+	Type  string          `json:"t"`
+	Value json.RawMessage `json:"v,omitempty"`
+}
+
+func orderKey(order []Sort) string {
+	keys := make([]string, len(order))
+	for i, sort := range order {
+		keys[i] = sort.String()
+	}
+	return strings.Join(keys, ",")
+}
+
+// NextCursor encodes the position of the last row of a page. values holds the
+// row's value for every field of order.
+func NextCursor(order []Sort, values map[string]interface{}) (string, error) {
+	c := cursor{Order: orderKey(order)}
+	for _, sort := range order {
+		v, err := encodeValue(values[sort.Field])
+		if err != nil {
+			return "", fmt.Errorf("cursor field %s: %w", sort.Field, err)
+		}
+		c.Values = append(c.Values, v)
+	}
+	raw, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// Seek returns the keyset expression selecting the rows after the spec's
+// cursor in the given order, or nil when the spec has no cursor:
+//
+//	(k1 > v1) OR (k1 = v1 AND k2 > v2) OR ...
+func (s Spec) Seek(order []Sort) (Expr, error) {
+	if s.Cursor == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s.Cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(raw, &c); err != nil || c.Order != orderKey(order) || len(c.Values) != len(order) {
+		return nil, ErrInvalidCursor
+	}
+
+	values := make([]interface{}, len(c.Values))
+	for i, v := range c.Values {
+		if values[i], err = decodeValue(v); err != nil {
+			return nil, ErrInvalidCursor
+		}
+	}
+
+	or := make(Or, 0, len(order))
+	for i, sort := range order {
+		and := make(And, 0, i+1)
+		for j := 0; j < i; j++ {
+			and = append(and, Filter{Field: order[j].Field, Op: Eq, Value: values[j]})
+		}
+		op := Gt
+		if sort.Direction == Desc {
+			op = Lt
+		}
+		and = append(and, Filter{Field: sort.Field, Op: op, Value: values[i]})
+		or = append(or, and)
+	}
+	return or, nil
+}
+
+func encodeValue(v interface{}) (cursorValue, error) {
+	var typ string
+	switch t := v.(type) {
+	case nil:
+		return cursorValue{Type: "null"}, nil
+	case string:
+		typ = "string"
+	case bool:
+		typ = "bool"
+	case int:
+		typ, v = "int", int64(t)
+	case int32:
+		typ, v = "int", int64(t)
+	case int64:
+		typ = "int"
+	case float64:
+		typ = "float"
+	case time.Time:
+		typ, v = "time", t.UTC().Format(time.RFC3339Nano)
+	default:
+		return cursorValue{}, fmt.Errorf("unsupported type %T", v)
+	}
+	raw, err := json.Marshal(v)
+	return cursorValue{Type: typ, Value: raw}, err
+}
+
+func decodeValue(v cursorValue) (interface{}, error) {
+	switch v.Type {
+	case "null":
+		return nil, nil
+	case "string":
+		var s string
+		err := json.Unmarshal(v.Value, &s)
+		return s, err
+	case "bool":
+		var b bool
+		err := json.Unmarshal(v.Value, &b)
+		return b, err
+	case "int":
+		var i int64
+		err := json.Unmarshal(v.Value, &i)
+		return i, err
+	case "float":
+		var f float64
+		err := json.Unmarshal(v.Value, &f)
+		return f, err
+	case "time":
+		var s string
+		if err := json.Unmarshal(v.Value, &s); err != nil {
+			return nil, err
+		}
+		return time.Parse(time.RFC3339Nano, s)
+	default:
+		return nil, fmt.Errorf("unknown cursor value type %q", v.Type)
+	}
+}
@@ -0,0 +1,188 @@
+// Package mongoquery translates query specs into Mongo filters and find
+// options.
+package mongoquery
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"cleanarch/boiler/internal/utils/query"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Field maps a spec field to a document field.
+type Field struct {
+	// Name is the document field.
+	Name string
+	// Convert turns a spec value into its stored representation, e.g. a hex
+	// string into an ObjectID. Optional.
+	Convert func(interface{}) (interface{}, error)
+}
+
+// Schema lists the fields a repository allows to filter and sort on.
+// Keyset paging over fields that may be missing is not supported, all sortable
+// fields should be present on every document.
+type Schema struct {
+	Fields map[string]Field
+	// TieBreaker is the unique spec field appended to every sort.
+	TieBreaker string
+}
+
+// ObjectID converts hex strings into ObjectIDs.
+func ObjectID(v interface{}) (interface{}, error) {
+	switch t := v.(type) {
+	case primitive.ObjectID:
+		return t, nil
+	case string:
+		return primitive.ObjectIDFromHex(t)
+	default:
+		return nil, fmt.Errorf("cannot convert %T to ObjectID", v)
+	}
+}
+
+// Filter translates an expression.
+func (s Schema) Filter(e query.Expr) (bson.M, error) {
+	switch t := e.(type) {
+	case query.Filter:
+		return s.filter(t)
+	case query.And:
+		return s.group("$and", t)
+	case query.Or:
+		return s.group("$or", t)
+	default:
+		return nil, fmt.Errorf("unsupported expression %T", e)
+	}
+}
+
+// Translate builds the filter, including the cursor position, and the find
+// options for spec. It returns the effective order, which is needed to encode
+// the next cursor. The limit is one more than the page size so callers can
+// tell whether another page follows.
+func (s Schema) Translate(spec query.Spec) (bson.M, []query.Sort, *options.FindOptions, error) {
+	order := spec.Order(s.TieBreaker)
+	seek, err := spec.Seek(order)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	exprs := append(query.And{}, spec.Filters...)
+	if seek != nil {
+		exprs = append(exprs, seek)
+	}
+	filter := bson.M{}
+	if len(exprs) > 0 {
+		if filter, err = s.Filter(exprs); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	sort := bson.D{}
+	for _, o := range order {
+		field, ok := s.Fields[o.Field]
+		if !ok {
+			return nil, nil, nil, query.ErrInvalidSort.WithMessage("invalid sort: " + o.Field)
+		}
+		sort = append(sort, bson.E{Key: field.Name, Value: int(o.Direction)})
+	}
+	opts := options.Find().SetSort(sort).SetLimit(int64(spec.PageSize() + 1))
+	return filter, order, opts, nil
+}
+
+// Find runs spec against coll, restricted by base, and decodes one page of
+// documents. values returns a document's value for each spec field of the
+// order and is used to build the next cursor. extra options, e.g. a
+// projection, are applied after the ones derived from spec.
+func Find[T any](ctx context.Context, coll *mongo.Collection, base bson.M, spec query.Spec, schema Schema, values func(*T) map[string]interface{}, extra ...*options.FindOptions) (*query.Page[*T], error) {
+	filter, order, opts, err := schema.Translate(spec)
+	if err != nil {
+		return nil, err
+	}
+	if len(base) > 0 {
+		filter = bson.M{"$and": bson.A{base, filter}}
+	}
+
+	cur, err := coll.Find(ctx, filter, append([]*options.FindOptions{opts}, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+	var items []*T
+	if err := cur.All(ctx, &items); err != nil {
+		return nil, err
+	}
+
+	page := &query.Page[*T]{Items: items}
+	if size := spec.PageSize(); len(items) > size {
+		page.Items = items[:size]
+		if page.NextCursor, err = query.NextCursor(order, values(page.Items[size-1])); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+func (s Schema) group(op string, exprs []query.Expr) (bson.M, error) {
+	parts := make(bson.A, 0, len(exprs))
+	for _, e := range exprs {
+		m, err := s.Filter(e)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, m)
+	}
+	return bson.M{op: parts}, nil
+}
+
+func (s Schema) filter(f query.Filter) (bson.M, error) {
+	field, ok := s.Fields[f.Field]
+	if !ok {
+		return nil, query.ErrUnknownField.WithMessage("unknown field: " + f.Field)
+	}
+
+	if f.Op == query.Prefix {
+		prefix, ok := f.Value.(string)
+		if !ok {
+			return nil, fmt.Errorf("prefix filter on %s needs a string", f.Field)
+		}
+		return bson.M{field.Name: primitive.Regex{Pattern: "^" + regexp.QuoteMeta(prefix)}}, nil
+	}
+
+	value, err := s.convert(field, f.Op, f.Value)
+	if err != nil {
+		return nil, query.ErrInvalidValue.WithMessage("invalid value for " + f.Field).Wrap(err)
+	}
+	switch f.Op {
+	case query.Eq:
+		return bson.M{field.Name: value}, nil
+	case query.Ne, query.Gt, query.Gte, query.Lt, query.Lte, query.In:
+		return bson.M{field.Name: bson.M{"$" + string(f.Op): value}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported operator %q", f.Op)
+	}
+}
+
+func (s Schema) convert(field Field, op query.Operator, value interface{}) (interface{}, error) {
+	if field.Convert == nil || value == nil {
+		return value, nil
+	}
+	if op != query.In {
+		return field.Convert(value)
+	}
+	values, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("in filter on %s needs a list", field.Name)
+	}
+	converted := make(bson.A, len(values))
+	for i, v := range values {
+		c, err := field.Convert(v)
+		if err != nil {
+			return nil, err
+		}
+		converted[i] = c
+	}
+	return converted, nil
+}
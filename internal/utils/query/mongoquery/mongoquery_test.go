@@ -0,0 +1,82 @@
+package mongoquery
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"cleanarch/boiler/internal/utils/query"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var schema = Schema{
+	Fields: map[string]Field{
+		"id":        {Name: "_id", Convert: ObjectID},
+		"createdAt": {Name: "createdAt"},
+		"email":     {Name: "email"},
+	},
+	TieBreaker: "id",
+}
+
+func TestTranslate_Seek(t *testing.T) {
+	id := primitive.NewObjectID()
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	order := query.Spec{Sort: []query.Sort{{Field: "createdAt", Direction: query.Desc}}}.Order("id")
+
+	cursor, err := query.NextCursor(order, map[string]interface{}{"createdAt": createdAt, "id": id.Hex()})
+	if err != nil {
+		t.Fatalf("NextCursor() error = %v", err)
+	}
+
+	spec := query.New().Where("email", query.Prefix, "a.b").OrderBy("createdAt", query.Desc).Take(10).After(cursor)
+	filter, gotOrder, opts, err := schema.Translate(*spec)
+	if err != nil {
+		t.Fatalf("Translate() error = %v", err)
+	}
+
+	want := bson.M{"$and": bson.A{
+		bson.M{"email": primitive.Regex{Pattern: `^a\.b`}},
+		bson.M{"$or": bson.A{
+			bson.M{"$and": bson.A{bson.M{"createdAt": bson.M{"$lt": createdAt}}}},
+			bson.M{"$and": bson.A{
+				bson.M{"createdAt": createdAt},
+				bson.M{"_id": bson.M{"$lt": id}},
+			}},
+		}},
+	}}
+	if !reflect.DeepEqual(filter, want) {
+		t.Fatalf("filter = %v\nwant %v", filter, want)
+	}
+	if !reflect.DeepEqual(gotOrder, order) {
+		t.Fatalf("order = %v, want %v", gotOrder, order)
+	}
+	if *opts.Limit != 11 {
+		t.Fatalf("limit = %d, want page size + 1", *opts.Limit)
+	}
+	if sort := opts.Sort.(bson.D); len(sort) != 2 || sort[0].Key != "createdAt" || sort[1].Key != "_id" {
+		t.Fatalf("sort = %v", sort)
+	}
+}
+
+func TestTranslate_CursorFromOtherOrder(t *testing.T) {
+	cursor, _ := query.NextCursor(query.Spec{}.Order("id"), map[string]interface{}{"id": primitive.NewObjectID().Hex()})
+
+	spec := query.New().OrderBy("email", query.Asc).After(cursor)
+	if _, _, _, err := schema.Translate(*spec); !errors.Is(err, query.ErrInvalidCursor) {
+		t.Fatalf("Translate() error = %v, want %v", err, query.ErrInvalidCursor)
+	}
+}
+
+func TestTranslate_UnknownField(t *testing.T) {
+	spec := query.New().OrderBy("password", query.Asc)
+	if _, _, _, err := schema.Translate(*spec); !errors.Is(err, query.ErrInvalidSort) {
+		t.Fatalf("Translate() error = %v, want %v", err, query.ErrInvalidSort)
+	}
+	spec = query.New().Where("password", query.Eq, "x")
+	if _, _, _, err := schema.Translate(*spec); !errors.Is(err, query.ErrUnknownField) {
+		t.Fatalf("Translate() error = %v, want %v", err, query.ErrUnknownField)
+	}
+}
@@ -0,0 +1,184 @@
+// Package query describes list queries independently of the storage that
+// runs them. Repositories translate a Spec into their own query language and
+// page through results with opaque keyset cursors.
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"cleanarch/boiler/internal/utils/apperr"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Errors caused by the client's query. They are validation errors, the
+// message of a returned copy names the offending field or value.
+var ErrInvalidCursor = apperr.New(apperr.Validation, "invalid_cursor", "invalid cursor")
+var ErrInvalidSort = apperr.New(apperr.Validation, "invalid_sort", "invalid sort")
+var ErrUnknownField = apperr.New(apperr.Validation, "unknown_field", "unknown field")
+var ErrInvalidValue = apperr.New(apperr.Validation, "invalid_value", "invalid value")
+var ErrInvalidLimit = apperr.New(apperr.Validation, "invalid_limit", fmt.Sprintf("limit must be between 1 and %d", MaxLimit))
+
+// Operator compares a field against a value.
+type Operator string
+
+const (
+	Eq     Operator = "eq"
+	Ne     Operator = "ne"
+	Gt     Operator = "gt"
+	Gte    Operator = "gte"
+	Lt     Operator = "lt"
+	Lte    Operator = "lte"
+	In     Operator = "in"
+	Prefix Operator = "prefix"
+)
+
+// Expr is a filter expression: a Filter, an And or an Or.
+type Expr interface {
+	expr()
+}
+
+// Filter is a single field comparison.
+type Filter struct {
+	Field string
+	Op    Operator
+	Value interface{}
+}
+
+// And matches when all of its expressions match.
+type And []Expr
+
+// Or matches when any of its expressions match.
+type Or []Expr
+
+func (Filter) expr() {}
+func (And) expr()    {}
+func (Or) expr()     {}
+
+// Direction is the order of a sort key.
+type Direction int
+
+const (
+	Asc  Direction = 1
+	Desc Direction = -1
+)
+
+// Sort orders results by a field.
+type Sort struct {
+	Field     string
+	Direction Direction
+}
+
+func (s Sort) String() string {
+	if s.Direction == Desc {
+		return "-" + s.Field
+	}
+	return s.Field
+}
+
+// Spec is a storage agnostic list query. Filters are combined with AND.
+type Spec struct {
+	Filters []Expr
+	Sort    []Sort
+	Limit   int
+	Cursor  string
+}
+
+// New starts an empty Spec.
+func New() *Spec {
+	return &Spec{}
+}
+
+// Where adds a field comparison.
+func (s *Spec) Where(field string, op Operator, value interface{}) *Spec {
+	s.Filters = append(s.Filters, Filter{Field: field, Op: op, Value: value})
+	return s
+}
+
+// Match adds an arbitrary expression.
+func (s *Spec) Match(e Expr) *Spec {
+	s.Filters = append(s.Filters, e)
+	return s
+}
+
+// OrderBy appends a sort key.
+func (s *Spec) OrderBy(field string, direction Direction) *Spec {
+	s.Sort = append(s.Sort, Sort{Field: field, Direction: direction})
+	return s
+}
+
+// Take sets the page size.
+func (s *Spec) Take(limit int) *Spec {
+	s.Limit = limit
+	return s
+}
+
+// After continues from the cursor returned with a previous page.
+func (s *Spec) After(cursor string) *Spec {
+	s.Cursor = cursor
+	return s
+}
+
+// PageSize returns the limit clamped to [1, MaxLimit], DefaultLimit if unset.
+func (s Spec) PageSize() int {
+	switch {
+	case s.Limit <= 0:
+		return DefaultLimit
+	case s.Limit > MaxLimit:
+		return MaxLimit
+	default:
+		return s.Limit
+	}
+}
+
+// Order returns the sort keys with tieBreaker appended, so that every row has
+// a unique position and keyset paging is stable. The tie breaker follows the
+// direction of the last sort key.
+func (s Spec) Order(tieBreaker string) []Sort {
+	order := make([]Sort, 0, len(s.Sort)+1)
+	direction := Asc
+	for _, sort := range s.Sort {
+		if sort.Field == tieBreaker {
+			return append(order, sort)
+		}
+		order = append(order, sort)
+		direction = sort.Direction
+	}
+	return append(order, Sort{Field: tieBreaker, Direction: direction})
+}
+
+// ParseSort parses a comma separated list of fields, a leading `-` meaning
+// descending, e.g. `-createdAt,email`.
+func ParseSort(value string) ([]Sort, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var sorts []Sort
+	seen := map[string]bool{}
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		direction := Asc
+		if strings.HasPrefix(part, "-") {
+			direction = Desc
+			part = part[1:]
+		} else {
+			part = strings.TrimPrefix(part, "+")
+		}
+		if part == "" || seen[part] {
+			return nil, ErrInvalidSort.WithMessage(fmt.Sprintf("invalid sort: %q", value))
+		}
+		seen[part] = true
+		sorts = append(sorts, Sort{Field: part, Direction: direction})
+	}
+	return sorts, nil
+}
+
+// Page is one page of results.
+type Page[T any] struct {
+	Items      []T
+	NextCursor string
+}
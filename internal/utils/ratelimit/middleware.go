@@ -0,0 +1,129 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cleanarch/boiler/internal/utils/apperr"
+	"cleanarch/boiler/internal/utils/logger"
+	"cleanarch/boiler/internal/utils/problem"
+)
+
+// ErrLimited is the error limited requests are answered with.
+var ErrLimited = apperr.New(apperr.RateLimited, "rate_limited", "rate limit exceeded")
+
+// KeyFunc returns the key a request is limited by, or false when the policy
+// does not apply to it, e.g. a per user policy on an anonymous request.
+type KeyFunc func(r *http.Request) (string, bool)
+
+// ByIP keys requests by the client address.
+func ByIP(r *http.Request) (string, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return host, host != ""
+}
+
+// ByHeader keys requests by the value of a header such as X-API-Key.
+func ByHeader(name string) KeyFunc {
+	return func(r *http.Request) (string, bool) {
+		v := r.Header.Get(name)
+		return v, v != ""
+	}
+}
+
+// Policy limits the requests sharing a key. A policy without requests is
+// disabled.
+type Policy struct {
+	// Name prefixes the bucket keys, so policies sharing a key function do
+	// not share buckets.
+	Name  string
+	Limit Limit
+	Key   KeyFunc
+}
+
+// Limiter applies policies to routes with buckets from a Store.
+type Limiter struct {
+	l     logger.Interface
+	store Store
+}
+
+func NewLimiter(l logger.Interface, store Store) *Limiter {
+	return &Limiter{l: l, store: store}
+}
+
+// Middleware enforces p and reports the outcome in the RateLimit-Limit,
+// RateLimit-Remaining, RateLimit-Reset and RateLimit-Policy headers. When
+// several policies apply the most restrictive one is reported. Limited
+// requests get 429 with Retry-After. If the store fails the request is let
+// through.
+func (lim *Limiter) Middleware(p Policy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if p.Limit.Requests <= 0 || p.Limit.Window <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, ok := p.Key(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			res, err := lim.store.Take(r.Context(), p.Name+":"+key, p.Limit)
+			if err != nil {
+				lim.l.WithContext(r.Context()).Error("rate limit store failed, allowing request", "policy", p.Name, "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			setHeaders(w.Header(), p, res)
+			if !res.Allowed {
+				lim.l.WithContext(r.Context()).Warn("rate limit exceeded", "policy", p.Name, "path", r.URL.Path)
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+				problem.Write(w, r, ErrLimited)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Allow takes a token of p for key outside of HTTP, e.g. in a gRPC method,
+// where p.Key does not apply. It returns ErrLimited when the bucket is empty.
+// As with Middleware, store failures let the call through.
+func (lim *Limiter) Allow(ctx context.Context, p Policy, key string) error {
+	if p.Limit.Requests <= 0 || p.Limit.Window <= 0 || key == "" {
+		return nil
+	}
+	res, err := lim.store.Take(ctx, p.Name+":"+key, p.Limit)
+	if err != nil {
+		lim.l.WithContext(ctx).Error("rate limit store failed, allowing request", "policy", p.Name, "error", err)
+		return nil
+	}
+	if !res.Allowed {
+		lim.l.WithContext(ctx).Warn("rate limit exceeded", "policy", p.Name)
+		return ErrLimited.WithMessage(fmt.Sprintf("rate limit exceeded, retry in %ds", ceilSeconds(res.RetryAfter)))
+	}
+	return nil
+}
+
+func setHeaders(h http.Header, p Policy, res Result) {
+	if prev := h.Get("RateLimit-Remaining"); prev != "" {
+		if n, err := strconv.Atoi(prev); err == nil && n <= res.Remaining && res.Allowed {
+			return
+		}
+	}
+	h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+	h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", p.Limit.Requests, ceilSeconds(p.Limit.Window)))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cleanarch/boiler/internal/utils/logger"
+)
+
+type clock struct{ now time.Time }
+
+func (c *clock) Now() time.Time { return c.now }
+
+func TestMemoryStore_TokenBucket(t *testing.T) {
+	c := &clock{now: time.Unix(0, 0)}
+	s := NewMemoryStore(c.Now)
+	limit := Limit{Requests: 2, Window: time.Second}
+	ctx := context.Background()
+
+	for i, want := range []bool{true, true, false} {
+		res, _ := s.Take(ctx, "k", limit)
+		if res.Allowed != want {
+			t.Fatalf("take %d: allowed = %v, want %v", i, res.Allowed, want)
+		}
+	}
+	res, _ := s.Take(ctx, "k", limit)
+	if res.RetryAfter != 500*time.Millisecond || res.Remaining != 0 {
+		t.Errorf("denied result = %+v, want retry after 500ms", res)
+	}
+
+	// half a window refills one token
+	c.now = c.now.Add(500 * time.Millisecond)
+	if res, _ := s.Take(ctx, "k", limit); !res.Allowed {
+		t.Error("bucket was not refilled")
+	}
+	if res, _ := s.Take(ctx, "other", limit); !res.Allowed || res.Remaining != 1 {
+		t.Errorf("keys share a bucket: %+v", res)
+	}
+
+	// idle buckets are full again and dropped
+	c.now = c.now.Add(2 * sweepInterval)
+	s.Take(ctx, "new", limit)
+	if s.Len() != 1 {
+		t.Errorf("%d buckets after sweep, want 1", s.Len())
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	lim := NewLimiter(logger.NewLogger("error"), NewMemoryStore(nil))
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	byIP := lim.Middleware(Policy{Name: "ip", Limit: Limit{Requests: 10, Window: time.Minute}, Key: ByIP})
+	byKey := lim.Middleware(Policy{Name: "apiKey", Limit: Limit{Requests: 1, Window: time.Minute}, Key: ByHeader("X-API-Key")})
+	h := byIP(byKey(ok))
+
+	do := func(apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do("")
+	if rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Remaining") != "9" || rec.Header().Get("RateLimit-Policy") != "10;w=60" {
+		t.Fatalf("got %d %v", rec.Code, rec.Header())
+	}
+
+	// the API key policy is the most restrictive one and is reported
+	rec = do("secret")
+	if rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Limit") != "1" || rec.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("got %d %v", rec.Code, rec.Header())
+	}
+	rec = do("secret")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "60" {
+		t.Fatalf("got %d %v, want 429 with Retry-After", rec.Code, rec.Header())
+	}
+
+	// the policy does not apply without a key
+	if rec := do(""); rec.Code != http.StatusOK {
+		t.Errorf("request without api key got %d", rec.Code)
+	}
+}
@@ -0,0 +1,45 @@
+// Package ratelimit limits request rates per key with token buckets. Buckets
+// live in a Store so that instances can share them.
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limit is a token bucket refilled with Requests tokens per Window, holding at
+// most Burst tokens. A zero Burst means Requests.
+type Limit struct {
+	Requests int
+	Window   time.Duration
+	Burst    int
+}
+
+func (l Limit) capacity() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return float64(l.Requests)
+}
+
+// rate is the refill rate in tokens per second.
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Window.Seconds()
+}
+
+// Result is the outcome of taking a token.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next token, set when not allowed.
+	RetryAfter time.Duration
+}
+
+// Store holds the buckets. Take removes one token from the bucket of key,
+// creating it full if it does not exist.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
@@ -0,0 +1,135 @@
+// Package requestlog logs one structured record per HTTP request and tags
+// every log call made while serving it with the request id.
+package requestlog
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+
+	"cleanarch/boiler/internal/utils/logger"
+)
+
+// HeaderRequestID carries the request id in both directions.
+const HeaderRequestID = "X-Request-ID"
+
+// maxRequestIDLength bounds client supplied ids, they end up in every log
+// record of the request.
+const maxRequestIDLength = 128
+
+type infoKey struct{}
+
+// info is filled in by the handlers, e.g. once the caller is authenticated,
+// and read when the request record is written.
+type info struct {
+	mu        sync.Mutex
+	requestID string
+	userID    string
+	tenantID  string
+}
+
+// SetUser records the authenticated caller of the request in ctx.
+func SetUser(ctx context.Context, userID, tenantID string) {
+	if i, ok := ctx.Value(infoKey{}).(*info); ok {
+		i.mu.Lock()
+		i.userID, i.tenantID = userID, tenantID
+		i.mu.Unlock()
+	}
+}
+
+// RequestID returns the id of the request served with ctx, or "".
+func RequestID(ctx context.Context) string {
+	if i, ok := ctx.Value(infoKey{}).(*info); ok {
+		return i.requestID
+	}
+	return ""
+}
+
+// User returns the caller recorded with SetUser, or "".
+func User(ctx context.Context) (userID, tenantID string) {
+	if i, ok := ctx.Value(infoKey{}).(*info); ok {
+		i.mu.Lock()
+		defer i.mu.Unlock()
+		return i.userID, i.tenantID
+	}
+	return "", ""
+}
+
+// NewContext starts tracking a request, HTTP or not, in ctx. It keeps the
+// client supplied id when usable and generates one otherwise. Loggers
+// obtained with WithContext from the returned context carry the id.
+func NewContext(ctx context.Context, id string) (context.Context, string) {
+	if !validRequestID(id) {
+		id = uuid.NewString()
+	}
+	ctx = context.WithValue(ctx, infoKey{}, &info{requestID: id})
+	return logger.NewContext(ctx, "request_id", id), id
+}
+
+// Middleware assigns the request id, from X-Request-ID when the client sent
+// a usable one, echoes it in the response and logs the request once it has
+// been served. Loggers obtained with WithContext from the request context
+// carry the id.
+func Middleware(l logger.Interface) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ctx, id := NewContext(r.Context(), r.Header.Get(HeaderRequestID))
+			w.Header().Set(HeaderRequestID, id)
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			route := ""
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				route = rctx.RoutePattern()
+			}
+			userID, tenantID := User(ctx)
+
+			args := []interface{}{
+				"request_id", id,
+				"method", r.Method,
+				"path", r.URL.Path,
+				"route", route,
+				"status", status,
+				"bytes", ww.BytesWritten(),
+				"latency_ms", float64(time.Since(start).Microseconds()) / 1000,
+				"remote_addr", r.RemoteAddr,
+			}
+			if userID != "" {
+				args = append(args, "user_id", userID, "tenant_id", tenantID)
+			}
+			switch {
+			case status >= http.StatusInternalServerError:
+				l.Error("request", args...)
+			case status >= http.StatusBadRequest:
+				l.Warn("request", args...)
+			default:
+				l.Info("request", args...)
+			}
+		})
+	}
+}
+
+// validRequestID accepts printable ASCII ids of reasonable length, anything
+// else could forge or break log lines.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
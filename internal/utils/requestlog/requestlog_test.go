@@ -0,0 +1,80 @@
+package requestlog
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"cleanarch/boiler/internal/utils/logger"
+)
+
+// recorder keeps the records logged through it.
+type recorder struct {
+	records []string
+}
+
+func (r *recorder) record(level string, message interface{}, args ...interface{}) {
+	r.records = append(r.records, fmt.Sprint(level, " ", message, " ", args))
+}
+
+func (r *recorder) Debug(message interface{}, args ...interface{}) {
+	r.record("DEBUG", message, args...)
+}
+func (r *recorder) Info(message string, args ...interface{}) { r.record("INFO", message, args...) }
+func (r *recorder) Warn(message string, args ...interface{}) { r.record("WARN", message, args...) }
+func (r *recorder) Error(message interface{}, args ...interface{}) {
+	r.record("ERROR", message, args...)
+}
+func (r *recorder) Fatal(message interface{}, args ...interface{}) {
+	r.record("FATAL", message, args...)
+}
+func (r *recorder) With(args ...interface{}) logger.Interface        { return r }
+func (r *recorder) WithContext(ctx context.Context) logger.Interface { return r }
+
+func TestMiddleware(t *testing.T) {
+	rec := &recorder{}
+	var seen string
+	r := chi.NewRouter()
+	r.Use(Middleware(rec))
+	r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestID(r.Context())
+		SetUser(r.Context(), "u1", "t1")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("missing"))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req.Header.Set(HeaderRequestID, "abc-123")
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+
+	if seen != "abc-123" || res.Header().Get(HeaderRequestID) != "abc-123" {
+		t.Errorf("request id in handler %q, in response %q, want the client's", seen, res.Header().Get(HeaderRequestID))
+	}
+	if len(rec.records) != 1 {
+		t.Fatalf("got %d records, want one per request", len(rec.records))
+	}
+	for _, want := range []string{"WARN request", "request_id abc-123", "route /users/{id}", "status 404", "bytes 7", "user_id u1", "tenant_id t1"} {
+		if !strings.Contains(rec.records[0], want) {
+			t.Errorf("record %q does not contain %q", rec.records[0], want)
+		}
+	}
+}
+
+func TestMiddleware_ReplacesUnusableRequestID(t *testing.T) {
+	h := Middleware(&recorder{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, id := range []string{"", "with space", "line\nbreak", strings.Repeat("x", maxRequestIDLength+1)} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(HeaderRequestID, id)
+		res := httptest.NewRecorder()
+		h.ServeHTTP(res, req)
+		if got := res.Header().Get(HeaderRequestID); got == id || len(got) != 36 {
+			t.Errorf("request id %q was not replaced, got %q", id, got)
+		}
+	}
+}
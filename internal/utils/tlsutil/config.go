@@ -0,0 +1,64 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"fmt"
+)
+
+// Options describes the server TLS policy.
+type Options struct {
+	// MinVersion is "1.2" or "1.3".
+	MinVersion string
+	// CipherSuites are IANA names of TLS 1.2 cipher suites, empty keeps Go's
+	// defaults.
+	CipherSuites []string
+}
+
+// NewConfig returns a server TLS config serving the certificates of r and
+// offering HTTP/2.
+func NewConfig(r *CertReloader, opts Options) (*tls.Config, error) {
+	version, err := parseVersion(opts.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	suites, err := parseCipherSuites(opts.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		MinVersion:     version,
+		CipherSuites:   suites,
+		GetCertificate: r.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}, nil
+}
+
+func parseVersion(v string) (uint16, error) {
+	switch v {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("tls: unsupported minimum version %q", v)
+}
+
+// parseCipherSuites only accepts the suites Go considers secure.
+func parseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	known := map[string]uint16{}
+	for _, s := range tls.CipherSuites() {
+		known[s.Name] = s.ID
+	}
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("tls: unknown or insecure cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,120 @@
+// Package tlsutil builds the server TLS configuration and keeps its
+// certificate up to date with the files on disk.
+package tlsutil
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"cleanarch/boiler/internal/utils/logger"
+)
+
+// CertReloader serves a certificate key pair from disk and reloads it when
+// the files change, so renewed certificates are picked up without a restart.
+type CertReloader struct {
+	l        logger.Interface
+	certFile string
+	keyFile  string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// NewCertReloader loads the key pair once and fails if it is unusable.
+func NewCertReloader(l logger.Interface, certFile, keyFile string) (*CertReloader, error) {
+	r := &CertReloader{l: l, certFile: certFile, keyFile: keyFile}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate is meant for tls.Config.GetCertificate.
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// Reload reads the key pair from disk. On failure the current certificate is
+// kept, a half written renewal must not take the server down.
+func (r *CertReloader) Reload() error {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("tls: loading key pair: %w", err)
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.modTime = modTime
+	r.mu.Unlock()
+	return nil
+}
+
+// Watch reloads the key pair when either file's modification time changes,
+// checked every interval, or when the process receives SIGHUP. It returns
+// when ctx is done.
+func (r *CertReloader) Watch(ctx context.Context, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			r.reload("SIGHUP")
+		case <-ticker.C:
+			if r.changed() {
+				r.reload("files changed")
+			}
+		}
+	}
+}
+
+func (r *CertReloader) reload(reason string) {
+	if err := r.Reload(); err != nil {
+		r.l.Error("unable to reload TLS certificate, keeping the current one", "reason", reason, "error", err)
+		return
+	}
+	r.l.Info("reloaded TLS certificate", "reason", reason)
+}
+
+func (r *CertReloader) changed() bool {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return !modTime.Equal(r.modTime)
+}
+
+func (r *CertReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, name := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("tls: %w", err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,123 @@
+package tlsutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"cleanarch/boiler/internal/utils/logger"
+)
+
+// writeKeyPair writes a self-signed certificate for commonName.
+func writeKeyPair(t *testing.T, certFile, keyFile, commonName string, modTime time.Time) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{certFile, keyFile} {
+		if err := os.Chtimes(name, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func commonName(t *testing.T, r *CertReloader) string {
+	t.Helper()
+	cert, _ := r.GetCertificate(&tls.ClientHelloInfo{})
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	start := time.Now().Add(-time.Minute)
+	writeKeyPair(t, certFile, keyFile, "old", start)
+
+	r, err := NewCertReloader(logger.NewLogger("error"), certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.changed() {
+		t.Fatal("unchanged files reported as changed")
+	}
+
+	writeKeyPair(t, certFile, keyFile, "new", start.Add(time.Second))
+	if !r.changed() {
+		t.Fatal("renewed certificate not detected")
+	}
+	r.reload("test")
+	if got := commonName(t, r); got != "new" {
+		t.Errorf("serving %q after reload, want new", got)
+	}
+
+	// a broken renewal keeps the current certificate
+	if err := os.WriteFile(keyFile, []byte("garbage"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Reload(); err == nil {
+		t.Error("reloading a broken key pair succeeded")
+	}
+	if got := commonName(t, r); got != "new" {
+		t.Errorf("serving %q after a failed reload, want new", got)
+	}
+}
+
+func TestRedirectHandler(t *testing.T) {
+	tests := []struct {
+		port   int
+		target string
+		want   string
+	}{
+		{443, "http://example.com:8080/users?cursor=abc", "https://example.com/users?cursor=abc"},
+		{8443, "http://example.com/me", "https://example.com:8443/me"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		RedirectHandler(tt.port).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+		if rec.Code != http.StatusPermanentRedirect || rec.Header().Get("Location") != tt.want {
+			t.Errorf("%s: got %d %s, want 308 %s", tt.target, rec.Code, rec.Header().Get("Location"), tt.want)
+		}
+	}
+}
+
+func TestNewConfig_RejectsUnknownCipherSuite(t *testing.T) {
+	if _, err := NewConfig(&CertReloader{}, Options{CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}}); err == nil {
+		t.Error("insecure cipher suite accepted")
+	}
+}
@@ -0,0 +1,63 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// MongoMonitor returns a driver command monitor that opens a client span per
+// command under the span of the calling repository method. Command bodies are
+// not recorded, they hold user data.
+func MongoMonitor() *event.CommandMonitor {
+	// spans of started commands by request id
+	var inflight sync.Map
+	end := func(requestID int64, err error) {
+		v, ok := inflight.LoadAndDelete(requestID)
+		if !ok {
+			return
+		}
+		span := v.(trace.Span)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			name := e.CommandName
+			attrs := []attribute.KeyValue{
+				semconv.DBSystemMongoDB,
+				semconv.DBNamespace(e.DatabaseName),
+				semconv.DBOperationName(e.CommandName),
+			}
+			// the value of the first element is the collection for CRUD commands
+			if elem, err := e.Command.IndexErr(0); err == nil {
+				if coll, ok := elem.Value().StringValueOK(); ok {
+					name = coll + "." + e.CommandName
+					attrs = append(attrs, semconv.DBCollectionName(coll))
+				}
+			}
+			_, span := otel.Tracer(instrumentationName).Start(ctx, name,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(attrs...),
+			)
+			inflight.Store(e.RequestID, span)
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			end(e.RequestID, nil)
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			end(e.RequestID, errors.New(e.Failure))
+		},
+	}
+}
@@ -0,0 +1,57 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+type Options struct {
+	ServiceName string
+	// SampleRatio is the fraction of new traces that are recorded. Traces
+	// started upstream follow the caller's sampling decision.
+	SampleRatio float64
+	// Exporter receives the finished spans. When nil no tracer provider is
+	// installed and spans are not recorded, trace context is still propagated.
+	Exporter sdktrace.SpanExporter
+}
+
+// Setup installs the W3C trace context propagator and, when an exporter is
+// given, a batching tracer provider as the global ones. The returned function
+// flushes pending spans and shuts the provider down.
+func Setup(opts Options) func(context.Context) error {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	if opts.Exporter == nil {
+		return func(context.Context) error { return nil }
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(opts.Exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName(opts.ServiceName),
+		)),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown
+}
+
+// NewOTLPExporter exports spans to an OTLP/HTTP collector at endpoint
+// (host:port). The connection is made lazily, a collector that is down does
+// not prevent startup.
+func NewOTLPExporter(ctx context.Context, endpoint string, insecure bool) (sdktrace.SpanExporter, error) {
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(endpoint)}
+	if insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	return otlptracehttp.New(ctx, opts...)
+}
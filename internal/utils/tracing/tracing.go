@@ -0,0 +1,35 @@
+// Package tracing wires OpenTelemetry tracing: the tracer provider and its
+// exporter, W3C trace context propagation, an HTTP middleware, a Mongo
+// command monitor and helpers to open spans in the application layers.
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the spans created by this module.
+const instrumentationName = "cleanarch/boiler"
+
+// Start opens a span named name as a child of the span in ctx. The name is
+// conventionally "Type.Method" of the function being traced.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records the error err points to, if any, and ends span. It is meant to
+// be deferred with the address of a named error result:
+//
+//	ctx, span := tracing.Start(ctx, "UserService.GetUserByID")
+//	defer tracing.End(span, &err)
+func End(span trace.Span, err *error) {
+	if err != nil && *err != nil {
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+	}
+	span.End()
+}
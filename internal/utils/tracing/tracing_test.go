@@ -0,0 +1,63 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestMiddleware_ContinuesIncomingTrace(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	otel.SetTracerProvider(tp)
+	Setup(Options{})
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		_, span := Start(r.Context(), "UserService.GetUserByID")
+		err = errors.New("not found")
+		End(span, &err)
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	child, server := spans[0], spans[1]
+
+	if server.Name != "GET /users/{id}" {
+		t.Errorf("server span name = %q, want route pattern", server.Name)
+	}
+	if got := server.SpanContext.TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("server span trace id = %s, want the incoming one", got)
+	}
+	if got := server.Parent.SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("server span parent = %s, want the incoming span", got)
+	}
+	if server.Status.Code == codes.Error {
+		t.Errorf("4xx responses must not mark the server span as failed")
+	}
+
+	if child.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Errorf("layer span is not a child of the server span")
+	}
+	if child.Status.Code != codes.Error || len(child.Events) == 0 {
+		t.Errorf("layer span did not record its error: %+v", child.Status)
+	}
+}
@@ -0,0 +1,100 @@
+// Package validate checks values against their `validate` struct tags and
+// reports failures as apperr validation errors with one entry per field.
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"cleanarch/boiler/internal/utils/apperr"
+	"cleanarch/boiler/internal/utils/origin"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var ErrInvalid = apperr.New(apperr.Validation, "invalid_request", "request is invalid")
+
+// validate is safe for concurrent use and caches struct metadata, so it is
+// shared by all callers.
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	// report fields by the name clients send them under
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			return f.Name
+		}
+		return name
+	})
+	// origin accepts a web origin such as https://app.example.com
+	v.RegisterValidation("origin", func(fl validator.FieldLevel) bool {
+		_, err := origin.Normalize(fl.Field().String())
+		return err == nil
+	})
+	return v
+}
+
+// Struct validates s. Failures are returned as ErrInvalid listing every
+// offending field, e.g. `email: must be a valid email address`.
+func Struct(s interface{}) error {
+	err := validate.Struct(s)
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return err
+	}
+	fields := make([]apperr.FieldError, 0, len(verrs))
+	for _, fe := range verrs {
+		fields = append(fields, apperr.FieldError{Field: field(fe), Message: Message(fe)})
+	}
+	return ErrInvalid.WithFields(fields...).Wrap(err)
+}
+
+// field returns the path of fe below the validated struct, e.g. `email` or
+// `settings[theme]`.
+func field(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if _, rest, ok := strings.Cut(ns, "."); ok {
+		return rest
+	}
+	return ns
+}
+
+// Message describes a failed rule in words.
+func Message(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "url":
+		return "must be a valid URL"
+	case "origin":
+		return "must be an origin like https://app.example.com, without a path"
+	case "uuid", "uuid4":
+		return "must be a valid UUID"
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(fe.Param()), ", ")
+	case "min", "gte":
+		return bound("at least", fe)
+	case "max", "lte":
+		return bound("at most", fe)
+	case "len":
+		return bound("exactly", fe)
+	}
+	return fmt.Sprintf("failed the %s rule", fe.Tag())
+}
+
+func bound(relation string, fe validator.FieldError) string {
+	switch fe.Kind() {
+	case reflect.String:
+		return fmt.Sprintf("must be %s %s characters long", relation, fe.Param())
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return fmt.Sprintf("must have %s %s items", relation, fe.Param())
+	default:
+		return fmt.Sprintf("must be %s %s", relation, fe.Param())
+	}
+}
@@ -0,0 +1,205 @@
+package webhook
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"cleanarch/boiler/internal/utils/query"
+)
+
+// maxHistory is how many attempts a delivery keeps in its history.
+const maxHistory = 10
+
+// MemoryStore is a Store kept in process memory, meant for tests and local
+// development. Its delivery pages only sort by creation time.
+type MemoryStore struct {
+	mu            sync.Mutex
+	subscriptions map[string]*Subscription
+	deliveries    map[string]*Delivery
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		subscriptions: make(map[string]*Subscription),
+		deliveries:    make(map[string]*Delivery),
+	}
+}
+
+func (m *MemoryStore) CreateSubscription(ctx context.Context, sub *Subscription) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	copied := *sub
+	m.subscriptions[sub.ID] = &copied
+	return nil
+}
+
+func (m *MemoryStore) Subscription(ctx context.Context, id string) (*Subscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sub, ok := m.subscriptions[id]
+	if !ok {
+		return nil, ErrSubscriptionNotFound
+	}
+	copied := *sub
+	return &copied, nil
+}
+
+func (m *MemoryStore) Subscriptions(ctx context.Context, tenantID string) ([]*Subscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	subs := []*Subscription{}
+	for _, sub := range m.subscriptions {
+		if sub.TenantID == tenantID {
+			copied := *sub
+			subs = append(subs, &copied)
+		}
+	}
+	slices.SortFunc(subs, func(a, b *Subscription) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return subs, nil
+}
+
+func (m *MemoryStore) DeleteSubscription(ctx context.Context, tenantID, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sub, ok := m.subscriptions[id]
+	if !ok || sub.TenantID != tenantID {
+		return ErrSubscriptionNotFound
+	}
+	delete(m.subscriptions, id)
+	return nil
+}
+
+func (m *MemoryStore) Enqueue(ctx context.Context, deliveries ...*Delivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, d := range deliveries {
+		if _, ok := m.deliveries[d.ID]; ok {
+			continue
+		}
+		copied := *d
+		m.deliveries[d.ID] = &copied
+	}
+	return nil
+}
+
+func (m *MemoryStore) Claim(ctx context.Context, limit int, lease time.Duration) ([]*Delivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+
+	var due []*Delivery
+	for _, d := range m.deliveries {
+		if d.Status == Pending && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	slices.SortFunc(due, func(a, b *Delivery) int {
+		return a.NextAttemptAt.Compare(b.NextAttemptAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := make([]*Delivery, 0, len(due))
+	for _, d := range due {
+		d.Attempts++
+		d.NextAttemptAt = now.Add(lease)
+		claimed = append(claimed, d.clone())
+	}
+	return claimed, nil
+}
+
+func (m *MemoryStore) Record(ctx context.Context, id string, attempt Attempt, status DeliveryStatus, retryAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d, ok := m.deliveries[id]
+	if !ok {
+		return ErrDeliveryNotFound
+	}
+	d.History = append(d.History, attempt)
+	if len(d.History) > maxHistory {
+		d.History = d.History[len(d.History)-maxHistory:]
+	}
+	d.Status = status
+	if status == Pending {
+		d.NextAttemptAt = retryAt
+	} else {
+		completed := attempt.At
+		d.CompletedAt = &completed
+	}
+	return nil
+}
+
+func (m *MemoryStore) Delivery(ctx context.Context, tenantID, id string) (*Delivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d, ok := m.deliveries[id]
+	if !ok || d.TenantID != tenantID {
+		return nil, ErrDeliveryNotFound
+	}
+	return d.clone(), nil
+}
+
+// Deliveries pages by offset, the cursor being the index of the next item.
+// Only the direction of a createdAt sort is honoured.
+func (m *MemoryStore) Deliveries(ctx context.Context, tenantID, subscriptionID string, spec query.Spec) (*query.Page[*Delivery], error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var matched []*Delivery
+	for _, d := range m.deliveries {
+		if d.TenantID == tenantID && d.SubscriptionID == subscriptionID {
+			matched = append(matched, d)
+		}
+	}
+	direction := query.Asc
+	if len(spec.Sort) > 0 && spec.Sort[0].Field == "createdAt" {
+		direction = spec.Sort[0].Direction
+	}
+	slices.SortFunc(matched, func(a, b *Delivery) int {
+		c := a.CreatedAt.Compare(b.CreatedAt)
+		if c == 0 {
+			c = cmp.Compare(a.ID, b.ID)
+		}
+		return c * int(direction)
+	})
+
+	offset := 0
+	if spec.Cursor != "" {
+		n, err := strconv.Atoi(spec.Cursor)
+		if err != nil || n < 0 {
+			return nil, query.ErrInvalidCursor
+		}
+		offset = min(n, len(matched))
+	}
+	end := min(offset+spec.PageSize(), len(matched))
+	page := &query.Page[*Delivery]{Items: make([]*Delivery, 0, end-offset)}
+	for _, d := range matched[offset:end] {
+		page.Items = append(page.Items, d.clone())
+	}
+	if end < len(matched) {
+		page.NextCursor = strconv.Itoa(end)
+	}
+	return page, nil
+}
+
+// Status returns the status of a delivery, or "" if it does not exist.
+func (m *MemoryStore) Status(id string) DeliveryStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if d, ok := m.deliveries[id]; ok {
+		return d.Status
+	}
+	return ""
+}
+
+func (d *Delivery) clone() *Delivery {
+	copied := *d
+	copied.History = slices.Clone(d.History)
+	return &copied
+}
@@ -0,0 +1,326 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cleanarch/boiler/internal/utils/query"
+	"cleanarch/boiler/internal/utils/query/mongoquery"
+	"cleanarch/boiler/internal/webhook"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	subscriptionsCollection = "webhook_subscriptions"
+	deliveriesCollection    = "webhook_deliveries"
+)
+
+// completedRetention is how long finished deliveries stay in the log before
+// the TTL index removes them.
+const completedRetention = 30 * 24 * time.Hour
+
+// maxHistory is how many attempts a delivery keeps in its history.
+const maxHistory = 10
+
+type subscription struct {
+	ID        string    `bson:"_id"`
+	TenantID  string    `bson:"tenantId"`
+	URL       string    `bson:"url"`
+	Events    []string  `bson:"events"`
+	Secret    string    `bson:"secret"`
+	CreatedAt time.Time `bson:"createdAt"`
+}
+
+type attempt struct {
+	At         time.Time     `bson:"at"`
+	StatusCode int           `bson:"statusCode,omitempty"`
+	Error      string        `bson:"error,omitempty"`
+	Duration   time.Duration `bson:"duration"`
+}
+
+type delivery struct {
+	ID             string     `bson:"_id"`
+	SubscriptionID string     `bson:"subscriptionId"`
+	TenantID       string     `bson:"tenantId"`
+	EventID        string     `bson:"eventId"`
+	EventName      string     `bson:"event"`
+	Payload        []byte     `bson:"payload"`
+	Status         string     `bson:"status"`
+	Attempts       int        `bson:"attempts"`
+	History        []attempt  `bson:"history"`
+	NextAttemptAt  time.Time  `bson:"nextAttemptAt"`
+	CreatedAt      time.Time  `bson:"createdAt"`
+	CompletedAt    *time.Time `bson:"completedAt,omitempty"`
+	ReplayOf       string     `bson:"replayOf,omitempty"`
+}
+
+// deliverySchema lists the fields deliveries can be filtered and sorted on.
+var deliverySchema = mongoquery.Schema{
+	Fields: map[string]mongoquery.Field{
+		"id":        {Name: "_id"},
+		"event":     {Name: "event"},
+		"status":    {Name: "status"},
+		"createdAt": {Name: "createdAt"},
+	},
+	TieBreaker: "id",
+}
+
+// Store keeps webhook subscriptions and deliveries in Mongo collections.
+type Store struct {
+	db *mongo.Database
+}
+
+func NewStore(db *mongo.Database) *Store {
+	return &Store{
+		db: db,
+	}
+}
+
+// CreateIndexes creates the indexes used to list subscriptions and
+// deliveries, to find due deliveries and the TTL index that purges finished
+// ones.
+func (s *Store) CreateIndexes(ctx context.Context) error {
+	_, err := s.db.Collection(subscriptionsCollection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "tenantId", Value: 1}, {Key: "createdAt", Value: 1}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Collection(deliveriesCollection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "nextAttemptAt", Value: 1}}},
+		{Keys: bson.D{{Key: "tenantId", Value: 1}, {Key: "subscriptionId", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{
+			Keys:    bson.D{{Key: "completedAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(completedRetention.Seconds())).SetName("completedAt_ttl"),
+		},
+	})
+	return err
+}
+
+func (s *Store) CreateSubscription(ctx context.Context, sub *webhook.Subscription) error {
+	_, err := s.db.Collection(subscriptionsCollection).InsertOne(ctx, subscription{
+		ID:        sub.ID,
+		TenantID:  sub.TenantID,
+		URL:       sub.URL,
+		Events:    sub.Events,
+		Secret:    sub.Secret,
+		CreatedAt: sub.CreatedAt,
+	})
+	return err
+}
+
+func (s *Store) Subscription(ctx context.Context, id string) (*webhook.Subscription, error) {
+	doc := new(subscription)
+	err := s.db.Collection(subscriptionsCollection).FindOne(ctx, bson.M{"_id": id}).Decode(doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, webhook.ErrSubscriptionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc.toSubscription(), nil
+}
+
+func (s *Store) Subscriptions(ctx context.Context, tenantID string) ([]*webhook.Subscription, error) {
+	cursor, err := s.db.Collection(subscriptionsCollection).Find(ctx, bson.M{"tenantId": tenantID},
+		options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	var docs []subscription
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	subs := make([]*webhook.Subscription, len(docs))
+	for i := range docs {
+		subs[i] = docs[i].toSubscription()
+	}
+	return subs, nil
+}
+
+func (s *Store) DeleteSubscription(ctx context.Context, tenantID, id string) error {
+	res, err := s.db.Collection(subscriptionsCollection).DeleteOne(ctx, bson.M{"_id": id, "tenantId": tenantID})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return webhook.ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+// Enqueue inserts deliveries unordered, so that duplicates of ids already
+// queued are skipped without stopping the others.
+func (s *Store) Enqueue(ctx context.Context, deliveries ...*webhook.Delivery) error {
+	docs := make([]interface{}, 0, len(deliveries))
+	for _, d := range deliveries {
+		docs = append(docs, fromDelivery(d))
+	}
+	_, err := s.db.Collection(deliveriesCollection).InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	var bulk mongo.BulkWriteException
+	if errors.As(err, &bulk) && onlyDuplicates(bulk) {
+		return nil
+	}
+	return err
+}
+
+// Claim leases due deliveries one at a time with FindOneAndUpdate so that
+// several workers can poll the same collection without sending a delivery
+// twice within a lease.
+func (s *Store) Claim(ctx context.Context, limit int, lease time.Duration) ([]*webhook.Delivery, error) {
+	coll := s.db.Collection(deliveriesCollection)
+	now := time.Now().UTC()
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "nextAttemptAt", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var claimed []*webhook.Delivery
+	for len(claimed) < limit {
+		doc := new(delivery)
+		err := coll.FindOneAndUpdate(ctx, bson.M{
+			"status":        string(webhook.Pending),
+			"nextAttemptAt": bson.M{"$lte": now},
+		}, bson.M{
+			"$set": bson.M{"nextAttemptAt": now.Add(lease)},
+			"$inc": bson.M{"attempts": 1},
+		}, opts).Decode(doc)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			break
+		}
+		if err != nil {
+			return claimed, err
+		}
+		claimed = append(claimed, doc.toDelivery())
+	}
+	return claimed, nil
+}
+
+func (s *Store) Record(ctx context.Context, id string, a webhook.Attempt, status webhook.DeliveryStatus, retryAt time.Time) error {
+	set := bson.M{"status": string(status)}
+	if status == webhook.Pending {
+		set["nextAttemptAt"] = retryAt.UTC()
+	} else {
+		set["completedAt"] = a.At.UTC()
+	}
+	res, err := s.db.Collection(deliveriesCollection).UpdateByID(ctx, id, bson.M{
+		"$set": set,
+		"$push": bson.M{"history": bson.M{
+			"$each":  []attempt{fromAttempt(a)},
+			"$slice": -maxHistory,
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return webhook.ErrDeliveryNotFound
+	}
+	return nil
+}
+
+func (s *Store) Delivery(ctx context.Context, tenantID, id string) (*webhook.Delivery, error) {
+	doc := new(delivery)
+	err := s.db.Collection(deliveriesCollection).FindOne(ctx, bson.M{"_id": id, "tenantId": tenantID}).Decode(doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, webhook.ErrDeliveryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc.toDelivery(), nil
+}
+
+func (s *Store) Deliveries(ctx context.Context, tenantID, subscriptionID string, spec query.Spec) (*query.Page[*webhook.Delivery], error) {
+	base := bson.M{"tenantId": tenantID, "subscriptionId": subscriptionID}
+	page, err := mongoquery.Find(ctx, s.db.Collection(deliveriesCollection), base, spec, deliverySchema, func(d *delivery) map[string]interface{} {
+		return map[string]interface{}{
+			"id":        d.ID,
+			"event":     d.EventName,
+			"status":    d.Status,
+			"createdAt": d.CreatedAt,
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	deliveries := make([]*webhook.Delivery, len(page.Items))
+	for i, d := range page.Items {
+		deliveries[i] = d.toDelivery()
+	}
+	return &query.Page[*webhook.Delivery]{Items: deliveries, NextCursor: page.NextCursor}, nil
+}
+
+func onlyDuplicates(bulk mongo.BulkWriteException) bool {
+	if bulk.WriteConcernError != nil {
+		return false
+	}
+	for _, we := range bulk.WriteErrors {
+		if !mongo.IsDuplicateKeyError(we) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *subscription) toSubscription() *webhook.Subscription {
+	return &webhook.Subscription{
+		ID:        s.ID,
+		TenantID:  s.TenantID,
+		URL:       s.URL,
+		Events:    s.Events,
+		Secret:    s.Secret,
+		CreatedAt: s.CreatedAt,
+	}
+}
+
+func fromAttempt(a webhook.Attempt) attempt {
+	return attempt{At: a.At.UTC(), StatusCode: a.StatusCode, Error: a.Error, Duration: a.Duration}
+}
+
+func fromDelivery(d *webhook.Delivery) delivery {
+	history := make([]attempt, len(d.History))
+	for i, a := range d.History {
+		history[i] = fromAttempt(a)
+	}
+	return delivery{
+		ID:             d.ID,
+		SubscriptionID: d.SubscriptionID,
+		TenantID:       d.TenantID,
+		EventID:        d.EventID,
+		EventName:      d.EventName,
+		Payload:        d.Payload,
+		Status:         string(d.Status),
+		Attempts:       d.Attempts,
+		History:        history,
+		NextAttemptAt:  d.NextAttemptAt.UTC(),
+		CreatedAt:      d.CreatedAt.UTC(),
+		CompletedAt:    d.CompletedAt,
+		ReplayOf:       d.ReplayOf,
+	}
+}
+
+func (d *delivery) toDelivery() *webhook.Delivery {
+	history := make([]webhook.Attempt, len(d.History))
+	for i, a := range d.History {
+		history[i] = webhook.Attempt{At: a.At, StatusCode: a.StatusCode, Error: a.Error, Duration: a.Duration}
+	}
+	return &webhook.Delivery{
+		ID:             d.ID,
+		SubscriptionID: d.SubscriptionID,
+		TenantID:       d.TenantID,
+		EventID:        d.EventID,
+		EventName:      d.EventName,
+		Payload:        d.Payload,
+		Status:         webhook.DeliveryStatus(d.Status),
+		Attempts:       d.Attempts,
+		History:        history,
+		NextAttemptAt:  d.NextAttemptAt,
+		CreatedAt:      d.CreatedAt,
+		CompletedAt:    d.CompletedAt,
+		ReplayOf:       d.ReplayOf,
+	}
+}
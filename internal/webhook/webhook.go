@@ -0,0 +1,328 @@
+// Package webhook delivers events to HTTP endpoints registered by tenants.
+//
+// Tenants subscribe a URL to a set of event names. Every matching event is
+// queued as a Delivery in a Store, and a Worker POSTs it with an HMAC-SHA256
+// signature, retrying failures with exponential backoff. Deliveries stay in
+// the store as a log that tenants can inspect and replay.
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"cleanarch/boiler/internal/events"
+	"cleanarch/boiler/internal/utils/apperr"
+	"cleanarch/boiler/internal/utils/logger"
+	"cleanarch/boiler/internal/utils/query"
+
+	"github.com/google/uuid"
+)
+
+var ErrSubscriptionNotFound = apperr.New(apperr.NotFound, "webhook_not_found", "webhook not found")
+var ErrDeliveryNotFound = apperr.New(apperr.NotFound, "webhook_delivery_not_found", "webhook delivery not found")
+var ErrInvalidSubscription = apperr.New(apperr.Validation, "invalid_webhook", "webhook is invalid")
+
+// Subscription sends the events named in Events, or every event for "*", to
+// URL. Secret signs the deliveries and is only shown when created.
+type Subscription struct {
+	ID        string    `json:"id"`
+	TenantID  string    `json:"tenant_id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AllEvents subscribes to every event.
+const AllEvents = "*"
+
+func (s *Subscription) wants(name string) bool {
+	return slices.Contains(s.Events, AllEvents) || slices.Contains(s.Events, name)
+}
+
+type DeliveryStatus string
+
+const (
+	// Pending deliveries are waiting for their next attempt.
+	Pending DeliveryStatus = "pending"
+	// Succeeded deliveries got a 2xx answer.
+	Succeeded DeliveryStatus = "succeeded"
+	// Failed deliveries ran out of attempts or lost their subscription.
+	Failed DeliveryStatus = "failed"
+)
+
+// Delivery is one event sent to one subscription. Payload is fixed when the
+// delivery is queued, so retries and replays send the same body.
+type Delivery struct {
+	ID             string          `json:"id"`
+	SubscriptionID string          `json:"subscription_id"`
+	TenantID       string          `json:"tenant_id"`
+	EventID        string          `json:"event_id"`
+	EventName      string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         DeliveryStatus  `json:"status"`
+	// Attempts counts the attempts made so far, History keeps the latest.
+	Attempts      int        `json:"attempts"`
+	History       []Attempt  `json:"history"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	CompletedAt   *time.Time `json:"completed_at,omitempty"`
+	// ReplayOf is the delivery this one replays.
+	ReplayOf string `json:"replay_of,omitempty"`
+}
+
+// Attempt records one try at a delivery. StatusCode is 0 when no response
+// was received.
+type Attempt struct {
+	At         time.Time     `json:"at"`
+	StatusCode int           `json:"status_code,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Duration   time.Duration `json:"duration_ns"`
+}
+
+// Store persists subscriptions and the delivery queue and log.
+type Store interface {
+	CreateSubscription(ctx context.Context, sub *Subscription) error
+	// Subscription returns ErrSubscriptionNotFound for unknown ids.
+	Subscription(ctx context.Context, id string) (*Subscription, error)
+	Subscriptions(ctx context.Context, tenantID string) ([]*Subscription, error)
+	DeleteSubscription(ctx context.Context, tenantID, id string) error
+
+	// Enqueue stores new deliveries, ignoring those whose id is already
+	// queued so that an event delivered twice to the handler is sent once.
+	Enqueue(ctx context.Context, deliveries ...*Delivery) error
+	// Claim leases up to limit pending deliveries that are due, hiding them
+	// from other workers for the lease and counting the attempt.
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]*Delivery, error)
+	// Record appends attempt to the history of a delivery and moves it to
+	// status. Pending deliveries are retried at retryAt.
+	Record(ctx context.Context, id string, attempt Attempt, status DeliveryStatus, retryAt time.Time) error
+	// Delivery returns ErrDeliveryNotFound for unknown ids.
+	Delivery(ctx context.Context, tenantID, id string) (*Delivery, error)
+	Deliveries(ctx context.Context, tenantID, subscriptionID string, spec query.Spec) (*query.Page[*Delivery], error)
+}
+
+// Options configures a Service.
+type Options struct {
+	// Events are the event names tenants may subscribe to.
+	Events []string
+	// AllowInsecure accepts http URLs, for development and tests.
+	AllowInsecure bool
+}
+
+// Service manages the subscriptions of tenants and queues deliveries for
+// their events.
+type Service struct {
+	l     logger.Interface
+	store Store
+	opts  Options
+}
+
+func NewService(l logger.Interface, store Store, opts Options) *Service {
+	return &Service{l: l, store: store, opts: opts}
+}
+
+// Subscribe registers url for the named events of a tenant and returns the
+// subscription with its newly generated secret.
+func (s *Service) Subscribe(ctx context.Context, tenantID, rawURL string, names []string) (*Subscription, error) {
+	var fields []apperr.FieldError
+	if msg := s.checkURL(rawURL); msg != "" {
+		fields = append(fields, apperr.FieldError{Field: "url", Message: msg})
+	}
+	if len(names) == 0 {
+		fields = append(fields, apperr.FieldError{Field: "events", Message: "is required"})
+	}
+	for i, name := range names {
+		if name != AllEvents && !slices.Contains(s.opts.Events, name) {
+			fields = append(fields, apperr.FieldError{Field: "events[" + strconv.Itoa(i) + "]", Message: "is not an event that can be subscribed to"})
+		}
+	}
+	if len(fields) > 0 {
+		return nil, ErrInvalidSubscription.WithFields(fields...)
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+	names = slices.Clone(names)
+	slices.Sort(names)
+	sub := &Subscription{
+		ID:        uuid.NewString(),
+		TenantID:  tenantID,
+		URL:       rawURL,
+		Events:    slices.Compact(names),
+		Secret:    secret,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := s.store.CreateSubscription(ctx, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// Subscriptions lists the subscriptions of a tenant without their secrets.
+func (s *Service) Subscriptions(ctx context.Context, tenantID string) ([]*Subscription, error) {
+	subs, err := s.store.Subscriptions(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	for _, sub := range subs {
+		sub.Secret = ""
+	}
+	return subs, nil
+}
+
+// Unsubscribe deletes a subscription. Its pending deliveries fail.
+func (s *Service) Unsubscribe(ctx context.Context, tenantID, id string) error {
+	return s.store.DeleteSubscription(ctx, tenantID, id)
+}
+
+// Deliveries pages through the delivery log of a subscription, newest first
+// unless spec sorts otherwise.
+func (s *Service) Deliveries(ctx context.Context, tenantID, subscriptionID string, spec query.Spec) (*query.Page[*Delivery], error) {
+	if _, err := s.subscription(ctx, tenantID, subscriptionID); err != nil {
+		return nil, err
+	}
+	if len(spec.Sort) == 0 {
+		spec.Sort = []query.Sort{{Field: "createdAt", Direction: query.Desc}}
+	}
+	return s.store.Deliveries(ctx, tenantID, subscriptionID, spec)
+}
+
+// Replay queues a new delivery of the payload of an earlier one, whatever
+// its outcome was.
+func (s *Service) Replay(ctx context.Context, tenantID, subscriptionID, deliveryID string) (*Delivery, error) {
+	original, err := s.store.Delivery(ctx, tenantID, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if original.SubscriptionID != subscriptionID {
+		return nil, ErrDeliveryNotFound
+	}
+	if _, err := s.subscription(ctx, tenantID, subscriptionID); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	replay := &Delivery{
+		ID:             uuid.NewString(),
+		SubscriptionID: original.SubscriptionID,
+		TenantID:       original.TenantID,
+		EventID:        original.EventID,
+		EventName:      original.EventName,
+		Payload:        original.Payload,
+		Status:         Pending,
+		History:        []Attempt{},
+		NextAttemptAt:  now,
+		CreatedAt:      now,
+		ReplayOf:       original.ID,
+	}
+	if err := s.store.Enqueue(ctx, replay); err != nil {
+		return nil, err
+	}
+	return replay, nil
+}
+
+// Payload is the JSON body of a delivery.
+type Payload struct {
+	// ID is the event id, the same for every delivery of the event.
+	ID         string            `json:"id"`
+	Type       string            `json:"type"`
+	TenantID   string            `json:"tenant_id"`
+	Subject    string            `json:"subject"`
+	OccurredAt time.Time         `json:"occurred_at"`
+	Data       map[string]string `json:"data"`
+}
+
+// Handle is an events.Handler queueing a delivery of event for every
+// subscription of its tenant that wants it. Delivery ids derive from the
+// event and subscription ids, so redelivered events are not queued twice.
+func (s *Service) Handle(ctx context.Context, event events.Event) error {
+	if event.TenantID == "" || !slices.Contains(s.opts.Events, event.Name) {
+		return nil
+	}
+	subs, err := s.store.Subscriptions(ctx, event.TenantID)
+	if err != nil {
+		return err
+	}
+	data := event.Payload
+	if data == nil {
+		data = map[string]string{}
+	}
+	body, err := json.Marshal(Payload{
+		ID:         event.ID,
+		Type:       event.Name,
+		TenantID:   event.TenantID,
+		Subject:    event.AggregateID,
+		OccurredAt: event.OccurredAt,
+		Data:       data,
+	})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	var deliveries []*Delivery
+	for _, sub := range subs {
+		if !sub.wants(event.Name) {
+			continue
+		}
+		deliveries = append(deliveries, &Delivery{
+			ID:             event.ID + ":" + sub.ID,
+			SubscriptionID: sub.ID,
+			TenantID:       sub.TenantID,
+			EventID:        event.ID,
+			EventName:      event.Name,
+			Payload:        body,
+			Status:         Pending,
+			History:        []Attempt{},
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return s.store.Enqueue(ctx, deliveries...)
+}
+
+// subscription returns the subscription of a tenant, hiding those of others.
+func (s *Service) subscription(ctx context.Context, tenantID, id string) (*Subscription, error) {
+	sub, err := s.store.Subscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if sub.TenantID != tenantID {
+		return nil, ErrSubscriptionNotFound
+	}
+	return sub, nil
+}
+
+// checkURL returns why rawURL cannot receive deliveries, or "" if it can.
+func (s *Service) checkURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	switch {
+	case err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http"):
+		return "must be an absolute http or https URL"
+	case u.Scheme == "http" && !s.opts.AllowInsecure:
+		return "must use https"
+	case u.User != nil:
+		return "must not contain credentials"
+	}
+	return ""
+}
+
+// newSecret returns a random signing secret.
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,220 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"cleanarch/boiler/internal/utils/logger"
+)
+
+const (
+	// EventHeader names the event of a delivery.
+	EventHeader = "Webhook-Event"
+	// DeliveryHeader identifies a delivery. Retries of a delivery reuse its
+	// id, so receivers can use it to drop duplicates.
+	DeliveryHeader = "Webhook-Delivery"
+	userAgent      = "boiler-webhooks/1"
+)
+
+var errPrivateAddress = errors.New("webhook address is not public")
+
+// WorkerOptions tunes how the Worker polls the queue and retries deliveries.
+type WorkerOptions struct {
+	// Interval between polls when the queue is drained.
+	Interval time.Duration
+	// BatchSize is the maximum number of deliveries claimed per poll.
+	BatchSize int
+	// Lease is how long a claimed delivery stays hidden from other workers.
+	// It must exceed Timeout.
+	Lease time.Duration
+	// Timeout bounds a single delivery request.
+	Timeout time.Duration
+	// MaxAttempts is the number of attempts after which a delivery fails.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry, doubled on each
+	// following one up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// AllowPrivateNetworks lets deliveries reach loopback and private
+	// addresses, for development and tests.
+	AllowPrivateNetworks bool
+}
+
+func (o WorkerOptions) withDefaults() WorkerOptions {
+	if o.Interval <= 0 {
+		o.Interval = time.Second
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = 50
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = 10 * time.Second
+	}
+	if o.Lease <= o.Timeout {
+		o.Lease = 2*o.Timeout + 10*time.Second
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = 8
+	}
+	if o.InitialBackoff <= 0 {
+		o.InitialBackoff = 30 * time.Second
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = 6 * time.Hour
+	}
+	return o
+}
+
+// Worker sends queued deliveries. A delivery succeeds on any 2xx answer;
+// other answers, redirects included, and transport errors are retried with
+// exponential backoff until MaxAttempts.
+type Worker struct {
+	l      logger.Interface
+	store  Store
+	opts   WorkerOptions
+	client *http.Client
+}
+
+func NewWorker(l logger.Interface, store Store, opts WorkerOptions) *Worker {
+	opts = opts.withDefaults()
+	dialer := &net.Dialer{Timeout: opts.Timeout}
+	if !opts.AllowPrivateNetworks {
+		dialer.Control = publicOnly
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &Worker{
+		l:     l,
+		store: store,
+		opts:  opts,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   opts.Timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Run polls the queue until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		n, err := w.DeliverOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			w.l.Error("webhook worker failed", "error", err)
+		}
+		// keep draining while full batches come back
+		if err == nil && n == w.opts.BatchSize {
+			timer.Reset(0)
+		} else {
+			timer.Reset(w.opts.Interval)
+		}
+	}
+}
+
+// DeliverOnce claims one batch of due deliveries and sends them. It returns
+// the number of deliveries claimed.
+func (w *Worker) DeliverOnce(ctx context.Context) (int, error) {
+	deliveries, err := w.store.Claim(ctx, w.opts.BatchSize, w.opts.Lease)
+	if err != nil {
+		return 0, err
+	}
+	for _, d := range deliveries {
+		w.deliver(ctx, d)
+	}
+	return len(deliveries), nil
+}
+
+func (w *Worker) deliver(ctx context.Context, d *Delivery) {
+	attempt := Attempt{At: time.Now().UTC()}
+	sub, err := w.store.Subscription(ctx, d.SubscriptionID)
+	if err == nil {
+		attempt.StatusCode, err = w.send(ctx, sub, d)
+	}
+	attempt.Duration = time.Since(attempt.At)
+
+	status, retryAt := Succeeded, time.Time{}
+	switch {
+	case errors.Is(err, ErrSubscriptionNotFound):
+		attempt.Error = "subscription deleted"
+		status = Failed
+	case err != nil:
+		attempt.Error = err.Error()
+		status = Failed
+		if d.Attempts < w.opts.MaxAttempts {
+			status, retryAt = Pending, time.Now().Add(w.backoff(d.Attempts))
+		}
+		w.l.Warn("webhook delivery failed", "delivery", d.ID, "attempt", d.Attempts, "error", err)
+	}
+	if err := w.store.Record(ctx, d.ID, attempt, status, retryAt); err != nil {
+		w.l.Error("unable to record webhook delivery", "delivery", d.ID, "error", err)
+	}
+}
+
+// send POSTs a delivery and returns the status code of the answer, with an
+// error unless it is a 2xx.
+func (w *Worker) send(ctx context.Context, sub *Subscription, d *Delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(EventHeader, d.EventName)
+	req.Header.Set(DeliveryHeader, d.ID)
+	req.Header.Set(SignatureHeader, Sign(sub.Secret, time.Now(), d.Payload))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// drain a little so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver answered %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff returns InitialBackoff doubled for every attempt after the first,
+// capped at MaxBackoff.
+func (w *Worker) backoff(attempts int) time.Duration {
+	delay := w.opts.InitialBackoff
+	for i := 1; i < attempts && delay < w.opts.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, w.opts.MaxBackoff)
+}
+
+// publicOnly refuses connections to addresses that are not on the public
+// internet, checked after DNS resolution so names cannot point inside.
+func publicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+		return errPrivateAddress
+	}
+	return nil
+}